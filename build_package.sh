@@ -10,7 +10,7 @@ ARCHIVE_NAME="${BINARY_NAME}.tgz"
 rm -rf "$BUILD_DIR" "$DIST_DIR"
 mkdir -p "$BUILD_DIR" "$DIST_DIR"
 
-GOOS="${GOOS:-}" GOARCH="${GOARCH:-}" CGO_ENABLED="${CGO_ENABLED:-1}" go build -o "$BUILD_DIR/$BINARY_NAME" .
+GOOS="${GOOS:-}" GOARCH="${GOARCH:-}" CGO_ENABLED="${CGO_ENABLED:-1}" go build -tags sqlite_fts5 -o "$BUILD_DIR/$BINARY_NAME" .
 
 cp "$ROOT_DIR/config.json" "$BUILD_DIR/"
 cp -R "$ROOT_DIR/static" "$BUILD_DIR/"
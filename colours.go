@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// minContrastRatio is the WCAG 2.1 threshold for graphical objects. Colours
+// below it are hard to distinguish from the white calendar background.
+const minContrastRatio = 3.0
+
+// personColour returns the configured colour of the person when it is valid,
+// or a colour derived from the name otherwise.
+func personColour(name, configured string) string {
+	color := fallbackColour(name)
+	if raw := strings.TrimSpace(configured); raw != "" {
+		parsed, err := normaliseHexColour(raw)
+		if err != nil {
+			log.Printf("warning: person %q: %v (using %s)", name, err, color)
+		} else {
+			color = parsed
+		}
+	}
+
+	if ratio := contrastAgainstWhite(color); ratio < minContrastRatio {
+		log.Printf("warning: person %q: colour %s has a poor contrast on white (%.2f:1, %.1f:1 recommended)", name, color, ratio, minContrastRatio)
+	}
+	return color
+}
+
+// normaliseHexColour validates a #rgb or #rrggbb colour and returns it in the
+// lower-case #rrggbb form.
+func normaliseHexColour(value string) (string, error) {
+	hex := strings.TrimPrefix(strings.ToLower(value), "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) != 6 || !strings.HasPrefix(value, "#") {
+		return "", fmt.Errorf("invalid colour %q (expected #rgb or #rrggbb)", value)
+	}
+	if _, err := strconv.ParseUint(hex, 16, 32); err != nil {
+		return "", fmt.Errorf("invalid colour %q (expected #rgb or #rrggbb)", value)
+	}
+	return "#" + hex, nil
+}
+
+// fallbackColour derives a stable colour from the name so that reordering the
+// configuration does not change anybody's colour. Saturation and lightness are
+// fixed to keep every hue readable on white.
+func fallbackColour(name string) string {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(name))
+	hue := float64(h.Sum32() % 360)
+	r, g, b := hslToRGB(hue, 0.65, 0.35)
+	return fmt.Sprintf("#%02x%02x%02x", r, g, b)
+}
+
+func hslToRGB(hue, saturation, lightness float64) (uint8, uint8, uint8) {
+	chroma := (1 - math.Abs(2*lightness-1)) * saturation
+	segment := hue / 60
+	x := chroma * (1 - math.Abs(math.Mod(segment, 2)-1))
+
+	var r, g, b float64
+	switch {
+	case segment < 1:
+		r, g, b = chroma, x, 0
+	case segment < 2:
+		r, g, b = x, chroma, 0
+	case segment < 3:
+		r, g, b = 0, chroma, x
+	case segment < 4:
+		r, g, b = 0, x, chroma
+	case segment < 5:
+		r, g, b = x, 0, chroma
+	default:
+		r, g, b = chroma, 0, x
+	}
+
+	m := lightness - chroma/2
+	toByte := func(v float64) uint8 {
+		return uint8(math.Round((v + m) * 255))
+	}
+	return toByte(r), toByte(g), toByte(b)
+}
+
+// contrastAgainstWhite returns the WCAG contrast ratio between the colour
+// (in #rrggbb form) and white.
+func contrastAgainstWhite(color string) float64 {
+	value, err := strconv.ParseUint(strings.TrimPrefix(color, "#"), 16, 32)
+	if err != nil {
+		return 0
+	}
+
+	channel := func(shift uint) float64 {
+		c := float64((value>>shift)&0xff) / 255
+		if c <= 0.03928 {
+			return c / 12.92
+		}
+		return math.Pow((c+0.055)/1.055, 2.4)
+	}
+	luminance := 0.2126*channel(16) + 0.7152*channel(8) + 0.0722*channel(0)
+	return 1.05 / (luminance + 0.05)
+}
@@ -0,0 +1,441 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// appConfig is the content of config.json. The top-level property settings
+// describe the single property of a simple installation; when "properties"
+// is present, they act as defaults for each listed property instead.
+type appConfig struct {
+	propertyConfig
+	Properties []propertyConfig `json:"properties"`
+}
+
+// propertyConfig gathers the settings of one property served by the binary.
+type propertyConfig struct {
+	ID          string         `json:"id"`
+	Name        string         `json:"name"`
+	People      []personConfig `json:"people"`
+	PageTitle   string         `json:"page_title"`
+	BannerTitle string         `json:"banner_title"`
+	BasePath    string         `json:"base_path"`
+	// Capacity is the number of guests the apartment sleeps. When zero, the
+	// beds of the configured rooms are counted (no limit without rooms).
+	Capacity       int          `json:"capacity"`
+	CapacityPolicy string       `json:"capacity_policy"`
+	Rooms          []roomConfig `json:"rooms"`
+	// Database is the SQLite file of the property and Auth the JSON file
+	// holding its password.
+	Database string `json:"database"`
+	Auth     string `json:"auth"`
+	// AttachmentsDir holds the files attached to reservations.
+	AttachmentsDir string `json:"attachments_dir"`
+	// Timezone is the IANA zone of the property (default Europe/Paris).
+	Timezone string `json:"timezone"`
+	// Admins lists the members allowed to approve bookings and manage the
+	// property.
+	Admins   []string        `json:"admins"`
+	Approval *approvalConfig `json:"approval"`
+	// SMTP is the mail server used for e-mail notifications; its password
+	// lives in the auth file.
+	SMTP   *smtpConfig   `json:"smtp"`
+	Quotas *quotasConfig `json:"quotas"`
+	// SchoolHolidays are dated periods ("YYYY-MM-DD" bounds, included) used
+	// by the yearly report.
+	SchoolHolidays []periodConfig `json:"school_holidays"`
+	// Rules are the booking rules every new stay and date change is checked
+	// against; admins may override them.
+	Rules   []ruleConfig   `json:"rules"`
+	Lottery *lotteryConfig `json:"lottery"`
+	// Turnover keeps half-days free after each departure for cleaning.
+	Turnover *turnoverConfig `json:"turnover"`
+	// Attachments limits the files attached to reservations.
+	Attachments *attachmentsConfig `json:"attachments"`
+	// Checklist lists the tasks of every stay.
+	Checklist *checklistConfig `json:"checklist"`
+	// MeterPrices is the unit price of each meter ("electricity", "water",
+	// "gas"), per kWh or cubic metre.
+	MeterPrices map[string]float64 `json:"meter_prices"`
+}
+
+// checklistConfig is the routine of every stay: the tasks to do on arrival
+// and before leaving.
+type checklistConfig struct {
+	Arrival   []string `json:"arrival"`
+	Departure []string `json:"departure"`
+}
+
+// attachmentsConfig bounds the size of an attachment, in megabytes, and the
+// content types accepted (default: JPEG, PNG and GIF images, PDF documents).
+type attachmentsConfig struct {
+	MaxMB int      `json:"max_mb"`
+	Types []string `json:"types"`
+}
+
+// turnoverConfig is the buffer kept free after each departure, in half-days.
+// CleaningBlocks publishes it as cleaning blocks in the API and the feed.
+type turnoverConfig struct {
+	HalfDays       int  `json:"half_days"`
+	CleaningBlocks bool `json:"cleaning_blocks"`
+}
+
+// lotteryConfig describes the allocation rounds of peak periods and how
+// their priority order is drawn ("lottery" or "rotation").
+type lotteryConfig struct {
+	Method string        `json:"method"`
+	Rounds []roundConfig `json:"rounds"`
+}
+
+// roundConfig is an allocation round. Wishes are accepted from Opens to
+// Closes ("YYYY-MM-DD", both included); each period runs from the afternoon
+// of its From day to the morning of its To day.
+type roundConfig struct {
+	ID              string         `json:"id"`
+	Name            string         `json:"name"`
+	Opens           string         `json:"opens"`
+	Closes          string         `json:"closes"`
+	Seed            *int64         `json:"seed"`
+	MaxPerHousehold int            `json:"max_per_household"`
+	Periods         []periodConfig `json:"periods"`
+}
+
+// ruleConfig is a booking rule. It applies all year, or only to the stays
+// touching the "MM-DD" period given by From and To.
+type ruleConfig struct {
+	Name            string   `json:"name"`
+	From            string   `json:"from"`
+	To              string   `json:"to"`
+	MinNights       int      `json:"min_nights"`
+	ChangeoverDays  []string `json:"changeover_days"`
+	MaxAdvanceDays  int      `json:"max_advance_days"`
+	NoSingleHalfDay bool     `json:"no_single_half_day"`
+}
+
+// quotasConfig limits the yearly use of the property. The default limits
+// apply to every household unless overridden under "households".
+type quotasConfig struct {
+	Policy     string                 `json:"policy"`
+	HighSeason []periodConfig         `json:"high_season"`
+	Households map[string]quotaConfig `json:"households"`
+	quotaConfig
+}
+
+// quotaConfig holds the limits of a household; zero means no limit.
+type quotaConfig struct {
+	NightsPerYear   *int `json:"nights_per_year"`
+	HighSeasonWeeks *int `json:"high_season_weeks"`
+}
+
+// smtpConfig describes the mail server sending e-mail notifications.
+type smtpConfig struct {
+	Host     string `json:"host"`
+	Port     int    `json:"port"`
+	Username string `json:"username"`
+	From     string `json:"from"`
+}
+
+// approvalConfig lists the yearly periods where new bookings stay tentative
+// until approved, either by an admin ("admin") or by a majority of the other
+// households ("majority").
+type approvalConfig struct {
+	Mode    string         `json:"mode"`
+	Periods []periodConfig `json:"periods"`
+}
+
+// periodConfig is a named yearly period given as "MM-DD" bounds, both
+// included.
+type periodConfig struct {
+	Name string `json:"name"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// roomConfig describes a bedroom reservations may claim.
+type roomConfig struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Beds int    `json:"beds"`
+}
+
+// personConfig describes an entry of the "people" list, i.e. a household.
+// Entries may be plain strings (the display name) or objects carrying an
+// explicit colour, a short label, an e-mail address and the household members.
+type personConfig struct {
+	Name    string         `json:"name"`
+	Color   string         `json:"color"`
+	Short   string         `json:"short"`
+	Email   string         `json:"email"`
+	Members []memberConfig `json:"members"`
+}
+
+func (p *personConfig) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*p = personConfig{Name: name}
+		return nil
+	}
+
+	type plain personConfig
+	var value plain
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	*p = personConfig(value)
+	return nil
+}
+
+// memberConfig describes an individual of a household. Like people, members
+// may be given as plain names.
+type memberConfig struct {
+	Name    string   `json:"name"`
+	Email   string   `json:"email"`
+	Webhook string   `json:"webhook"`
+	Notify  []string `json:"notify"`
+}
+
+func (m *memberConfig) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*m = memberConfig{Name: name}
+		return nil
+	}
+
+	type plain memberConfig
+	var value plain
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	*m = memberConfig(value)
+	return nil
+}
+
+type authConfig struct {
+	Password     string            `json:"password"`
+	Hint         string            `json:"hint"`
+	Members      map[string]string `json:"members"`
+	SMTPPassword string            `json:"smtp_password"`
+}
+
+func loadConfig(path string) appConfig {
+	cfg := defaultConfig()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Printf("warning: unable to read config %q: %v (using defaults)", path, err)
+		}
+		applyConfigDefaults(&cfg)
+		return cfg
+	}
+
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		log.Printf("warning: unable to parse config %q: %v (using defaults)", path, err)
+		cfg = defaultConfig()
+	}
+
+	applyConfigDefaults(&cfg)
+	return cfg
+}
+
+func loadAuthConfig(path string) authConfig {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			log.Fatalf("auth configuration %q introuvable", path)
+		}
+		log.Fatalf("lecture de la configuration auth %q impossible: %v", path, err)
+	}
+
+	var cfg authConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		log.Fatalf("analyse de la configuration auth %q impossible: %v", path, err)
+	}
+
+	cfg.Password = strings.TrimSpace(cfg.Password)
+	cfg.Hint = strings.TrimSpace(cfg.Hint)
+	for name, password := range cfg.Members {
+		password = strings.TrimSpace(password)
+		if password == "" {
+			delete(cfg.Members, name)
+			continue
+		}
+		cfg.Members[name] = password
+	}
+	if cfg.Password == "" {
+		log.Fatalf("la configuration auth %q doit contenir un mot de passe non vide", path)
+	}
+
+	return cfg
+}
+
+func defaultConfig() appConfig {
+	return appConfig{
+		propertyConfig: propertyConfig{
+			People: []personConfig{
+				{Name: "Annabelle"},
+				{Name: "Florence"},
+				{Name: "Gregoire"},
+				{Name: "Manon"},
+				{Name: "Valentin"},
+				{Name: "Yves"},
+			},
+			PageTitle:   "Reservations appartement",
+			BannerTitle: "Planning des 18 prochains mois",
+			BasePath:    "",
+		},
+	}
+}
+
+// applyConfigDefaults fills the top-level settings, then derives every
+// property from them. Without "properties", the top-level settings form the
+// single property, stored in data/reservations.db as before.
+func applyConfigDefaults(cfg *appConfig) {
+	def := defaultConfig()
+	if len(cfg.People) == 0 {
+		cfg.People = def.People
+	}
+	if cfg.PageTitle == "" {
+		cfg.PageTitle = def.PageTitle
+	}
+	if cfg.BannerTitle == "" {
+		cfg.BannerTitle = def.BannerTitle
+	}
+	if cfg.Auth == "" {
+		cfg.Auth = "auth.json"
+	}
+	if cfg.Timezone == "" {
+		cfg.Timezone = "Europe/Paris"
+	}
+
+	if len(cfg.Properties) == 0 {
+		single := cfg.propertyConfig
+		if single.Database == "" {
+			single.Database = filepath.Join("data", "reservations.db")
+		}
+		if single.AttachmentsDir == "" {
+			single.AttachmentsDir = filepath.Join("data", "attachments")
+		}
+		single.BasePath = sanitiseBasePath(single.BasePath)
+		single.CapacityPolicy = sanitiseCapacityPolicy(single.CapacityPolicy)
+		cfg.Properties = []propertyConfig{single}
+		return
+	}
+
+	ids := make(map[string]bool, len(cfg.Properties))
+	paths := make(map[string]bool, len(cfg.Properties))
+	for i := range cfg.Properties {
+		prop := &cfg.Properties[i]
+		prop.ID = strings.TrimSpace(prop.ID)
+		if prop.ID == "" {
+			log.Fatalf("property #%d must have an id", i+1)
+		}
+		if ids[prop.ID] {
+			log.Fatalf("property %q is declared more than once", prop.ID)
+		}
+		ids[prop.ID] = true
+
+		if prop.Name == "" {
+			prop.Name = prop.ID
+		}
+		if len(prop.People) == 0 {
+			prop.People = cfg.People
+		}
+		if prop.PageTitle == "" {
+			prop.PageTitle = cfg.PageTitle
+		}
+		if prop.BannerTitle == "" {
+			prop.BannerTitle = cfg.BannerTitle
+		}
+		if prop.CapacityPolicy == "" {
+			prop.CapacityPolicy = cfg.CapacityPolicy
+		}
+		if prop.Auth == "" {
+			prop.Auth = cfg.Auth
+		}
+		if prop.Timezone == "" {
+			prop.Timezone = cfg.Timezone
+		}
+		if prop.Admins == nil {
+			prop.Admins = cfg.Admins
+		}
+		if prop.Approval == nil {
+			prop.Approval = cfg.Approval
+		}
+		if prop.SMTP == nil {
+			prop.SMTP = cfg.SMTP
+		}
+		if prop.Quotas == nil {
+			prop.Quotas = cfg.Quotas
+		}
+		if prop.SchoolHolidays == nil {
+			prop.SchoolHolidays = cfg.SchoolHolidays
+		}
+		if prop.Rules == nil {
+			prop.Rules = cfg.Rules
+		}
+		if prop.Lottery == nil {
+			prop.Lottery = cfg.Lottery
+		}
+		if prop.Turnover == nil {
+			prop.Turnover = cfg.Turnover
+		}
+		if prop.Attachments == nil {
+			prop.Attachments = cfg.Attachments
+		}
+		if prop.Checklist == nil {
+			prop.Checklist = cfg.Checklist
+		}
+		if prop.MeterPrices == nil {
+			prop.MeterPrices = cfg.MeterPrices
+		}
+		if prop.Database == "" {
+			prop.Database = filepath.Join("data", prop.ID+".db")
+		}
+		if prop.AttachmentsDir == "" {
+			prop.AttachmentsDir = filepath.Join("data", "attachments", prop.ID)
+		}
+		if prop.BasePath == "" {
+			prop.BasePath = prop.ID
+		}
+		prop.BasePath = sanitiseBasePath(prop.BasePath)
+		prop.CapacityPolicy = sanitiseCapacityPolicy(prop.CapacityPolicy)
+
+		if prop.BasePath == "" {
+			log.Fatalf("property %q: base_path must not be the root", prop.ID)
+		}
+		if paths[prop.BasePath] {
+			log.Fatalf("property %q: base_path %q is already used", prop.ID, prop.BasePath)
+		}
+		paths[prop.BasePath] = true
+	}
+}
+
+func sanitiseBasePath(value string) string {
+	trimmed := strings.TrimSpace(value)
+	if trimmed == "" || trimmed == "/" {
+		return ""
+	}
+	if !strings.HasPrefix(trimmed, "/") {
+		trimmed = "/" + trimmed
+	}
+	trimmed = strings.TrimRight(trimmed, "/")
+	return trimmed
+}
+
+func sanitiseCapacityPolicy(value string) string {
+	switch policy := strings.ToLower(strings.TrimSpace(value)); policy {
+	case "", "reject":
+		return "reject"
+	case "warn":
+		return policy
+	default:
+		log.Printf("warning: unknown capacity_policy %q (using \"reject\")", value)
+		return "reject"
+	}
+}
@@ -0,0 +1,53 @@
+// Package fold normalises text for matching that ignores case and accents,
+// as French names and words call for: "Joëlle" and "joelle" fold alike.
+package fold
+
+import (
+	"strings"
+	"unicode"
+)
+
+// String lowers s and strips the accents of Latin letters, keeping one rune
+// per rune of s so that positions in the result match those in s.
+func String(s string) string {
+	return strings.Map(foldRune, s)
+}
+
+func foldRune(r rune) rune {
+	r = unicode.ToLower(r)
+	if folded, ok := accents[r]; ok {
+		return folded
+	}
+	return r
+}
+
+var accents = func() map[rune]rune {
+	table := map[rune]string{
+		'a': "àáâãäåāăą",
+		'c': "çćĉċč",
+		'd': "ďđ",
+		'e': "èéêëēĕėęě",
+		'g': "ĝğġģ",
+		'h': "ĥħ",
+		'i': "ìíîïĩīĭįı",
+		'j': "ĵ",
+		'k': "ķ",
+		'l': "ĺļľŀł",
+		'n': "ñńņňŉ",
+		'o': "òóôõöøōŏő",
+		'r': "ŕŗř",
+		's': "śŝşšș",
+		't': "ţťŧț",
+		'u': "ùúûüũūŭůűų",
+		'w': "ŵ",
+		'y': "ýÿŷ",
+		'z': "źżž",
+	}
+	out := make(map[rune]rune)
+	for base, variants := range table {
+		for _, variant := range variants {
+			out[variant] = base
+		}
+	}
+	return out
+}()
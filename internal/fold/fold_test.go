@@ -0,0 +1,30 @@
+package fold
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"", ""},
+		{"joelle", "joelle"},
+		{"Joëlle", "joelle"},
+		{"NOËL À CHÂTEAU-D'OLÉRON", "noel a chateau-d'oleron"},
+		{"Ça s'écrit œuf", "ca s'ecrit œuf"},
+		{"Łódź", "lodz"},
+		{"Ärger über Öl", "arger uber ol"},
+	}
+	for _, tt := range tests {
+		got := String(tt.in)
+		if got != tt.want {
+			t.Errorf("String(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+		if utf8.RuneCountInString(got) != utf8.RuneCountInString(tt.in) {
+			t.Errorf("String(%q) changed the rune count", tt.in)
+		}
+	}
+}
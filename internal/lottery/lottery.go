@@ -0,0 +1,89 @@
+// Package lottery allocates peak periods among households from their ranked
+// wishes. The allocation is deterministic: the same inputs and seed always
+// give the same result, so anybody can check a draw afterwards.
+package lottery
+
+import (
+	"math/rand/v2"
+	"sort"
+)
+
+// Priority methods.
+const (
+	// MethodLottery orders the households by a seeded shuffle.
+	MethodLottery = "lottery"
+	// MethodRotation puts first the households that won the fewest periods
+	// in past rounds; the seeded shuffle breaks ties.
+	MethodRotation = "rotation"
+)
+
+// Wish is a period a household asks for; rank 1 is its first choice.
+type Wish struct {
+	Household string
+	Rank      int
+	Period    string
+}
+
+// Allocation is a period given to a household.
+type Allocation struct {
+	Period    string
+	Household string
+	Rank      int
+}
+
+// Priority returns the order in which households pick their periods. wins
+// counts the periods each household won in past rounds and only matters for
+// the rotation method.
+func Priority(method string, seed int64, households []string, wins map[string]int) []string {
+	order := append([]string(nil), households...)
+	random := rand.New(rand.NewPCG(uint64(seed), 0))
+	random.Shuffle(len(order), func(i, j int) {
+		order[i], order[j] = order[j], order[i]
+	})
+
+	if method == MethodRotation {
+		sort.SliceStable(order, func(i, j int) bool {
+			return wins[order[i]] < wins[order[j]]
+		})
+	}
+	return order
+}
+
+// Allocate gives the periods away in turns: following the priority order,
+// each household receives its best-ranked wish still free, then the next
+// turn starts, until nobody can receive anything more. A household receives
+// at most max periods (no limit when zero); unavailable periods are skipped.
+func Allocate(order []string, wishes []Wish, available map[string]bool, max int) []Allocation {
+	byHousehold := make(map[string][]Wish, len(order))
+	for _, wish := range wishes {
+		byHousehold[wish.Household] = append(byHousehold[wish.Household], wish)
+	}
+	for _, list := range byHousehold {
+		sort.SliceStable(list, func(i, j int) bool {
+			return list[i].Rank < list[j].Rank
+		})
+	}
+
+	var out []Allocation
+	taken := make(map[string]bool)
+	received := make(map[string]int)
+	for progress := true; progress; {
+		progress = false
+		for _, household := range order {
+			if max > 0 && received[household] >= max {
+				continue
+			}
+			for _, wish := range byHousehold[household] {
+				if taken[wish.Period] || !available[wish.Period] {
+					continue
+				}
+				taken[wish.Period] = true
+				received[household]++
+				out = append(out, Allocation{Period: wish.Period, Household: household, Rank: wish.Rank})
+				progress = true
+				break
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,139 @@
+package lottery
+
+import (
+	"slices"
+	"testing"
+)
+
+var households = []string{"Manon", "Noel", "Lucie", "Paul", "Jeanne"}
+
+func TestPrioritySeeded(t *testing.T) {
+	// Published draws must stay reproducible: the order of a given seed
+	// must never change.
+	first := Priority(MethodLottery, 42, households, nil)
+	if want := []string{"Lucie", "Noel", "Paul", "Manon", "Jeanne"}; !slices.Equal(first, want) {
+		t.Fatalf("seed 42 gave %v, want %v", first, want)
+	}
+	if again := Priority(MethodLottery, 42, households, nil); !slices.Equal(first, again) {
+		t.Fatalf("same seed gave %v then %v", first, again)
+	}
+
+	sorted := slices.Clone(first)
+	slices.Sort(sorted)
+	want := slices.Clone(households)
+	slices.Sort(want)
+	if !slices.Equal(sorted, want) {
+		t.Fatalf("order %v is not a permutation of %v", first, households)
+	}
+
+	differs := false
+	for seed := int64(1); seed <= 10 && !differs; seed++ {
+		differs = !slices.Equal(first, Priority(MethodLottery, seed, households, nil))
+	}
+	if !differs {
+		t.Errorf("ten other seeds all gave %v", first)
+	}
+	if households[0] != "Manon" {
+		t.Errorf("Priority reordered its input: %v", households)
+	}
+}
+
+func TestPriorityRotation(t *testing.T) {
+	wins := map[string]int{"Manon": 2, "Noel": 1, "Paul": 1}
+	for seed := int64(0); seed < 20; seed++ {
+		shuffled := Priority(MethodLottery, seed, households, nil)
+		got := Priority(MethodRotation, seed, households, wins)
+
+		// Households without wins come first, then one win, then two; the
+		// shuffle keeps deciding within each group.
+		var want []string
+		for _, count := range []int{0, 1, 2} {
+			for _, household := range shuffled {
+				if wins[household] == count {
+					want = append(want, household)
+				}
+			}
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("seed %d: rotation order = %v, want %v", seed, got, want)
+		}
+	}
+}
+
+func TestAllocate(t *testing.T) {
+	all := map[string]bool{"Noel": true, "Nouvel an": true, "Aout 1": true, "Aout 2": true}
+	tests := []struct {
+		name      string
+		order     []string
+		wishes    []Wish
+		available map[string]bool
+		max       int
+		want      []Allocation
+	}{
+		{
+			name:  "first in order gets the first choice",
+			order: []string{"Noel", "Manon"},
+			wishes: []Wish{
+				{Household: "Manon", Rank: 1, Period: "Noel"},
+				{Household: "Manon", Rank: 2, Period: "Nouvel an"},
+				{Household: "Noel", Rank: 1, Period: "Noel"},
+			},
+			available: all,
+			want: []Allocation{
+				{Period: "Noel", Household: "Noel", Rank: 1},
+				{Period: "Nouvel an", Household: "Manon", Rank: 2},
+			},
+		},
+		{
+			name:  "turns alternate between households",
+			order: []string{"Manon", "Noel"},
+			wishes: []Wish{
+				{Household: "Manon", Rank: 2, Period: "Aout 1"},
+				{Household: "Manon", Rank: 1, Period: "Noel"},
+				{Household: "Manon", Rank: 3, Period: "Aout 2"},
+				{Household: "Noel", Rank: 1, Period: "Aout 1"},
+			},
+			available: all,
+			want: []Allocation{
+				{Period: "Noel", Household: "Manon", Rank: 1},
+				{Period: "Aout 1", Household: "Noel", Rank: 1},
+				{Period: "Aout 2", Household: "Manon", Rank: 3},
+			},
+		},
+		{
+			name:  "limit per household",
+			order: []string{"Manon"},
+			wishes: []Wish{
+				{Household: "Manon", Rank: 1, Period: "Noel"},
+				{Household: "Manon", Rank: 2, Period: "Nouvel an"},
+			},
+			available: all,
+			max:       1,
+			want:      []Allocation{{Period: "Noel", Household: "Manon", Rank: 1}},
+		},
+		{
+			name:  "unavailable periods are skipped",
+			order: []string{"Manon"},
+			wishes: []Wish{
+				{Household: "Manon", Rank: 1, Period: "Noel"},
+				{Household: "Manon", Rank: 2, Period: "Nouvel an"},
+			},
+			available: map[string]bool{"Nouvel an": true},
+			want:      []Allocation{{Period: "Nouvel an", Household: "Manon", Rank: 2}},
+		},
+		{
+			name:      "households outside the order receive nothing",
+			order:     []string{"Manon"},
+			wishes:    []Wish{{Household: "Paul", Rank: 1, Period: "Noel"}},
+			available: all,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Allocate(tt.order, tt.wishes, tt.available, tt.max)
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("Allocate = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,289 @@
+// Package media checks uploaded files and prepares images for storage: they
+// are decoded and re-encoded, which drops their metadata (EXIF location,
+// camera details, comments), and get a small thumbnail.
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+// Supported content types. Only these images can be re-encoded with the
+// standard library; PDF documents are stored as uploaded.
+const (
+	TypeJPEG = "image/jpeg"
+	TypePNG  = "image/png"
+	TypeGIF  = "image/gif"
+	TypePDF  = "application/pdf"
+)
+
+// DefaultTypes lists the content types accepted unless configured otherwise.
+var DefaultTypes = []string{TypeJPEG, TypePNG, TypeGIF, TypePDF}
+
+const (
+	// maxPixels bounds the decoded size of an image, protecting the server
+	// from small files expanding to huge bitmaps.
+	maxPixels = 50_000_000
+	// ThumbnailSize is the longest side of a thumbnail, in pixels.
+	ThumbnailSize = 320
+)
+
+var (
+	// ErrUnsupported is returned for content types that are not handled.
+	ErrUnsupported = errors.New("unsupported file type")
+	// ErrTooLarge is returned for images of too many pixels.
+	ErrTooLarge = errors.New("image is too large")
+)
+
+// Supported reports whether the content type can be stored.
+func Supported(contentType string) bool {
+	for _, known := range DefaultTypes {
+		if known == contentType {
+			return true
+		}
+	}
+	return false
+}
+
+// IsImage reports whether the content type is an image that gets
+// re-encoded and a thumbnail.
+func IsImage(contentType string) bool {
+	return contentType == TypeJPEG || contentType == TypePNG || contentType == TypeGIF
+}
+
+// Detect returns the content type of data, sniffed from its first bytes
+// rather than trusted from the client.
+func Detect(data []byte) string {
+	return http.DetectContentType(data)
+}
+
+// Sanitise returns the file to store for data of the given content type:
+// images are decoded and re-encoded without their metadata, JPEG photos
+// being turned upright first according to their EXIF orientation; other
+// supported files are returned as is.
+func Sanitise(data []byte, contentType string) ([]byte, error) {
+	if !Supported(contentType) {
+		return nil, ErrUnsupported
+	}
+	if !IsImage(contentType) {
+		return data, nil
+	}
+	if err := checkDimensions(data); err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	switch contentType {
+	case TypeJPEG:
+		img, err := jpeg.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		img = orient(img, jpegOrientation(data))
+		if err := jpeg.Encode(&out, img, &jpeg.Options{Quality: 90}); err != nil {
+			return nil, err
+		}
+	case TypePNG:
+		img, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		if err := png.Encode(&out, img); err != nil {
+			return nil, err
+		}
+	case TypeGIF:
+		anim, err := gif.DecodeAll(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		if err := gif.EncodeAll(&out, anim); err != nil {
+			return nil, err
+		}
+	}
+	return out.Bytes(), nil
+}
+
+// Thumbnail returns a JPEG thumbnail of an image stored by Sanitise, its
+// longest side reduced to ThumbnailSize.
+func Thumbnail(data []byte) ([]byte, error) {
+	if err := checkDimensions(data); err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	if err := jpeg.Encode(&out, shrink(img, ThumbnailSize), &jpeg.Options{Quality: 80}); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+func checkDimensions(data []byte) error {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxPixels {
+		return ErrTooLarge
+	}
+	return nil
+}
+
+// shrink scales img down so that its longest side is at most size, averaging
+// the source pixels covered by each destination pixel. Transparent areas are
+// laid on white, JPEG having no alpha channel.
+func shrink(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	dstWidth, dstHeight := width, height
+	if width > size || height > size {
+		if width >= height {
+			dstWidth, dstHeight = size, max(1, height*size/width)
+		} else {
+			dstWidth, dstHeight = max(1, width*size/height), size
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+	for y := 0; y < dstHeight; y++ {
+		y0 := bounds.Min.Y + y*height/dstHeight
+		y1 := max(y0+1, bounds.Min.Y+(y+1)*height/dstHeight)
+		for x := 0; x < dstWidth; x++ {
+			x0 := bounds.Min.X + x*width/dstWidth
+			x1 := max(x0+1, bounds.Min.X+(x+1)*width/dstWidth)
+
+			var r, g, b, count uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := img.At(sx, sy).RGBA()
+					// Blend the premultiplied colour over white.
+					white := 0xffff - uint64(ca)
+					r += uint64(cr) + white
+					g += uint64(cg) + white
+					b += uint64(cb) + white
+					count++
+				}
+			}
+			dst.Set(x, y, color.RGBA64{
+				R: uint16(r / count),
+				G: uint16(g / count),
+				B: uint16(b / count),
+				A: 0xffff,
+			})
+		}
+	}
+	return dst
+}
+
+// jpegOrientation reads the EXIF orientation of a JPEG file, 1 (upright)
+// when absent or unreadable.
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xff || data[1] != 0xd8 {
+		return 1
+	}
+	for pos := 2; pos+4 <= len(data); {
+		if data[pos] != 0xff {
+			return 1
+		}
+		marker := data[pos+1]
+		if marker == 0xda || marker == 0xd9 {
+			// Start of scan or end of image: no EXIF segment.
+			return 1
+		}
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		if length < 2 || pos+2+length > len(data) {
+			return 1
+		}
+		segment := data[pos+4 : pos+2+length]
+		if marker == 0xe1 && len(segment) > 6 && string(segment[:6]) == "Exif\x00\x00" {
+			return exifOrientation(segment[6:])
+		}
+		pos += 2 + length
+	}
+	return 1
+}
+
+// exifOrientation reads the orientation tag of the first IFD of a TIFF
+// header.
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	offset := int(order.Uint32(tiff[4:]))
+	if offset < 8 || offset+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[offset:]))
+	for i := 0; i < entries; i++ {
+		entry := offset + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			value := int(order.Uint16(tiff[entry+8:]))
+			if value < 1 || value > 8 {
+				return 1
+			}
+			return value
+		}
+	}
+	return 1
+}
+
+// orient applies an EXIF orientation (2 to 8) to img.
+func orient(img image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	transposed := orientation >= 5
+	dstWidth, dstHeight := width, height
+	if transposed {
+		dstWidth, dstHeight = height, width
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // mirrored
+				dx, dy = width-1-x, y
+			case 3: // rotated 180°
+				dx, dy = width-1-x, height-1-y
+			case 4: // mirrored vertically
+				dx, dy = x, height-1-y
+			case 5: // transposed
+				dx, dy = y, x
+			case 6: // rotated 90° clockwise
+				dx, dy = height-1-y, x
+			case 7: // transversed
+				dx, dy = height-1-y, width-1-x
+			case 8: // rotated 90° counter-clockwise
+				dx, dy = y, width-1-x
+			}
+			dst.Set(dx, dy, img.At(bounds.Min.X+x, bounds.Min.Y+y))
+		}
+	}
+	return dst
+}
@@ -0,0 +1,201 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// withOrientation inserts an EXIF segment carrying the orientation right
+// after the start of image marker of a JPEG file.
+func withOrientation(t *testing.T, data []byte, order binary.ByteOrder, orientation uint16) []byte {
+	t.Helper()
+	tiff := make([]byte, 8+2+12+4)
+	if order == binary.LittleEndian {
+		copy(tiff, "II")
+	} else {
+		copy(tiff, "MM")
+	}
+	order.PutUint16(tiff[2:], 42)
+	order.PutUint32(tiff[4:], 8)
+	order.PutUint16(tiff[8:], 1)
+	order.PutUint16(tiff[10:], 0x0112) // orientation tag
+	order.PutUint16(tiff[12:], 3)      // SHORT
+	order.PutUint32(tiff[14:], 1)
+	order.PutUint16(tiff[18:], orientation)
+
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+	header := []byte{0xff, 0xe1, 0, 0}
+	binary.BigEndian.PutUint16(header[2:], uint16(len(segment)+2))
+
+	out := append([]byte(nil), data[:2]...)
+	out = append(out, header...)
+	out = append(out, segment...)
+	return append(out, data[2:]...)
+}
+
+func encodeJPEG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestJPEGOrientation(t *testing.T) {
+	plain := encodeJPEG(t, image.NewGray(image.Rect(0, 0, 4, 2)))
+	tests := []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{"no EXIF", plain, 1},
+		{"big endian", withOrientation(t, plain, binary.BigEndian, 6), 6},
+		{"little endian", withOrientation(t, plain, binary.LittleEndian, 8), 8},
+		{"out of range", withOrientation(t, plain, binary.BigEndian, 9), 1},
+		{"not a JPEG", []byte("%PDF-1.7"), 1},
+		{"truncated", withOrientation(t, plain, binary.BigEndian, 3)[:20], 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := jpegOrientation(tt.data); got != tt.want {
+				t.Fatalf("jpegOrientation = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrient(t *testing.T) {
+	// A 3×2 image with marked top corners.
+	topLeft := color.RGBA{R: 255, A: 255}
+	topRight := color.RGBA{G: 255, A: 255}
+	src := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	src.Set(0, 0, topLeft)
+	src.Set(2, 0, topRight)
+
+	tests := []struct {
+		orientation   int
+		width, height int
+		left, right   image.Point
+	}{
+		{1, 3, 2, image.Pt(0, 0), image.Pt(2, 0)},
+		{2, 3, 2, image.Pt(2, 0), image.Pt(0, 0)},
+		{3, 3, 2, image.Pt(2, 1), image.Pt(0, 1)},
+		{4, 3, 2, image.Pt(0, 1), image.Pt(2, 1)},
+		{5, 2, 3, image.Pt(0, 0), image.Pt(0, 2)},
+		{6, 2, 3, image.Pt(1, 0), image.Pt(1, 2)},
+		{7, 2, 3, image.Pt(1, 2), image.Pt(1, 0)},
+		{8, 2, 3, image.Pt(0, 2), image.Pt(0, 0)},
+	}
+	for _, tt := range tests {
+		got := orient(src, tt.orientation)
+		if size := got.Bounds().Size(); size != image.Pt(tt.width, tt.height) {
+			t.Errorf("orientation %d: size = %v, want %dx%d", tt.orientation, size, tt.width, tt.height)
+			continue
+		}
+		if c := color.RGBAModel.Convert(got.At(tt.left.X, tt.left.Y)); c != topLeft {
+			t.Errorf("orientation %d: top left corner not at %v", tt.orientation, tt.left)
+		}
+		if c := color.RGBAModel.Convert(got.At(tt.right.X, tt.right.Y)); c != topRight {
+			t.Errorf("orientation %d: top right corner not at %v", tt.orientation, tt.right)
+		}
+	}
+}
+
+func TestSanitise(t *testing.T) {
+	rotated := withOrientation(t, encodeJPEG(t, image.NewGray(image.Rect(0, 0, 8, 4))), binary.BigEndian, 6)
+	pdf := []byte("%PDF-1.7\n%%EOF\n")
+
+	// A PNG header claiming far more pixels than allowed, with a valid
+	// checksum.
+	huge := encodePNG(t, image.NewGray(image.Rect(0, 0, 1, 1)))
+	binary.BigEndian.PutUint32(huge[16:], 20000)
+	binary.BigEndian.PutUint32(huge[20:], 20000)
+	binary.BigEndian.PutUint32(huge[29:], crc32.ChecksumIEEE(huge[12:29]))
+
+	tests := []struct {
+		name        string
+		data        []byte
+		contentType string
+		err         error
+		size        image.Point
+	}{
+		{"JPEG turned upright", rotated, TypeJPEG, nil, image.Pt(4, 8)},
+		{"PNG re-encoded", encodePNG(t, image.NewGray(image.Rect(0, 0, 5, 3))), TypePNG, nil, image.Pt(5, 3)},
+		{"PDF kept", pdf, TypePDF, nil, image.Point{}},
+		{"unsupported type", []byte("hello"), "text/plain", ErrUnsupported, image.Point{}},
+		{"too many pixels", huge, TypePNG, ErrTooLarge, image.Point{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Sanitise(tt.data, tt.contentType)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Sanitise error = %v, want %v", err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+			if !IsImage(tt.contentType) {
+				if !bytes.Equal(got, tt.data) {
+					t.Fatalf("Sanitise changed a %s file", tt.contentType)
+				}
+				return
+			}
+			if bytes.Contains(got, []byte("Exif")) {
+				t.Errorf("Sanitise kept the EXIF segment")
+			}
+			cfg, _, err := image.DecodeConfig(bytes.NewReader(got))
+			if err != nil {
+				t.Fatalf("decoding the result: %v", err)
+			}
+			if size := image.Pt(cfg.Width, cfg.Height); size != tt.size {
+				t.Errorf("size = %v, want %v", size, tt.size)
+			}
+		})
+	}
+}
+
+func TestThumbnail(t *testing.T) {
+	tests := []struct {
+		width, height int
+		want          image.Point
+	}{
+		{640, 320, image.Pt(ThumbnailSize, 160)},
+		{200, 1000, image.Pt(64, ThumbnailSize)},
+		{100, 50, image.Pt(100, 50)},
+	}
+	for _, tt := range tests {
+		data := encodePNG(t, image.NewNRGBA(image.Rect(0, 0, tt.width, tt.height)))
+		thumb, err := Thumbnail(data)
+		if err != nil {
+			t.Fatalf("Thumbnail(%dx%d): %v", tt.width, tt.height, err)
+		}
+		img, err := jpeg.Decode(bytes.NewReader(thumb))
+		if err != nil {
+			t.Fatalf("decoding the thumbnail: %v", err)
+		}
+		if size := img.Bounds().Size(); size != tt.want {
+			t.Errorf("Thumbnail(%dx%d) size = %v, want %v", tt.width, tt.height, size, tt.want)
+		}
+		// A transparent image is laid on white.
+		if r, g, b, _ := img.At(0, 0).RGBA(); r < 0xf000 || g < 0xf000 || b < 0xf000 {
+			t.Errorf("Thumbnail(%dx%d) corner = %v, want white", tt.width, tt.height, img.At(0, 0))
+		}
+	}
+}
@@ -0,0 +1,63 @@
+// Package mention finds the people named with an "@" in a message. Names are
+// matched regardless of case and accents, so "@joelle" mentions Joëlle, and
+// may contain spaces, as household names such as "Joëlle et Yves" do.
+package mention
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"AppartmentBooker/internal/fold"
+)
+
+// Find returns the names of the list mentioned in text, in their configured
+// spelling and in order of first appearance. When several names start alike,
+// the longest one written wins: "@Joëlle et Yves" mentions the household,
+// not Joëlle alone.
+func Find(text string, names []string) []string {
+	candidates := make([][]rune, 0, len(names))
+	byFolded := make(map[string]string, len(names))
+	for _, name := range names {
+		folded := fold.String(strings.TrimSpace(name))
+		if folded == "" {
+			continue
+		}
+		if _, ok := byFolded[folded]; ok {
+			continue
+		}
+		byFolded[folded] = name
+		candidates = append(candidates, []rune(folded))
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return len(candidates[i]) > len(candidates[j]) })
+
+	runes := []rune(fold.String(text))
+	var found []string
+	seen := make(map[string]bool)
+	for i, r := range runes {
+		if r != '@' || (i > 0 && isWordRune(runes[i-1])) {
+			// Skip e-mail addresses such as "manon@example.org".
+			continue
+		}
+		rest := runes[i+1:]
+		for _, candidate := range candidates {
+			if len(rest) < len(candidate) || string(rest[:len(candidate)]) != string(candidate) {
+				continue
+			}
+			if len(rest) > len(candidate) && isWordRune(rest[len(candidate)]) {
+				continue
+			}
+			name := byFolded[string(candidate)]
+			if !seen[name] {
+				seen[name] = true
+				found = append(found, name)
+			}
+			break
+		}
+	}
+	return found
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
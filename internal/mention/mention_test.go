@@ -0,0 +1,32 @@
+package mention
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestFind(t *testing.T) {
+	names := []string{"Joëlle", "Joëlle et Yves", "Manon", "Noël", "manon"}
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"none", "Pas de mention ici.", nil},
+		{"plain", "Merci @Manon !", []string{"Manon"}},
+		{"case and accents ignored", "@joelle et @NOEL, a samedi", []string{"Joëlle", "Noël"}},
+		{"longest name wins", "@Joëlle et Yves arrivent vendredi", []string{"Joëlle et Yves"}},
+		{"order of first appearance", "@Noël puis @Manon puis @Noël", []string{"Noël", "Manon"}},
+		{"e-mail addresses ignored", "Ecrire a manon@example.org", nil},
+		{"partial word ignored", "@Manonette n'existe pas", nil},
+		{"punctuation ends the name", "(@Manon)", []string{"Manon"}},
+		{"name at the end", "Demande a @noel", []string{"Noël"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Find(tt.text, names); !slices.Equal(got, tt.want) {
+				t.Fatalf("Find(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
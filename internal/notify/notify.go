@@ -0,0 +1,138 @@
+// Package notify delivers short messages to members by e-mail or webhook.
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Channels supported by the notifier.
+const (
+	ChannelEmail   = "email"
+	ChannelWebhook = "webhook"
+)
+
+// SMTPConfig describes the mail server used for e-mail notifications.
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// Recipient is a member to notify on the channels they subscribed to.
+type Recipient struct {
+	Name     string
+	Email    string
+	Webhook  string
+	Channels []string
+}
+
+// Message is a notification.
+type Message struct {
+	Subject string
+	Body    string
+}
+
+// Notifier sends messages in the background. A nil Notifier drops them.
+type Notifier struct {
+	smtp   SMTPConfig
+	client *http.Client
+}
+
+// New returns a notifier sending e-mails through the provided server. E-mail
+// notifications are skipped when no host is configured.
+func New(cfg SMTPConfig) *Notifier {
+	if cfg.Port == 0 {
+		cfg.Port = 587
+	}
+	return &Notifier{
+		smtp:   cfg,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Send delivers the message to the recipient on each of their channels.
+// Delivery happens in the background; failures are logged.
+func (n *Notifier) Send(recipient Recipient, msg Message) {
+	if n == nil {
+		return
+	}
+	for _, channel := range recipient.Channels {
+		switch channel {
+		case ChannelEmail:
+			if recipient.Email == "" || n.smtp.Host == "" {
+				continue
+			}
+			go func() {
+				if err := n.sendEmail(recipient, msg); err != nil {
+					log.Printf("warning: e-mail to %q failed: %v", recipient.Name, err)
+				}
+			}()
+		case ChannelWebhook:
+			if recipient.Webhook == "" {
+				continue
+			}
+			go func() {
+				if err := n.sendWebhook(recipient, msg); err != nil {
+					log.Printf("warning: webhook for %q failed: %v", recipient.Name, err)
+				}
+			}()
+		}
+	}
+}
+
+func (n *Notifier) sendEmail(recipient Recipient, msg Message) error {
+	from := n.smtp.From
+	if from == "" {
+		from = n.smtp.Username
+	}
+
+	var body bytes.Buffer
+	fmt.Fprintf(&body, "From: %s\r\n", from)
+	fmt.Fprintf(&body, "To: %s\r\n", recipient.Email)
+	fmt.Fprintf(&body, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	body.WriteString("MIME-Version: 1.0\r\n")
+	body.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	body.WriteString("\r\n")
+	body.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	body.WriteString("\r\n")
+
+	var auth smtp.Auth
+	if n.smtp.Username != "" {
+		auth = smtp.PlainAuth("", n.smtp.Username, n.smtp.Password, n.smtp.Host)
+	}
+	addr := net.JoinHostPort(n.smtp.Host, strconv.Itoa(n.smtp.Port))
+	return smtp.SendMail(addr, auth, from, []string{recipient.Email}, body.Bytes())
+}
+
+func (n *Notifier) sendWebhook(recipient Recipient, msg Message) error {
+	payload, err := json.Marshal(map[string]string{
+		"recipient": recipient.Name,
+		"subject":   msg.Subject,
+		"text":      msg.Body,
+	})
+	if err != nil {
+		return err
+	}
+
+	resp, err := n.client.Post(recipient.Webhook, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
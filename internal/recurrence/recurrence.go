@@ -0,0 +1,458 @@
+// Package recurrence parses and expands the subset of RFC 5545 recurrence
+// rules used by recurring reservations.
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frequency is the FREQ part of a rule.
+type Frequency string
+
+// Supported frequencies.
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+	Yearly  Frequency = "YEARLY"
+)
+
+// maxIterations bounds the number of periods examined while expanding a rule
+// whose filters never match.
+const maxIterations = 100000
+
+// WeekdayNum is a BYDAY entry such as "SA" (every Saturday) or "1SA" (the
+// first Saturday of the month, or of the year for yearly rules).
+type WeekdayNum struct {
+	N       int
+	Weekday time.Weekday
+}
+
+// Rule is a parsed RRULE.
+type Rule struct {
+	Freq       Frequency
+	Interval   int
+	Count      int
+	Until      time.Time
+	ByDay      []WeekdayNum
+	ByMonth    []int
+	ByMonthDay []int
+}
+
+var weekdayCodes = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// Parse reads a rule such as "FREQ=YEARLY;BYMONTH=2;BYDAY=1SA". An optional
+// "RRULE:" prefix is accepted. Floating UNTIL values are read in loc.
+func Parse(value string, loc *time.Location) (Rule, error) {
+	if loc == nil {
+		loc = time.Local
+	}
+
+	rule := Rule{Interval: 1}
+	text := strings.TrimPrefix(strings.TrimSpace(value), "RRULE:")
+	if text == "" {
+		return Rule{}, errors.New("empty rule")
+	}
+
+	for _, part := range strings.Split(text, ";") {
+		key, val, ok := strings.Cut(part, "=")
+		if !ok {
+			return Rule{}, fmt.Errorf("invalid rule part %q", part)
+		}
+		key = strings.ToUpper(strings.TrimSpace(key))
+		val = strings.ToUpper(strings.TrimSpace(val))
+
+		switch key {
+		case "FREQ":
+			switch freq := Frequency(val); freq {
+			case Daily, Weekly, Monthly, Yearly:
+				rule.Freq = freq
+			default:
+				return Rule{}, fmt.Errorf("unsupported frequency %q", val)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 {
+				return Rule{}, fmt.Errorf("invalid interval %q", val)
+			}
+			rule.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 {
+				return Rule{}, fmt.Errorf("invalid count %q", val)
+			}
+			rule.Count = n
+		case "UNTIL":
+			until, err := parseUntil(val, loc)
+			if err != nil {
+				return Rule{}, err
+			}
+			rule.Until = until
+		case "BYDAY":
+			for _, item := range strings.Split(val, ",") {
+				day, err := parseWeekdayNum(item)
+				if err != nil {
+					return Rule{}, err
+				}
+				rule.ByDay = append(rule.ByDay, day)
+			}
+		case "BYMONTH":
+			months, err := parseInts(val, 1, 12, false)
+			if err != nil {
+				return Rule{}, fmt.Errorf("invalid BYMONTH: %w", err)
+			}
+			rule.ByMonth = months
+		case "BYMONTHDAY":
+			days, err := parseInts(val, 1, 31, true)
+			if err != nil {
+				return Rule{}, fmt.Errorf("invalid BYMONTHDAY: %w", err)
+			}
+			rule.ByMonthDay = days
+		case "WKST":
+			if val != "MO" {
+				return Rule{}, fmt.Errorf("unsupported WKST %q", val)
+			}
+		default:
+			return Rule{}, fmt.Errorf("unsupported rule part %q", key)
+		}
+	}
+
+	if rule.Freq == "" {
+		return Rule{}, errors.New("FREQ is required")
+	}
+	if rule.Count > 0 && !rule.Until.IsZero() {
+		return Rule{}, errors.New("COUNT and UNTIL are mutually exclusive")
+	}
+	for _, day := range rule.ByDay {
+		if day.N != 0 && rule.Freq != Monthly && rule.Freq != Yearly {
+			return Rule{}, errors.New("numbered BYDAY requires a monthly or yearly rule")
+		}
+	}
+	return rule, nil
+}
+
+// String formats the rule in RFC 5545 syntax, without the "RRULE:" prefix.
+func (r Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	if len(r.ByMonth) > 0 {
+		parts = append(parts, "BYMONTH="+joinInts(r.ByMonth))
+	}
+	if len(r.ByMonthDay) > 0 {
+		parts = append(parts, "BYMONTHDAY="+joinInts(r.ByMonthDay))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, 0, len(r.ByDay))
+		for _, day := range r.ByDay {
+			code := weekdayCode(day.Weekday)
+			if day.N != 0 {
+				code = strconv.Itoa(day.N) + code
+			}
+			days = append(days, code)
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Occurrences returns the start of every occurrence of the rule anchored at
+// dtstart that begins before limit. dtstart is always the first occurrence;
+// the others keep its time of day in its location.
+func (r Rule) Occurrences(dtstart, limit time.Time) []time.Time {
+	var out []time.Time
+	emit := func(t time.Time) bool {
+		if !r.Until.IsZero() && t.After(r.Until) {
+			return false
+		}
+		if !t.Before(limit) {
+			return false
+		}
+		out = append(out, t)
+		return r.Count == 0 || len(out) < r.Count
+	}
+
+	if !emit(dtstart) {
+		return out
+	}
+
+	for period := 0; period < maxIterations; period++ {
+		candidates := r.candidates(dtstart, period)
+		for _, candidate := range candidates {
+			if !candidate.After(dtstart) {
+				continue
+			}
+			if !emit(candidate) {
+				return out
+			}
+		}
+		if r.periodStart(dtstart, period+1).After(limit) {
+			return out
+		}
+		if !r.Until.IsZero() && r.periodStart(dtstart, period+1).After(r.Until) {
+			return out
+		}
+	}
+	return out
+}
+
+// periodStart returns the first day of the nth period after dtstart's.
+func (r Rule) periodStart(dtstart time.Time, n int) time.Time {
+	step := n * r.Interval
+	y, m, d := dtstart.Date()
+	loc := dtstart.Location()
+	switch r.Freq {
+	case Daily:
+		return time.Date(y, m, d+step, 0, 0, 0, 0, loc)
+	case Weekly:
+		monday := d - (int(dtstart.Weekday())+6)%7
+		return time.Date(y, m, monday+7*step, 0, 0, 0, 0, loc)
+	case Monthly:
+		return time.Date(y, m+time.Month(step), 1, 0, 0, 0, 0, loc)
+	default:
+		return time.Date(y+step, time.January, 1, 0, 0, 0, 0, loc)
+	}
+}
+
+// candidates lists the occurrences falling in the nth period, sorted.
+func (r Rule) candidates(dtstart time.Time, n int) []time.Time {
+	start := r.periodStart(dtstart, n)
+	hour, minute, second := dtstart.Clock()
+	loc := dtstart.Location()
+
+	var days []time.Time
+	switch r.Freq {
+	case Daily:
+		if r.matchesFilters(start) {
+			days = append(days, start)
+		}
+	case Weekly:
+		for i := 0; i < 7; i++ {
+			day := start.AddDate(0, 0, i)
+			if len(r.ByDay) == 0 && day.Weekday() != dtstart.Weekday() {
+				continue
+			}
+			if r.matchesFilters(day) {
+				days = append(days, day)
+			}
+		}
+	case Monthly:
+		if len(r.ByMonth) == 0 || containsInt(r.ByMonth, int(start.Month())) {
+			days = r.daysInMonth(start, dtstart)
+		}
+	case Yearly:
+		switch {
+		case len(r.ByMonth) == 0 && len(r.ByMonthDay) == 0 && len(r.ByDay) > 0:
+			days = r.weekdaysInScope(start, start.AddDate(1, 0, 0))
+		case len(r.ByMonth) == 0:
+			days = r.daysInMonth(time.Date(start.Year(), dtstart.Month(), 1, 0, 0, 0, 0, loc), dtstart)
+		default:
+			for _, month := range r.ByMonth {
+				days = append(days, r.daysInMonth(time.Date(start.Year(), time.Month(month), 1, 0, 0, 0, 0, loc), dtstart)...)
+			}
+		}
+	}
+
+	out := make([]time.Time, 0, len(days))
+	for _, day := range days {
+		y, m, d := day.Date()
+		out = append(out, time.Date(y, m, d, hour, minute, second, 0, loc))
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Before(out[j]) })
+	return out
+}
+
+// daysInMonth applies BYMONTHDAY and BYDAY within the month starting at
+// first, defaulting to dtstart's day of month.
+func (r Rule) daysInMonth(first, dtstart time.Time) []time.Time {
+	next := first.AddDate(0, 1, 0)
+	length := next.AddDate(0, 0, -1).Day()
+
+	var byMonthDay []time.Time
+	for _, value := range r.ByMonthDay {
+		day := value
+		if day < 0 {
+			day = length + day + 1
+		}
+		if day >= 1 && day <= length {
+			byMonthDay = append(byMonthDay, first.AddDate(0, 0, day-1))
+		}
+	}
+
+	switch {
+	case len(r.ByMonthDay) > 0 && len(r.ByDay) > 0:
+		var out []time.Time
+		for _, day := range byMonthDay {
+			if r.matchesWeekday(day) {
+				out = append(out, day)
+			}
+		}
+		return out
+	case len(r.ByMonthDay) > 0:
+		return byMonthDay
+	case len(r.ByDay) > 0:
+		return r.weekdaysInScope(first, next)
+	default:
+		if dtstart.Day() > length {
+			return nil
+		}
+		return []time.Time{first.AddDate(0, 0, dtstart.Day()-1)}
+	}
+}
+
+// weekdaysInScope returns the days of [from, to) selected by BYDAY, numbered
+// entries being counted within that scope.
+func (r Rule) weekdaysInScope(from, to time.Time) []time.Time {
+	var out []time.Time
+	for _, entry := range r.ByDay {
+		var matches []time.Time
+		for day := from; day.Before(to); day = day.AddDate(0, 0, 1) {
+			if day.Weekday() == entry.Weekday {
+				matches = append(matches, day)
+			}
+		}
+		switch {
+		case entry.N == 0:
+			out = append(out, matches...)
+		case entry.N > 0 && entry.N <= len(matches):
+			out = append(out, matches[entry.N-1])
+		case entry.N < 0 && -entry.N <= len(matches):
+			out = append(out, matches[len(matches)+entry.N])
+		}
+	}
+	return out
+}
+
+// matchesFilters applies the BYMONTH, BYMONTHDAY and (un-numbered) BYDAY
+// filters used by daily and weekly rules.
+func (r Rule) matchesFilters(day time.Time) bool {
+	if len(r.ByMonth) > 0 && !containsInt(r.ByMonth, int(day.Month())) {
+		return false
+	}
+	if len(r.ByMonthDay) > 0 {
+		length := time.Date(day.Year(), day.Month()+1, 0, 0, 0, 0, 0, day.Location()).Day()
+		matched := false
+		for _, value := range r.ByMonthDay {
+			if value == day.Day() || (value < 0 && length+value+1 == day.Day()) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return len(r.ByDay) == 0 || r.matchesWeekday(day)
+}
+
+func (r Rule) matchesWeekday(day time.Time) bool {
+	for _, entry := range r.ByDay {
+		if entry.Weekday == day.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+func parseUntil(value string, loc *time.Location) (time.Time, error) {
+	switch {
+	case strings.HasSuffix(value, "Z"):
+		return time.Parse("20060102T150405Z", value)
+	case strings.Contains(value, "T"):
+		return time.ParseInLocation("20060102T150405", value, loc)
+	default:
+		day, err := time.ParseInLocation("20060102", value, loc)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid UNTIL %q", value)
+		}
+		// A date-only UNTIL includes the whole day.
+		return day.AddDate(0, 0, 1).Add(-time.Second), nil
+	}
+}
+
+func parseWeekdayNum(value string) (WeekdayNum, error) {
+	value = strings.TrimSpace(value)
+	if len(value) < 2 {
+		return WeekdayNum{}, fmt.Errorf("invalid BYDAY %q", value)
+	}
+	code := value[len(value)-2:]
+	weekday, ok := weekdayCodes[code]
+	if !ok {
+		return WeekdayNum{}, fmt.Errorf("invalid BYDAY %q", value)
+	}
+
+	n := 0
+	if prefix := value[:len(value)-2]; prefix != "" {
+		parsed, err := strconv.Atoi(prefix)
+		if err != nil || parsed == 0 || parsed < -53 || parsed > 53 {
+			return WeekdayNum{}, fmt.Errorf("invalid BYDAY %q", value)
+		}
+		n = parsed
+	}
+	return WeekdayNum{N: n, Weekday: weekday}, nil
+}
+
+func parseInts(value string, min, max int, allowNegative bool) ([]int, error) {
+	var out []int
+	for _, item := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(item))
+		if err != nil {
+			return nil, err
+		}
+		abs := n
+		if n < 0 && allowNegative {
+			abs = -n
+		}
+		if abs < min || abs > max {
+			return nil, fmt.Errorf("%d out of range", n)
+		}
+		out = append(out, n)
+	}
+	return out, nil
+}
+
+func joinInts(values []int) string {
+	parts := make([]string, 0, len(values))
+	for _, value := range values {
+		parts = append(parts, strconv.Itoa(value))
+	}
+	return strings.Join(parts, ",")
+}
+
+func containsInt(values []int, target int) bool {
+	for _, value := range values {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
+
+func weekdayCode(day time.Weekday) string {
+	for code, weekday := range weekdayCodes {
+		if weekday == day {
+			return code
+		}
+	}
+	return ""
+}
@@ -0,0 +1,262 @@
+package recurrence
+
+import (
+	"testing"
+	"time"
+)
+
+func mustLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("time zone %s unavailable: %v", name, err)
+	}
+	return loc
+}
+
+func TestParseRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		rule string
+	}{
+		{"empty", ""},
+		{"missing freq", "INTERVAL=2"},
+		{"unknown freq", "FREQ=HOURLY"},
+		{"zero interval", "FREQ=DAILY;INTERVAL=0"},
+		{"zero count", "FREQ=DAILY;COUNT=0"},
+		{"count and until", "FREQ=DAILY;COUNT=2;UNTIL=20270101"},
+		{"bad until", "FREQ=DAILY;UNTIL=2027"},
+		{"bad weekday", "FREQ=WEEKLY;BYDAY=XX"},
+		{"numbered weekday in weekly rule", "FREQ=WEEKLY;BYDAY=1SA"},
+		{"month out of range", "FREQ=YEARLY;BYMONTH=13"},
+		{"month day out of range", "FREQ=MONTHLY;BYMONTHDAY=32"},
+		{"sunday week start", "FREQ=WEEKLY;WKST=SU"},
+		{"unsupported part", "FREQ=DAILY;BYHOUR=10"},
+		{"malformed part", "FREQ=DAILY;COUNT"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.rule, time.UTC); err == nil {
+				t.Fatalf("Parse(%q) succeeded, want an error", tt.rule)
+			}
+		})
+	}
+}
+
+func TestParseString(t *testing.T) {
+	tests := []struct {
+		rule string
+		want string
+	}{
+		{"FREQ=YEARLY;BYMONTH=2;BYDAY=1SA", "FREQ=YEARLY;BYMONTH=2;BYDAY=1SA"},
+		{"RRULE:freq=weekly;interval=2;byday=sa,su", "FREQ=WEEKLY;INTERVAL=2;BYDAY=SA,SU"},
+		{"FREQ=MONTHLY;BYMONTHDAY=-1;COUNT=3", "FREQ=MONTHLY;COUNT=3;BYMONTHDAY=-1"},
+		{"FREQ=DAILY;INTERVAL=1;WKST=MO", "FREQ=DAILY"},
+		{"FREQ=DAILY;UNTIL=20270105T100000Z", "FREQ=DAILY;UNTIL=20270105T100000Z"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			rule, err := Parse(tt.rule, time.UTC)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.rule, err)
+			}
+			if got := rule.String(); got != tt.want {
+				t.Fatalf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOccurrences(t *testing.T) {
+	at := func(value string) time.Time {
+		t.Helper()
+		parsed, err := time.Parse("2006-01-02 15:04", value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parsed
+	}
+
+	tests := []struct {
+		name    string
+		rule    string
+		dtstart string
+		limit   string
+		want    []string
+	}{
+		{
+			name:    "daily with count",
+			rule:    "FREQ=DAILY;COUNT=3",
+			dtstart: "2027-01-04 17:00",
+			limit:   "2028-01-01 00:00",
+			want:    []string{"2027-01-04 17:00", "2027-01-05 17:00", "2027-01-06 17:00"},
+		},
+		{
+			name:    "daily with interval stops at limit",
+			rule:    "FREQ=DAILY;INTERVAL=3",
+			dtstart: "2027-01-04 17:00",
+			limit:   "2027-01-13 17:00",
+			want:    []string{"2027-01-04 17:00", "2027-01-07 17:00", "2027-01-10 17:00"},
+		},
+		{
+			name:    "weekly keeps the weekday of dtstart",
+			rule:    "FREQ=WEEKLY;COUNT=3",
+			dtstart: "2027-01-08 17:00",
+			limit:   "2028-01-01 00:00",
+			want:    []string{"2027-01-08 17:00", "2027-01-15 17:00", "2027-01-22 17:00"},
+		},
+		{
+			name:    "weekly by day",
+			rule:    "FREQ=WEEKLY;BYDAY=FR,SA;COUNT=4",
+			dtstart: "2027-01-08 17:00",
+			limit:   "2028-01-01 00:00",
+			want:    []string{"2027-01-08 17:00", "2027-01-09 17:00", "2027-01-15 17:00", "2027-01-16 17:00"},
+		},
+		{
+			// Weeks start on Monday: the Sunday of dtstart closes its week,
+			// so the next occurrences fall two weeks later.
+			name:    "weeks start on monday",
+			rule:    "FREQ=WEEKLY;INTERVAL=2;BYDAY=SA,SU;WKST=MO;COUNT=5",
+			dtstart: "2027-01-03 10:00",
+			limit:   "2028-01-01 00:00",
+			want:    []string{"2027-01-03 10:00", "2027-01-16 10:00", "2027-01-17 10:00", "2027-01-30 10:00", "2027-01-31 10:00"},
+		},
+		{
+			name:    "monthly by numbered day",
+			rule:    "FREQ=MONTHLY;BYDAY=1SA;COUNT=3",
+			dtstart: "2027-01-02 09:00",
+			limit:   "2028-01-01 00:00",
+			want:    []string{"2027-01-02 09:00", "2027-02-06 09:00", "2027-03-06 09:00"},
+		},
+		{
+			name:    "monthly last day",
+			rule:    "FREQ=MONTHLY;BYMONTHDAY=-1;COUNT=3",
+			dtstart: "2027-01-31 09:00",
+			limit:   "2028-01-01 00:00",
+			want:    []string{"2027-01-31 09:00", "2027-02-28 09:00", "2027-03-31 09:00"},
+		},
+		{
+			name:    "monthly skips months without the day",
+			rule:    "FREQ=MONTHLY;COUNT=3",
+			dtstart: "2027-01-31 09:00",
+			limit:   "2028-01-01 00:00",
+			want:    []string{"2027-01-31 09:00", "2027-03-31 09:00", "2027-05-31 09:00"},
+		},
+		{
+			name:    "yearly first saturday of february",
+			rule:    "FREQ=YEARLY;BYMONTH=2;BYDAY=1SA;COUNT=3",
+			dtstart: "2027-02-06 17:00",
+			limit:   "2031-01-01 00:00",
+			want:    []string{"2027-02-06 17:00", "2028-02-05 17:00", "2029-02-03 17:00"},
+		},
+		{
+			name:    "yearly last sunday of the year",
+			rule:    "FREQ=YEARLY;BYDAY=-1SU;COUNT=2",
+			dtstart: "2027-12-26 12:00",
+			limit:   "2031-01-01 00:00",
+			want:    []string{"2027-12-26 12:00", "2028-12-31 12:00"},
+		},
+		{
+			name:    "until includes the whole day",
+			rule:    "FREQ=DAILY;UNTIL=20270106",
+			dtstart: "2027-01-04 17:00",
+			limit:   "2028-01-01 00:00",
+			want:    []string{"2027-01-04 17:00", "2027-01-05 17:00", "2027-01-06 17:00"},
+		},
+		{
+			name:    "until with a time excludes later occurrences",
+			rule:    "FREQ=WEEKLY;UNTIL=20270115T120000Z",
+			dtstart: "2027-01-01 17:00",
+			limit:   "2028-01-01 00:00",
+			want:    []string{"2027-01-01 17:00", "2027-01-08 17:00"},
+		},
+		{
+			name:    "dtstart after limit",
+			rule:    "FREQ=DAILY",
+			dtstart: "2027-01-04 17:00",
+			limit:   "2027-01-04 17:00",
+			want:    nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := Parse(tt.rule, time.UTC)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.rule, err)
+			}
+			got := rule.Occurrences(at(tt.dtstart), at(tt.limit))
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d occurrences %v, want %v", len(got), got, tt.want)
+			}
+			for i, want := range tt.want {
+				if !got[i].Equal(at(want)) {
+					t.Errorf("occurrence %d = %s, want %s", i, got[i].Format("2006-01-02 15:04"), want)
+				}
+			}
+		})
+	}
+}
+
+func TestOccurrencesAcrossDST(t *testing.T) {
+	paris := mustLocation(t, "Europe/Paris")
+
+	tests := []struct {
+		name    string
+		rule    string
+		dtstart time.Time
+		want    []time.Time
+	}{
+		{
+			// Summer time starts on 28 March 2027: the local time of day is
+			// kept, so the UTC instant moves an hour earlier.
+			name:    "spring forward",
+			rule:    "FREQ=WEEKLY;COUNT=3",
+			dtstart: time.Date(2027, time.March, 19, 17, 0, 0, 0, paris),
+			want: []time.Time{
+				time.Date(2027, time.March, 19, 16, 0, 0, 0, time.UTC),
+				time.Date(2027, time.March, 26, 16, 0, 0, 0, time.UTC),
+				time.Date(2027, time.April, 2, 15, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:    "fall back",
+			rule:    "FREQ=DAILY;COUNT=3",
+			dtstart: time.Date(2027, time.October, 30, 10, 0, 0, 0, paris),
+			want: []time.Time{
+				time.Date(2027, time.October, 30, 8, 0, 0, 0, time.UTC),
+				time.Date(2027, time.October, 31, 9, 0, 0, 0, time.UTC),
+				time.Date(2027, time.November, 1, 9, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			// A floating UNTIL is read in the property's time zone.
+			name:    "floating until",
+			rule:    "FREQ=DAILY;UNTIL=20270328T170000",
+			dtstart: time.Date(2027, time.March, 27, 17, 0, 0, 0, paris),
+			want: []time.Time{
+				time.Date(2027, time.March, 27, 16, 0, 0, 0, time.UTC),
+				time.Date(2027, time.March, 28, 15, 0, 0, 0, time.UTC),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := Parse(tt.rule, paris)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.rule, err)
+			}
+			got := rule.Occurrences(tt.dtstart, tt.dtstart.AddDate(1, 0, 0))
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d occurrences %v, want %d", len(got), got, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if !got[i].Equal(want) {
+					t.Errorf("occurrence %d = %s, want %s", i, got[i].UTC(), want)
+				}
+				if hour := got[i].In(paris).Hour(); hour != tt.dtstart.Hour() {
+					t.Errorf("occurrence %d starts at %dh local, want %dh", i, hour, tt.dtstart.Hour())
+				}
+			}
+		})
+	}
+}
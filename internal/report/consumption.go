@@ -0,0 +1,196 @@
+package report
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	"AppartmentBooker/internal/storage"
+)
+
+// Usage is the consumption of a meter over a stay, from the readings noted at
+// arrival and departure, and its cost at the configured unit price. It is
+// only computed when both readings are known and consistent.
+type Usage struct {
+	Meter       string   `json:"meter"`
+	Arrival     *float64 `json:"arrival,omitempty"`
+	Departure   *float64 `json:"departure,omitempty"`
+	Consumption float64  `json:"consumption"`
+	Cost        float64  `json:"cost"`
+	Complete    bool     `json:"complete"`
+}
+
+// StayConsumption is the consumption of a stay.
+type StayConsumption struct {
+	ReservationID int64     `json:"reservation_id"`
+	Person        string    `json:"person"`
+	Start         time.Time `json:"start"`
+	End           time.Time `json:"end"`
+	Usage         []Usage   `json:"usage"`
+	Cost          float64   `json:"cost"`
+}
+
+// HouseholdConsumption sums the consumption of the stays of a household.
+type HouseholdConsumption struct {
+	Person      string             `json:"person"`
+	Consumption map[string]float64 `json:"consumption"`
+	Cost        float64            `json:"cost"`
+}
+
+// Consumption gathers the consumption of the stays starting in a year.
+type Consumption struct {
+	Year       int                    `json:"year"`
+	Prices     map[string]float64     `json:"prices"`
+	Stays      []StayConsumption      `json:"stays"`
+	Households []HouseholdConsumption `json:"households"`
+}
+
+// StayUsage computes the usage of every meter from the readings of a stay.
+func StayUsage(readings []storage.MeterReading, prices map[string]float64) ([]Usage, float64) {
+	usage := make([]Usage, 0, len(storage.Meters))
+	var total float64
+	for _, meter := range storage.Meters {
+		u := Usage{Meter: meter}
+		for _, reading := range readings {
+			if reading.Meter != meter {
+				continue
+			}
+			value := reading.Value
+			if reading.Phase == storage.PhaseArrival {
+				u.Arrival = &value
+			} else {
+				u.Departure = &value
+			}
+		}
+		if u.Arrival != nil && u.Departure != nil && *u.Departure >= *u.Arrival {
+			u.Complete = true
+			u.Consumption = round(*u.Departure-*u.Arrival, 3)
+			u.Cost = round(u.Consumption*prices[meter], 2)
+			total += u.Cost
+		}
+		usage = append(usage, u)
+	}
+	return usage, round(total, 2)
+}
+
+// round rounds value to the given number of decimals, dropping the noise of
+// floating-point subtractions.
+func round(value float64, decimals int) float64 {
+	scale := math.Pow(10, float64(decimals))
+	return math.Round(value*scale) / scale
+}
+
+// BuildConsumption computes the consumption of the stays starting in the
+// year, and its sum per household. Stays without any reading are left out.
+func BuildConsumption(ctx context.Context, store *storage.Store, year int, prices map[string]float64, opts Options) (Consumption, error) {
+	loc := opts.Location
+	if loc == nil {
+		loc = time.Local
+	}
+	from := time.Date(year, time.January, 1, 0, 0, 0, 0, loc)
+	to := time.Date(year+1, time.January, 1, 0, 0, 0, 0, loc)
+
+	reservations, err := store.ListReservationsBetween(ctx, from, to)
+	if err != nil {
+		return Consumption{}, err
+	}
+	readings, err := store.MeterReadingsByReservation(ctx)
+	if err != nil {
+		return Consumption{}, err
+	}
+
+	out := Consumption{Year: year, Prices: prices, Stays: []StayConsumption{}}
+	households := make([]HouseholdConsumption, len(opts.People))
+	index := make(map[string]int, len(opts.People))
+	for i, person := range opts.People {
+		households[i] = HouseholdConsumption{Person: person, Consumption: make(map[string]float64)}
+		index[person] = i
+	}
+
+	for _, res := range reservations {
+		if res.ID == 0 || res.Start.Before(from) || len(readings[res.ID]) == 0 {
+			continue
+		}
+		usage, cost := StayUsage(readings[res.ID], prices)
+		out.Stays = append(out.Stays, StayConsumption{
+			ReservationID: res.ID,
+			Person:        res.Person,
+			Start:         res.Start.In(loc),
+			End:           res.End.In(loc),
+			Usage:         usage,
+			Cost:          cost,
+		})
+
+		i, ok := index[res.Person]
+		if !ok {
+			continue
+		}
+		for _, u := range usage {
+			households[i].Consumption[u.Meter] = round(households[i].Consumption[u.Meter]+u.Consumption, 3)
+		}
+		households[i].Cost = round(households[i].Cost+cost, 2)
+	}
+	out.Households = households
+	return out, nil
+}
+
+// WriteCSV writes the consumption as CSV: one line per stay and meter
+// followed by one line per household and meter.
+func (c Consumption) WriteCSV(w io.Writer) error {
+	out := csv.NewWriter(w)
+	out.Comma = ';'
+
+	rows := [][]string{
+		{"annee", "reservation", "foyer", "arrivee", "depart", "compteur", "releve_arrivee", "releve_depart", "consommation", "prix_unitaire", "cout"},
+	}
+	for _, stay := range c.Stays {
+		for _, u := range stay.Usage {
+			if u.Arrival == nil && u.Departure == nil {
+				continue
+			}
+			rows = append(rows, []string{
+				strconv.Itoa(c.Year),
+				strconv.FormatInt(stay.ReservationID, 10),
+				stay.Person,
+				stay.Start.Format("2006-01-02"),
+				stay.End.Format("2006-01-02"),
+				u.Meter,
+				formatReading(u.Arrival),
+				formatReading(u.Departure),
+				formatFloat(u.Consumption),
+				formatPrice(c.Prices[u.Meter]),
+				formatFloat(u.Cost),
+			})
+		}
+	}
+	rows = append(rows, []string{}, []string{"annee", "foyer", "compteur", "consommation", "prix_unitaire", "cout"})
+	for _, h := range c.Households {
+		for _, meter := range storage.Meters {
+			consumption := h.Consumption[meter]
+			rows = append(rows, []string{
+				strconv.Itoa(c.Year),
+				h.Person,
+				meter,
+				formatFloat(consumption),
+				formatPrice(c.Prices[meter]),
+				formatFloat(consumption * c.Prices[meter]),
+			})
+		}
+	}
+
+	return out.WriteAll(rows)
+}
+
+func formatReading(value *float64) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*value, 'f', -1, 64)
+}
+
+func formatPrice(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
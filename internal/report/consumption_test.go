@@ -0,0 +1,124 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"AppartmentBooker/internal/storage"
+)
+
+func reading(meter, phase string, value float64) storage.MeterReading {
+	return storage.MeterReading{Meter: meter, Phase: phase, Value: value}
+}
+
+func TestStayUsage(t *testing.T) {
+	prices := map[string]float64{storage.MeterElectricity: 0.2516, storage.MeterWater: 4.2}
+	readings := []storage.MeterReading{
+		reading(storage.MeterElectricity, storage.PhaseArrival, 1234.5),
+		reading(storage.MeterElectricity, storage.PhaseDeparture, 1240.2),
+		// A departure below the arrival is a typo, not a consumption.
+		reading(storage.MeterWater, storage.PhaseArrival, 310.4),
+		reading(storage.MeterWater, storage.PhaseDeparture, 301.2),
+		reading(storage.MeterGas, storage.PhaseArrival, 88),
+	}
+
+	usage, total := StayUsage(readings, prices)
+	if len(usage) != len(storage.Meters) {
+		t.Fatalf("got %d meters, want %d", len(usage), len(storage.Meters))
+	}
+	tests := []struct {
+		meter       string
+		complete    bool
+		consumption float64
+		cost        float64
+	}{
+		{storage.MeterElectricity, true, 5.7, 1.43},
+		{storage.MeterWater, false, 0, 0},
+		{storage.MeterGas, false, 0, 0},
+	}
+	for i, tt := range tests {
+		u := usage[i]
+		if u.Meter != tt.meter || u.Complete != tt.complete || u.Consumption != tt.consumption || u.Cost != tt.cost {
+			t.Errorf("usage %d = %+v, want %s complete=%v consumption=%v cost=%v", i, u, tt.meter, tt.complete, tt.consumption, tt.cost)
+		}
+	}
+	if usage[2].Arrival == nil || *usage[2].Arrival != 88 || usage[2].Departure != nil {
+		t.Errorf("gas readings = %v, %v, want arrival only", usage[2].Arrival, usage[2].Departure)
+	}
+	if total != 1.43 {
+		t.Errorf("total = %v, want 1.43", total)
+	}
+}
+
+func TestBuildConsumption(t *testing.T) {
+	store := newStore(t)
+	ctx := context.Background()
+	prices := map[string]float64{storage.MeterElectricity: 0.25, storage.MeterWater: 4}
+
+	add := func(res storage.Reservation, readings ...storage.MeterReading) {
+		t.Helper()
+		id, err := store.CreateReservation(ctx, res)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, m := range readings {
+			m.ReservationID = id
+			if _, err := store.SetMeterReading(ctx, m); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	add(storage.Reservation{Person: "Manon", Start: noon(time.January, 8), End: noon(time.January, 10)},
+		reading(storage.MeterElectricity, storage.PhaseArrival, 100),
+		reading(storage.MeterElectricity, storage.PhaseDeparture, 110),
+		reading(storage.MeterWater, storage.PhaseArrival, 20),
+		reading(storage.MeterWater, storage.PhaseDeparture, 20.5))
+	add(storage.Reservation{Person: "Manon", Start: noon(time.March, 5), End: noon(time.March, 7)},
+		reading(storage.MeterElectricity, storage.PhaseArrival, 110),
+		reading(storage.MeterElectricity, storage.PhaseDeparture, 114))
+	// Stays without readings, or starting the year before, are left out.
+	add(storage.Reservation{Person: "Noel", Start: noon(time.April, 1), End: noon(time.April, 3)})
+	add(storage.Reservation{Person: "Noel", Start: time.Date(2026, time.December, 30, 12, 0, 0, 0, time.UTC), End: noon(time.January, 2)},
+		reading(storage.MeterElectricity, storage.PhaseArrival, 50),
+		reading(storage.MeterElectricity, storage.PhaseDeparture, 60))
+
+	got, err := BuildConsumption(ctx, store, 2027, prices, Options{People: []string{"Manon", "Noel"}, Location: time.UTC})
+	if err != nil {
+		t.Fatalf("BuildConsumption: %v", err)
+	}
+	if len(got.Stays) != 2 {
+		t.Fatalf("got %d stays, want 2: %+v", len(got.Stays), got.Stays)
+	}
+	if got.Stays[0].Cost != 4.5 || got.Stays[1].Cost != 1 {
+		t.Errorf("stay costs = %v, %v, want 4.5, 1", got.Stays[0].Cost, got.Stays[1].Cost)
+	}
+
+	manon, noel := got.Households[0], got.Households[1]
+	if manon.Consumption[storage.MeterElectricity] != 14 || manon.Consumption[storage.MeterWater] != 0.5 || manon.Cost != 5.5 {
+		t.Errorf("Manon = %+v, want 14 kWh, 0.5 m3 and 5.5", manon)
+	}
+	if len(noel.Consumption) != 0 || noel.Cost != 0 {
+		t.Errorf("Noel = %+v, want nothing", noel)
+	}
+
+	var buf bytes.Buffer
+	if err := got.WriteCSV(&buf); err != nil {
+		t.Fatalf("WriteCSV: %v", err)
+	}
+	lines := strings.Split(buf.String(), "\n")
+	wantLines := map[string]bool{
+		"2027;1;Manon;2027-01-08;2027-01-10;electricity;100;110;10.00;0.25;2.50": true,
+		"2027;1;Manon;2027-01-08;2027-01-10;water;20;20.5;0.50;4;2.00":           true,
+		"2027;Manon;electricity;14.00;0.25;3.50":                                 true,
+		"2027;Noel;water;0.00;4;0.00":                                            true,
+	}
+	for _, line := range lines {
+		delete(wantLines, line)
+	}
+	for line := range wantLines {
+		t.Errorf("CSV misses line %q:\n%s", line, buf.String())
+	}
+}
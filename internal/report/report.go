@@ -0,0 +1,247 @@
+// Package report computes yearly usage statistics from the reservations kept
+// in storage.
+package report
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+
+	"AppartmentBooker/internal/storage"
+)
+
+// Holiday is a school holiday, both days included.
+type Holiday struct {
+	Name string
+	From time.Time
+	To   time.Time
+}
+
+// contains reports whether the calendar day of t falls in the holiday.
+func (h Holiday) contains(t time.Time) bool {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	from := time.Date(h.From.Year(), h.From.Month(), h.From.Day(), 0, 0, 0, 0, time.UTC)
+	to := time.Date(h.To.Year(), h.To.Month(), h.To.Day(), 0, 0, 0, 0, time.UTC)
+	return !day.Before(from) && !day.After(to)
+}
+
+// Options describes the property the report is computed for.
+type Options struct {
+	// People lists the households, in display order.
+	People []string
+	// Location is the time zone days and months are taken in.
+	Location *time.Location
+	// Holidays are the school holidays.
+	Holidays []Holiday
+}
+
+// Household gathers the nights spent by a household over the year. Nights
+// are counted from half-day slots, two slots making a night; weekend nights
+// are those of Friday and Saturday.
+type Household struct {
+	Person         string  `json:"person"`
+	Nights         float64 `json:"nights"`
+	WeekendNights  float64 `json:"weekend_nights"`
+	WeekdayNights  float64 `json:"weekday_nights"`
+	HolidayNights  float64 `json:"school_holiday_nights"`
+	HolidayShare   float64 `json:"school_holiday_share"`
+	OccupancyShare float64 `json:"occupancy_share"`
+}
+
+// Month is the occupancy of the property over a month.
+type Month struct {
+	Month         int     `json:"month"`
+	OccupiedSlots int     `json:"occupied_slots"`
+	TotalSlots    int     `json:"total_slots"`
+	Rate          float64 `json:"rate"`
+}
+
+// Year is the report of a calendar year.
+type Year struct {
+	Year          int         `json:"year"`
+	Households    []Household `json:"households"`
+	Months        []Month     `json:"months"`
+	Occupancy     float64     `json:"occupancy"`
+	WeekendRate   float64     `json:"weekend_occupancy"`
+	WeekdayRate   float64     `json:"weekday_occupancy"`
+	HolidayRate   float64     `json:"school_holiday_occupancy"`
+	OccupiedSlots int         `json:"occupied_slots"`
+}
+
+// slot is a morning or afternoon of a day.
+type slot struct {
+	start   time.Time
+	end     time.Time
+	weekend bool
+	holiday bool
+}
+
+// Build computes the report of the year. Declined reservations are ignored;
+// a slot counts as occupied as soon as one stay covers it.
+func Build(ctx context.Context, store *storage.Store, year int, opts Options) (Year, error) {
+	loc := opts.Location
+	if loc == nil {
+		loc = time.Local
+	}
+	from := time.Date(year, time.January, 1, 0, 0, 0, 0, loc)
+	to := time.Date(year+1, time.January, 1, 0, 0, 0, 0, loc)
+
+	reservations, err := store.ListReservationsBetween(ctx, from, to)
+	if err != nil {
+		return Year{}, err
+	}
+
+	households := make([]Household, len(opts.People))
+	index := make(map[string]int, len(opts.People))
+	for i, person := range opts.People {
+		households[i] = Household{Person: person}
+		index[person] = i
+	}
+
+	report := Year{Year: year, Months: make([]Month, 12)}
+	for i := range report.Months {
+		report.Months[i].Month = i + 1
+	}
+
+	var weekendSlots, weekendOccupied, holidaySlots, holidayOccupied int
+	for _, current := range slots(from, to, opts.Holidays) {
+		month := &report.Months[current.start.Month()-1]
+		month.TotalSlots++
+		if current.weekend {
+			weekendSlots++
+		}
+		if current.holiday {
+			holidaySlots++
+		}
+
+		occupied := false
+		counted := make(map[int]bool)
+		for _, res := range reservations {
+			if !res.Active() || !storage.Overlaps(res.Start, res.End, current.start, current.end) {
+				continue
+			}
+			occupied = true
+			i, ok := index[res.Person]
+			if !ok || counted[i] {
+				continue
+			}
+			counted[i] = true
+			households[i].Nights += 0.5
+			if current.weekend {
+				households[i].WeekendNights += 0.5
+			} else {
+				households[i].WeekdayNights += 0.5
+			}
+			if current.holiday {
+				households[i].HolidayNights += 0.5
+			}
+		}
+		if !occupied {
+			continue
+		}
+		report.OccupiedSlots++
+		month.OccupiedSlots++
+		if current.weekend {
+			weekendOccupied++
+		}
+		if current.holiday {
+			holidayOccupied++
+		}
+	}
+
+	var totalSlots, totalNights, totalHoliday float64
+	for i := range report.Months {
+		month := &report.Months[i]
+		month.Rate = ratio(float64(month.OccupiedSlots), float64(month.TotalSlots))
+		totalSlots += float64(month.TotalSlots)
+	}
+	for _, household := range households {
+		totalNights += household.Nights
+		totalHoliday += household.HolidayNights
+	}
+	for i := range households {
+		households[i].OccupancyShare = ratio(households[i].Nights, totalNights)
+		households[i].HolidayShare = ratio(households[i].HolidayNights, totalHoliday)
+	}
+
+	report.Households = households
+	report.Occupancy = ratio(float64(report.OccupiedSlots), totalSlots)
+	report.WeekendRate = ratio(float64(weekendOccupied), float64(weekendSlots))
+	report.WeekdayRate = ratio(float64(report.OccupiedSlots-weekendOccupied), totalSlots-float64(weekendSlots))
+	report.HolidayRate = ratio(float64(holidayOccupied), float64(holidaySlots))
+	return report, nil
+}
+
+// slots returns the morning and afternoon slots of every day of [from, to).
+// A night runs from an afternoon to the next morning, so the nights of Friday
+// and Saturday make the weekend: from Friday afternoon to Sunday morning.
+func slots(from, to time.Time, holidays []Holiday) []slot {
+	var out []slot
+	for day := from; day.Before(to); day = day.AddDate(0, 0, 1) {
+		noon := time.Date(day.Year(), day.Month(), day.Day(), 12, 0, 0, 0, day.Location())
+		next := day.AddDate(0, 0, 1)
+		weekday := day.Weekday()
+		morningWeekend := weekday == time.Saturday || weekday == time.Sunday
+		afternoonWeekend := weekday == time.Friday || weekday == time.Saturday
+		holiday := false
+		for _, h := range holidays {
+			if h.contains(day) {
+				holiday = true
+				break
+			}
+		}
+		out = append(out,
+			slot{start: day, end: noon, weekend: morningWeekend, holiday: holiday},
+			slot{start: noon, end: next, weekend: afternoonWeekend, holiday: holiday},
+		)
+	}
+	return out
+}
+
+func ratio(value, total float64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return value / total
+}
+
+// WriteCSV writes the report as CSV: one line per household followed by one
+// line per month.
+func (r Year) WriteCSV(w io.Writer) error {
+	out := csv.NewWriter(w)
+	out.Comma = ';'
+
+	rows := [][]string{
+		{"annee", "foyer", "nuits", "nuits_weekend", "nuits_semaine", "nuits_vacances_scolaires", "part_occupation", "part_vacances_scolaires"},
+	}
+	for _, h := range r.Households {
+		rows = append(rows, []string{
+			strconv.Itoa(r.Year),
+			h.Person,
+			formatFloat(h.Nights),
+			formatFloat(h.WeekendNights),
+			formatFloat(h.WeekdayNights),
+			formatFloat(h.HolidayNights),
+			formatFloat(h.OccupancyShare),
+			formatFloat(h.HolidayShare),
+		})
+	}
+	rows = append(rows, []string{}, []string{"annee", "mois", "demi_journees_occupees", "demi_journees", "taux_occupation"})
+	for _, m := range r.Months {
+		rows = append(rows, []string{
+			strconv.Itoa(r.Year),
+			strconv.Itoa(m.Month),
+			strconv.Itoa(m.OccupiedSlots),
+			strconv.Itoa(m.TotalSlots),
+			formatFloat(m.Rate),
+		})
+	}
+
+	return out.WriteAll(rows)
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', 2, 64)
+}
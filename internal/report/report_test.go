@@ -0,0 +1,145 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"AppartmentBooker/internal/storage"
+)
+
+func newStore(t *testing.T) *storage.Store {
+	t.Helper()
+	store, err := storage.New(filepath.Join(t.TempDir(), "test.db"), time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func noon(month time.Month, day int) time.Time {
+	return time.Date(2027, month, day, 12, 0, 0, 0, time.UTC)
+}
+
+func TestSlotsWeekend(t *testing.T) {
+	// Thursday 7 to Monday 11 January 2027.
+	from := time.Date(2027, time.January, 7, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2027, time.January, 12, 0, 0, 0, 0, time.UTC)
+	want := []bool{
+		false, false, // Thursday
+		false, true, // Friday night starts in the afternoon
+		true, true, // Saturday
+		true, false, // Sunday morning ends Saturday night
+		false, false, // Monday
+	}
+	got := slots(from, to, nil)
+	if len(got) != len(want) {
+		t.Fatalf("got %d slots, want %d", len(got), len(want))
+	}
+	var weekend float64
+	for i, slot := range got {
+		if slot.weekend != want[i] {
+			t.Errorf("slot %d starting %s: weekend = %v, want %v", i, slot.start.Format("Mon 15h"), slot.weekend, want[i])
+		}
+		if slot.weekend {
+			weekend += 0.5
+		}
+	}
+	if weekend != 2 {
+		t.Errorf("weekend nights = %v, want 2", weekend)
+	}
+}
+
+func TestBuild(t *testing.T) {
+	store := newStore(t)
+	ctx := context.Background()
+	stays := []storage.Reservation{
+		// A weekend, from Friday to Sunday.
+		{Person: "Manon", Start: noon(time.January, 8), End: noon(time.January, 10)},
+		// Two weeknights during the school holidays.
+		{Person: "Noel", Start: noon(time.February, 15), End: noon(time.February, 17)},
+		// Two weeknights, one of them shared with Manon.
+		{Person: "Noel", Start: noon(time.March, 1), End: noon(time.March, 3)},
+		{Person: "Manon", Start: noon(time.March, 2), End: noon(time.March, 3)},
+		// Declined stays are ignored.
+		{Person: "Manon", Start: noon(time.April, 1), End: noon(time.April, 20), Status: storage.StatusDeclined},
+		// Stays of unknown households only count as occupation.
+		{Person: "Invite", Start: noon(time.May, 3), End: noon(time.May, 4)},
+	}
+	for _, res := range stays {
+		if _, err := store.CreateReservation(ctx, res); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := Build(ctx, store, 2027, Options{
+		People:   []string{"Manon", "Noel"},
+		Location: time.UTC,
+		Holidays: []Holiday{{Name: "Hiver", From: noon(time.February, 13), To: noon(time.February, 28)}},
+	})
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+
+	want := []Household{
+		{Person: "Manon", Nights: 3, WeekendNights: 2, WeekdayNights: 1, OccupancyShare: 3.0 / 7},
+		{Person: "Noel", Nights: 4, WeekdayNights: 4, HolidayNights: 2, HolidayShare: 1, OccupancyShare: 4.0 / 7},
+	}
+	if len(got.Households) != len(want) {
+		t.Fatalf("got %d households, want %d", len(got.Households), len(want))
+	}
+	for i := range want {
+		if got.Households[i] != want[i] {
+			t.Errorf("household %d = %+v, want %+v", i, got.Households[i], want[i])
+		}
+	}
+
+	// 2027 starts and ends on a Friday: 53 Friday afternoons, 104 Saturday
+	// slots and 52 Sunday mornings make 209 weekend slots out of 730.
+	if got.OccupiedSlots != 14 {
+		t.Errorf("occupied slots = %d, want 14", got.OccupiedSlots)
+	}
+	rates := []struct {
+		name      string
+		got, want float64
+	}{
+		{"occupancy", got.Occupancy, 14.0 / 730},
+		{"weekend", got.WeekendRate, 4.0 / 209},
+		{"weekday", got.WeekdayRate, 10.0 / 521},
+		{"school holidays", got.HolidayRate, 4.0 / 32},
+		{"january", got.Months[0].Rate, 4.0 / 62},
+		{"may", got.Months[4].Rate, 2.0 / 62},
+	}
+	for _, rate := range rates {
+		if rate.got != rate.want {
+			t.Errorf("%s rate = %v, want %v", rate.name, rate.got, rate.want)
+		}
+	}
+}
+
+func TestYearWriteCSV(t *testing.T) {
+	report := Year{
+		Year:       2027,
+		Households: []Household{{Person: "Manon", Nights: 3.5, WeekendNights: 2, WeekdayNights: 1.5, OccupancyShare: 0.5}},
+		Months:     []Month{{Month: 1, OccupiedSlots: 7, TotalSlots: 62, Rate: 7.0 / 62}},
+	}
+	var buf bytes.Buffer
+	if err := report.WriteCSV(&buf); err != nil {
+		t.Fatalf("WriteCSV: %v", err)
+	}
+	want := strings.Join([]string{
+		"annee;foyer;nuits;nuits_weekend;nuits_semaine;nuits_vacances_scolaires;part_occupation;part_vacances_scolaires",
+		"2027;Manon;3.50;2.00;1.50;0.00;0.50;0.00",
+		"",
+		"annee;mois;demi_journees_occupees;demi_journees;taux_occupation",
+		"2027;1;7;62;0.11",
+		"",
+	}, "\n")
+	if got := buf.String(); got != want {
+		t.Errorf("WriteCSV =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,118 @@
+// Package rules evaluates the booking rules of a property, such as a minimum
+// stay or fixed changeover days during a season.
+package rules
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"AppartmentBooker/internal/season"
+	"AppartmentBooker/internal/storage"
+)
+
+// Checks a rule may carry.
+const (
+	CheckMinNights     = "min_nights"
+	CheckChangeover    = "changeover_days"
+	CheckAdvanceWindow = "max_advance_days"
+	CheckSingleHalfDay = "no_single_half_day"
+)
+
+// Rule is a set of checks applying all year, or only to the stays touching
+// Period when set. Zero values disable a check.
+type Rule struct {
+	Name   string
+	Period *season.Period
+	// MinNights is the shortest stay allowed.
+	MinNights int
+	// Changeover lists the weekdays arrivals and departures falling in the
+	// period must happen on.
+	Changeover []time.Weekday
+	// MaxAdvanceDays is how far ahead a stay may start.
+	MaxAdvanceDays int
+	// NoSingleHalfDay refuses stays of a lone morning or afternoon.
+	NoSingleHalfDay bool
+}
+
+// Violation describes a rule a stay breaks.
+type Violation struct {
+	Rule    string `json:"rule"`
+	Check   string `json:"check"`
+	Message string `json:"message"`
+}
+
+// Evaluate returns every check of the rules that the stay [start, end),
+// booked at now, breaks. Days are taken in loc.
+func Evaluate(rules []Rule, start, end, now time.Time, loc *time.Location) []Violation {
+	var out []Violation
+	for _, rule := range rules {
+		out = append(out, rule.evaluate(start, end, now, loc)...)
+	}
+	return out
+}
+
+func (r Rule) evaluate(start, end, now time.Time, loc *time.Location) []Violation {
+	if r.Period != nil && !r.Period.Overlaps(start, end, loc) {
+		return nil
+	}
+
+	var out []Violation
+	add := func(check, format string, args ...any) {
+		out = append(out, Violation{Rule: r.Name, Check: check, Message: fmt.Sprintf(format, args...)})
+	}
+
+	halfDays := len(storage.HalfDaySlots(start, end))
+	if r.NoSingleHalfDay && halfDays == 1 {
+		add(CheckSingleHalfDay, "a stay must last more than a single half-day")
+	}
+	if nights := float64(halfDays) / 2; r.MinNights > 0 && nights < float64(r.MinNights) {
+		add(CheckMinNights, "a stay must last at least %d night(s), %g booked", r.MinNights, nights)
+	}
+	if len(r.Changeover) > 0 {
+		if day := start.In(loc); r.inPeriod(day) && !r.isChangeover(day.Weekday()) {
+			add(CheckChangeover, "arrivals are only allowed on %s", r.changeoverNames())
+		}
+		if day := end.In(loc); r.inPeriod(day) && !r.isChangeover(day.Weekday()) {
+			add(CheckChangeover, "departures are only allowed on %s", r.changeoverNames())
+		}
+	}
+	if r.MaxAdvanceDays > 0 {
+		if limit := now.In(loc).AddDate(0, 0, r.MaxAdvanceDays); start.After(limit) {
+			add(CheckAdvanceWindow, "stays cannot start more than %d day(s) ahead", r.MaxAdvanceDays)
+		}
+	}
+	return out
+}
+
+func (r Rule) inPeriod(day time.Time) bool {
+	return r.Period == nil || r.Period.Contains(day)
+}
+
+func (r Rule) isChangeover(day time.Weekday) bool {
+	for _, allowed := range r.Changeover {
+		if allowed == day {
+			return true
+		}
+	}
+	return false
+}
+
+func (r Rule) changeoverNames() string {
+	names := make([]string, 0, len(r.Changeover))
+	for _, day := range r.Changeover {
+		names = append(names, day.String())
+	}
+	return strings.Join(names, ", ")
+}
+
+// ParseWeekday reads an English weekday name such as "saturday".
+func ParseWeekday(value string) (time.Weekday, error) {
+	name := strings.ToLower(strings.TrimSpace(value))
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.ToLower(day.String()) == name {
+			return day, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown weekday %q", value)
+}
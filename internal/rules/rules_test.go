@@ -0,0 +1,123 @@
+package rules
+
+import (
+	"testing"
+	"time"
+
+	"AppartmentBooker/internal/season"
+)
+
+func TestEvaluate(t *testing.T) {
+	summer, err := season.Parse("Ete", "07-01", "08-31")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(month time.Month, day, hour int) time.Time {
+		return time.Date(2027, month, day, hour, 0, 0, 0, time.UTC)
+	}
+	now := at(time.January, 1, 9)
+	allYear := Rule{Name: "Toute l'annee", MinNights: 2, NoSingleHalfDay: true, MaxAdvanceDays: 300}
+	saturdays := Rule{Name: "Ete", Period: &summer, MinNights: 7, Changeover: []time.Weekday{time.Saturday}}
+
+	tests := []struct {
+		name       string
+		rules      []Rule
+		start, end time.Time
+		want       []string
+	}{
+		{
+			name:  "stay following every rule",
+			rules: []Rule{allYear},
+			start: at(time.March, 5, 12),
+			end:   at(time.March, 8, 12),
+		},
+		{
+			name:  "single half-day is also too short",
+			rules: []Rule{allYear},
+			start: at(time.March, 5, 12),
+			end:   at(time.March, 6, 0),
+			want:  []string{CheckSingleHalfDay, CheckMinNights},
+		},
+		{
+			name:  "one night and a half",
+			rules: []Rule{allYear},
+			start: at(time.March, 5, 12),
+			end:   at(time.March, 7, 0),
+			want:  []string{CheckMinNights},
+		},
+		{
+			name:  "too far ahead",
+			rules: []Rule{allYear},
+			start: at(time.December, 1, 12),
+			end:   at(time.December, 5, 12),
+			want:  []string{CheckAdvanceWindow},
+		},
+		{
+			name:  "seasonal rule ignored outside its period",
+			rules: []Rule{saturdays},
+			start: at(time.June, 2, 12),
+			end:   at(time.June, 4, 12),
+		},
+		{
+			name:  "saturday to saturday in summer",
+			rules: []Rule{saturdays},
+			start: at(time.July, 3, 12),
+			end:   at(time.July, 10, 12),
+		},
+		{
+			name:  "wrong arrival and departure days",
+			rules: []Rule{saturdays},
+			start: at(time.July, 5, 12),
+			end:   at(time.July, 12, 12),
+			want:  []string{CheckChangeover, CheckChangeover},
+		},
+		{
+			// The arrival falls before the season: only the departure must
+			// be on a Saturday.
+			name:  "stay entering the season",
+			rules: []Rule{saturdays},
+			start: at(time.June, 27, 12),
+			end:   at(time.July, 10, 12),
+		},
+		{
+			name:  "rules add up",
+			rules: []Rule{allYear, saturdays},
+			start: at(time.August, 4, 12),
+			end:   at(time.August, 5, 12),
+			want:  []string{CheckMinNights, CheckMinNights, CheckChangeover, CheckChangeover},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Evaluate(tt.rules, tt.start, tt.end, now, time.UTC)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %+v, want checks %v", got, tt.want)
+			}
+			for i, check := range tt.want {
+				if got[i].Check != check {
+					t.Errorf("violation %d = %+v, want check %s", i, got[i], check)
+				}
+			}
+		})
+	}
+}
+
+func TestParseWeekday(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Weekday
+		ok    bool
+	}{
+		{"saturday", time.Saturday, true},
+		{" Sunday ", time.Sunday, true},
+		{"MONDAY", time.Monday, true},
+		{"samedi", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		got, err := ParseWeekday(tt.value)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("ParseWeekday(%q) = %v, %v, want %v (ok %v)", tt.value, got, err, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+// Package season describes periods that come back every year, such as
+// "from 20 December to 5 January".
+package season
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MonthDay is a day of the year without year.
+type MonthDay struct {
+	Month time.Month
+	Day   int
+}
+
+// ParseMonthDay reads a "MM-DD" value.
+func ParseMonthDay(value string) (MonthDay, error) {
+	var month, day int
+	if _, err := fmt.Sscanf(strings.TrimSpace(value), "%d-%d", &month, &day); err != nil {
+		return MonthDay{}, fmt.Errorf("invalid month-day %q (expected MM-DD)", value)
+	}
+	if month < 1 || month > 12 || day < 1 || day > 31 {
+		return MonthDay{}, fmt.Errorf("invalid month-day %q (expected MM-DD)", value)
+	}
+	return MonthDay{Month: time.Month(month), Day: day}, nil
+}
+
+func (md MonthDay) ordinal() int {
+	return int(md.Month)*100 + md.Day
+}
+
+// Period is a yearly period, both bounds included. A period whose end comes
+// before its start spans the new year.
+type Period struct {
+	Name string
+	From MonthDay
+	To   MonthDay
+}
+
+// Parse builds a period from "MM-DD" bounds.
+func Parse(name, from, to string) (Period, error) {
+	start, err := ParseMonthDay(from)
+	if err != nil {
+		return Period{}, err
+	}
+	end, err := ParseMonthDay(to)
+	if err != nil {
+		return Period{}, err
+	}
+	return Period{Name: name, From: start, To: end}, nil
+}
+
+// Contains reports whether the calendar day of t falls in the period.
+func (p Period) Contains(t time.Time) bool {
+	day := MonthDay{Month: t.Month(), Day: t.Day()}.ordinal()
+	from, to := p.From.ordinal(), p.To.ordinal()
+	if from <= to {
+		return day >= from && day <= to
+	}
+	return day >= from || day <= to
+}
+
+// Overlaps reports whether a day of [start, end), taken in loc, falls in the
+// period.
+func (p Period) Overlaps(start, end time.Time, loc *time.Location) bool {
+	for _, day := range Days(start, end, loc) {
+		if p.Contains(day) {
+			return true
+		}
+	}
+	return false
+}
+
+// Days returns the calendar days, at midnight in loc, touched by [start, end).
+func Days(start, end time.Time, loc *time.Location) []time.Time {
+	if !end.After(start) {
+		return nil
+	}
+	first := start.In(loc)
+	last := end.Add(-time.Nanosecond).In(loc)
+	day := time.Date(first.Year(), first.Month(), first.Day(), 0, 0, 0, 0, loc)
+	stop := time.Date(last.Year(), last.Month(), last.Day(), 0, 0, 0, 0, loc)
+
+	var out []time.Time
+	for !day.After(stop) {
+		out = append(out, day)
+		day = day.AddDate(0, 0, 1)
+	}
+	return out
+}
@@ -0,0 +1,221 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"AppartmentBooker/internal/storage"
+)
+
+const (
+	approvalAdmin    = "admin"
+	approvalMajority = "majority"
+)
+
+// isAdmin reports whether the member has administrative rights.
+func (s *Server) isAdmin(member string) bool {
+	return member != "" && s.admins[member]
+}
+
+// approvalRequired reports whether the stay touches one of the periods where
+// bookings need to be approved.
+func (s *Server) approvalRequired(start, end time.Time) bool {
+	for _, period := range s.periods {
+		if period.Overlaps(start, end, s.location) {
+			return true
+		}
+	}
+	return false
+}
+
+// initialStatus returns the status of a new reservation made by member.
+// Admins never wait for approval, nor does a household alone in the property.
+func (s *Server) initialStatus(member string, res storage.Reservation) string {
+	if !s.approvalRequired(res.Start, res.End) || s.isAdmin(member) {
+		return storage.StatusConfirmed
+	}
+	if s.approval == approvalMajority && len(s.people) < 2 {
+		return storage.StatusConfirmed
+	}
+	return storage.StatusTentative
+}
+
+// seriesStatus returns the status of a series whose upcoming occurrences
+// are given: it waits for approval as a whole as soon as one of them would.
+func (s *Server) seriesStatus(member string, occurrences []storage.Reservation) string {
+	for _, occurrence := range occurrences {
+		if s.initialStatus(member, occurrence) == storage.StatusTentative {
+			return storage.StatusTentative
+		}
+	}
+	return storage.StatusConfirmed
+}
+
+// decideReservation records the approval or refusal of a tentative
+// reservation. An admin decides alone; in majority mode, the members of the
+// other households vote and the reservation is settled once a majority of
+// them agrees.
+func (s *Server) decideReservation(w http.ResponseWriter, r *http.Request, id int64, approve bool) {
+	member, _ := s.currentMember(r)
+	if member == "" {
+		http.Error(w, "a member login is required", http.StatusForbidden)
+		return
+	}
+
+	res, err := s.store.GetReservation(r.Context(), id)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, "failed to load reservation", http.StatusInternalServerError)
+		return
+	}
+	if res.Status != storage.StatusTentative {
+		http.Error(w, "reservation is not tentative", http.StatusConflict)
+		return
+	}
+	if _, err := s.store.WaitlistOffer(r.Context(), id); err == nil {
+		http.Error(w, "reservation is a pending waitlist offer", http.StatusConflict)
+		return
+	} else if !errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, "failed to load reservation", http.StatusInternalServerError)
+		return
+	}
+
+	if !s.mayDecide(w, member, res.Person) {
+		return
+	}
+
+	vote := storage.Vote{Household: s.households[member], Member: member, Approve: approve, CreatedAt: time.Now()}
+	if err := s.store.RecordVote(r.Context(), id, vote); err != nil {
+		http.Error(w, "failed to record vote", http.StatusInternalServerError)
+		return
+	}
+	votes, err := s.store.ListVotes(r.Context(), id)
+	if err != nil {
+		http.Error(w, "failed to list votes", http.StatusInternalServerError)
+		return
+	}
+
+	status := s.decidedStatus(member, approve, res.Person, votes)
+	if status != storage.StatusTentative {
+		if err := s.store.SetReservationStatus(r.Context(), id, status); err != nil {
+			http.Error(w, "failed to update", http.StatusInternalServerError)
+			return
+		}
+		res.Status = status
+		if status == storage.StatusDeclined {
+			s.processWaitlist(r.Context())
+		}
+	}
+
+	response := newReservationResponse(res)
+	response.Votes = votes
+	writeJSON(w, http.StatusOK, response)
+}
+
+// decideSeries records the approval or refusal of a tentative series, the
+// same way decideReservation does for a single reservation.
+func (s *Server) decideSeries(w http.ResponseWriter, r *http.Request, series storage.Series, approve bool) {
+	member, _ := s.currentMember(r)
+	if member == "" {
+		http.Error(w, "a member login is required", http.StatusForbidden)
+		return
+	}
+	if series.Status != storage.StatusTentative {
+		http.Error(w, "series is not tentative", http.StatusConflict)
+		return
+	}
+	if !s.mayDecide(w, member, series.Person) {
+		return
+	}
+
+	vote := storage.Vote{Household: s.households[member], Member: member, Approve: approve, CreatedAt: time.Now()}
+	if err := s.store.RecordSeriesVote(r.Context(), series.ID, vote); err != nil {
+		http.Error(w, "failed to record vote", http.StatusInternalServerError)
+		return
+	}
+	votes, err := s.store.ListSeriesVotes(r.Context(), series.ID)
+	if err != nil {
+		http.Error(w, "failed to list votes", http.StatusInternalServerError)
+		return
+	}
+
+	status := s.decidedStatus(member, approve, series.Person, votes)
+	if status != storage.StatusTentative {
+		if err := s.store.SetSeriesStatus(r.Context(), series.ID, status); err != nil {
+			http.Error(w, "failed to update", http.StatusInternalServerError)
+			return
+		}
+		series.Status = status
+		if status == storage.StatusDeclined {
+			s.processWaitlist(r.Context())
+		}
+	}
+
+	response := newSeriesResponse(series)
+	response.Votes = votes
+	writeJSON(w, http.StatusOK, response)
+}
+
+// mayDecide reports whether member may approve or decline a booking of
+// person: admins always may, members of the other households only in
+// majority mode. It writes the refusal itself.
+func (s *Server) mayDecide(w http.ResponseWriter, member, person string) bool {
+	if s.isAdmin(member) {
+		return true
+	}
+	if s.approval != approvalMajority {
+		http.Error(w, "admin rights required", http.StatusForbidden)
+		return false
+	}
+	if s.households[member] == person {
+		http.Error(w, "a household cannot vote on its own booking", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// decidedStatus returns the status of a booking of person once member has
+// voted: an admin settles it alone, otherwise the votes are tallied.
+func (s *Server) decidedStatus(member string, approve bool, person string, votes []storage.Vote) string {
+	switch {
+	case s.isAdmin(member) && approve:
+		return storage.StatusConfirmed
+	case s.isAdmin(member):
+		return storage.StatusDeclined
+	default:
+		return s.tallyVotes(person, votes)
+	}
+}
+
+// tallyVotes returns the status resulting from the votes of the households
+// other than person: confirmed once a strict majority approves, declined once
+// such a majority can no longer be reached.
+func (s *Server) tallyVotes(person string, votes []storage.Vote) string {
+	others := len(s.people) - 1
+	needed := others/2 + 1
+
+	yes, no := 0, 0
+	for _, vote := range votes {
+		if vote.Household == person {
+			continue
+		}
+		if vote.Approve {
+			yes++
+		} else {
+			no++
+		}
+	}
+
+	switch {
+	case yes >= needed:
+		return storage.StatusConfirmed
+	case no > others-needed:
+		return storage.StatusDeclined
+	default:
+		return storage.StatusTentative
+	}
+}
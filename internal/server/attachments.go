@@ -0,0 +1,295 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"AppartmentBooker/internal/media"
+	"AppartmentBooker/internal/storage"
+)
+
+// attachmentExtensions gives the extension of the stored files.
+var attachmentExtensions = map[string]string{
+	media.TypeJPEG: ".jpg",
+	media.TypePNG:  ".png",
+	media.TypeGIF:  ".gif",
+	media.TypePDF:  ".pdf",
+}
+
+type attachmentResponse struct {
+	storage.Attachment
+	HasThumbnail bool `json:"has_thumbnail"`
+}
+
+func newAttachmentResponses(attachments []storage.Attachment) []attachmentResponse {
+	out := make([]attachmentResponse, 0, len(attachments))
+	for _, a := range attachments {
+		out = append(out, attachmentResponse{Attachment: a, HasThumbnail: a.Thumbnail != ""})
+	}
+	return out
+}
+
+// handleAttachments serves /api/reservations/{id}/attachments: GET lists the
+// files of the reservation, POST uploads one (multipart field "file").
+func (s *Server) handleAttachments(w http.ResponseWriter, r *http.Request, reservationID int64) {
+	if s.attachmentsDir == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	res, err := s.store.GetReservation(r.Context(), reservationID)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, "failed to load reservation", http.StatusInternalServerError)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		attachments, err := s.store.ListAttachments(r.Context(), res.ID)
+		if err != nil {
+			http.Error(w, "failed to list attachments", http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, newAttachmentResponses(attachments))
+	case http.MethodPost:
+		s.uploadAttachment(w, r, res)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// uploadAttachment stores an uploaded file. Its type is sniffed from the
+// content; images are re-encoded to drop their metadata and get a thumbnail.
+func (s *Server) uploadAttachment(w http.ResponseWriter, r *http.Request, res storage.Reservation) {
+	// Leave room for the multipart envelope around the file.
+	r.Body = http.MaxBytesReader(w, r.Body, s.attachmentMaxBytes+1<<20)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "file is too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "a file is required", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, s.attachmentMaxBytes+1))
+	if err != nil {
+		http.Error(w, "failed to read file", http.StatusBadRequest)
+		return
+	}
+	if int64(len(data)) > s.attachmentMaxBytes {
+		http.Error(w, "file is too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if len(data) == 0 {
+		http.Error(w, "file is empty", http.StatusBadRequest)
+		return
+	}
+
+	contentType, _, _ := strings.Cut(media.Detect(data), ";")
+	if !s.acceptsAttachment(contentType) {
+		http.Error(w, "file type not allowed", http.StatusUnsupportedMediaType)
+		return
+	}
+	if data, err = media.Sanitise(data, contentType); err != nil {
+		if errors.Is(err, media.ErrTooLarge) {
+			http.Error(w, "image is too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "invalid file", http.StatusBadRequest)
+		return
+	}
+
+	base, err := randomName()
+	if err != nil {
+		http.Error(w, "failed to store file", http.StatusInternalServerError)
+		return
+	}
+	member, _ := s.currentMember(r)
+	attachment := storage.Attachment{
+		ReservationID: res.ID,
+		Name:          attachmentName(header.Filename),
+		ContentType:   contentType,
+		Size:          int64(len(data)),
+		File:          base + attachmentExtensions[contentType],
+		UploadedBy:    member,
+		CreatedAt:     time.Now().UTC().Truncate(time.Second),
+	}
+	if err := os.WriteFile(s.attachmentPath(attachment.File), data, 0o640); err != nil {
+		http.Error(w, "failed to store file", http.StatusInternalServerError)
+		return
+	}
+	if media.IsImage(contentType) {
+		thumbnail, err := media.Thumbnail(data)
+		if err == nil {
+			attachment.Thumbnail = base + "-thumb.jpg"
+			err = os.WriteFile(s.attachmentPath(attachment.Thumbnail), thumbnail, 0o640)
+		}
+		if err != nil {
+			log.Printf("warning: thumbnail of %q failed: %v", attachment.Name, err)
+			attachment.Thumbnail = ""
+		}
+	}
+
+	id, err := s.store.CreateAttachment(r.Context(), attachment)
+	if err != nil {
+		s.removeAttachmentFiles(attachment)
+		http.Error(w, "failed to create", http.StatusInternalServerError)
+		return
+	}
+	attachment.ID = id
+	writeJSON(w, http.StatusCreated, attachmentResponse{Attachment: attachment, HasThumbnail: attachment.Thumbnail != ""})
+}
+
+func (s *Server) acceptsAttachment(contentType string) bool {
+	for _, accepted := range s.attachmentTypes {
+		if accepted == contentType {
+			return true
+		}
+	}
+	return false
+}
+
+// handleAttachment serves /api/attachments/{id} (GET downloads the file,
+// DELETE removes it) and /api/attachments/{id}/thumbnail.
+func (s *Server) handleAttachment(w http.ResponseWriter, r *http.Request) {
+	if !s.isAuthenticated(r) {
+		s.writeUnauthorized(w)
+		return
+	}
+	if s.attachmentsDir == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	idStr, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/attachments/"), "/")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	attachment, err := s.store.GetAttachment(r.Context(), id)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, "failed to load attachment", http.StatusInternalServerError)
+		return
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		s.serveAttachmentFile(w, r, attachment.File, attachment.ContentType, attachment.Name, attachment.CreatedAt)
+	case action == "thumbnail" && r.Method == http.MethodGet:
+		if attachment.Thumbnail == "" {
+			http.NotFound(w, r)
+			return
+		}
+		s.serveAttachmentFile(w, r, attachment.Thumbnail, media.TypeJPEG, "", attachment.CreatedAt)
+	case action == "" && r.Method == http.MethodDelete:
+		s.deleteAttachment(w, r, attachment)
+	case action == "" || action == "thumbnail":
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) serveAttachmentFile(w http.ResponseWriter, r *http.Request, file, contentType, name string, modified time.Time) {
+	f, err := os.Open(s.attachmentPath(file))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; img-src 'self'; style-src 'unsafe-inline'; sandbox")
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	if name != "" {
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": name}))
+	}
+	http.ServeContent(w, r, "", modified, f)
+}
+
+// deleteAttachment removes an attachment; it is reserved to its uploader, the
+// household of the reservation and admins.
+func (s *Server) deleteAttachment(w http.ResponseWriter, r *http.Request, attachment storage.Attachment) {
+	member, _ := s.currentMember(r)
+	if member != "" && member != attachment.UploadedBy && !s.isAdmin(member) {
+		res, err := s.store.GetReservation(r.Context(), attachment.ReservationID)
+		if err != nil {
+			http.Error(w, "failed to load reservation", http.StatusInternalServerError)
+			return
+		}
+		if s.households[member] != res.Person {
+			http.Error(w, "member does not belong to this household", http.StatusForbidden)
+			return
+		}
+	}
+
+	if err := s.store.DeleteAttachment(r.Context(), attachment.ID); err != nil {
+		http.Error(w, "failed to delete", http.StatusInternalServerError)
+		return
+	}
+	s.removeAttachmentFiles(attachment)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// removeAttachmentFiles deletes the files of the attachments from disk.
+func (s *Server) removeAttachmentFiles(attachments ...storage.Attachment) {
+	for _, attachment := range attachments {
+		for _, file := range []string{attachment.File, attachment.Thumbnail} {
+			if file == "" {
+				continue
+			}
+			if err := os.Remove(s.attachmentPath(file)); err != nil && !errors.Is(err, os.ErrNotExist) {
+				log.Printf("warning: failed to remove attachment file %q: %v", file, err)
+			}
+		}
+	}
+}
+
+func (s *Server) attachmentPath(file string) string {
+	return filepath.Join(s.attachmentsDir, filepath.Base(file))
+}
+
+// attachmentName keeps the base name of an uploaded file, as sent by the
+// browser, for display and download.
+func attachmentName(name string) string {
+	name = strings.TrimSpace(filepath.Base(strings.ReplaceAll(name, "\\", "/")))
+	if name == "" || name == "." || name == "/" {
+		return "piece-jointe"
+	}
+	if len(name) > 200 {
+		name = name[:200]
+	}
+	return strings.ToValidUTF8(name, "")
+}
+
+func randomName() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
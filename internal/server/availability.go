@@ -0,0 +1,154 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"AppartmentBooker/internal/rules"
+	"AppartmentBooker/internal/storage"
+)
+
+const (
+	// availabilityMonths is the default search range.
+	availabilityMonths = 3
+	// availabilityLimit caps the number of windows returned.
+	availabilityLimit = 100
+)
+
+type availabilityWindow struct {
+	Start  string `json:"start"`
+	End    string `json:"end"`
+	Nights int    `json:"nights"`
+}
+
+// parseDay reads a "YYYY-MM-DD" day or an RFC 3339 instant, returning the
+// midnight of its day in the property time zone.
+func (s *Server) parseDay(value string) (time.Time, error) {
+	t, err := time.ParseInLocation("2006-01-02", value, s.location)
+	if err != nil {
+		if t, err = time.Parse(time.RFC3339, value); err != nil {
+			return time.Time{}, err
+		}
+		t = t.In(s.location)
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, s.location), nil
+}
+
+// handleAvailability lists the stays of the requested number of nights that
+// could be booked between from (default: today) and to (default: three
+// months later), ranked by start. Stays arrive in the afternoon and leave in
+// the morning; weekdays restricts the arrival days. A window is free when it
+// is not blacked out, leaves the rooms (the whole apartment by default) and
+// the turnover buffers free, fits the capacity and follows the booking rules.
+func (s *Server) handleAvailability(w http.ResponseWriter, r *http.Request) {
+	if !s.isAuthenticated(r) {
+		s.writeUnauthorized(w)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	nights, err := strconv.Atoi(query.Get("nights"))
+	if err != nil || nights < 1 || nights > 365 {
+		http.Error(w, "invalid nights", http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now().In(s.location)
+	from := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, s.location)
+	if value := query.Get("from"); value != "" {
+		if from, err = s.parseDay(value); err != nil {
+			http.Error(w, "invalid from", http.StatusBadRequest)
+			return
+		}
+	}
+	to := from.AddDate(0, availabilityMonths, 0)
+	if value := query.Get("to"); value != "" {
+		if to, err = s.parseDay(value); err != nil {
+			http.Error(w, "invalid to", http.StatusBadRequest)
+			return
+		}
+		to = to.AddDate(0, 0, 1)
+	}
+	if !to.After(from) || to.After(from.AddDate(2, 0, 0)) {
+		http.Error(w, "invalid range", http.StatusBadRequest)
+		return
+	}
+
+	var weekdays map[time.Weekday]bool
+	if value := strings.TrimSpace(query.Get("weekdays")); value != "" {
+		weekdays = make(map[time.Weekday]bool)
+		for _, name := range strings.Split(value, ",") {
+			day, err := rules.ParseWeekday(name)
+			if err != nil {
+				http.Error(w, "invalid weekdays", http.StatusBadRequest)
+				return
+			}
+			weekdays[day] = true
+		}
+	}
+
+	var rooms []string
+	if value := strings.TrimSpace(query.Get("rooms")); value != "" {
+		var ok bool
+		if rooms, ok = s.normaliseRooms(strings.Split(value, ",")); !ok {
+			http.Error(w, "unknown room", http.StatusBadRequest)
+			return
+		}
+	}
+
+	adults := 1
+	if value := query.Get("guests"); value != "" {
+		if adults, err = strconv.Atoi(value); err != nil || adults < 1 {
+			http.Error(w, "invalid guests", http.StatusBadRequest)
+			return
+		}
+	}
+
+	member, _ := s.currentMember(r)
+	out := []availabilityWindow{}
+	for day := from; day.Before(to) && len(out) < availabilityLimit; day = day.AddDate(0, 0, 1) {
+		if weekdays != nil && !weekdays[day.Weekday()] {
+			continue
+		}
+		start := time.Date(day.Year(), day.Month(), day.Day(), 12, 0, 0, 0, s.location)
+		last := day.AddDate(0, 0, nights)
+		end := time.Date(last.Year(), last.Month(), last.Day(), 12, 0, 0, 0, s.location)
+		if start.Before(now) || end.After(to) {
+			continue
+		}
+
+		if len(rules.Evaluate(s.rules, start, end, now, s.location)) > 0 {
+			continue
+		}
+		candidate := storage.Reservation{
+			Person: s.households[member],
+			Start:  start,
+			End:    end,
+			Adults: adults,
+			Rooms:  rooms,
+		}
+		blocked, err := s.isBlocked(r.Context(), candidate)
+		if err != nil {
+			http.Error(w, "failed to check availability", http.StatusInternalServerError)
+			return
+		}
+		if blocked {
+			continue
+		}
+
+		out = append(out, availabilityWindow{
+			Start:  start.Format(time.RFC3339),
+			End:    end.Format(time.RFC3339),
+			Nights: nights,
+		})
+	}
+
+	writeJSON(w, http.StatusOK, out)
+}
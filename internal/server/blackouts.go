@@ -0,0 +1,189 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"AppartmentBooker/internal/storage"
+)
+
+type blackoutResponse struct {
+	ID        int64  `json:"id"`
+	Start     string `json:"start"`
+	End       string `json:"end"`
+	Reason    string `json:"reason"`
+	CreatedBy string `json:"created_by,omitempty"`
+	CreatedAt string `json:"created_at"`
+}
+
+func newBlackoutResponse(b storage.Blackout) blackoutResponse {
+	return blackoutResponse{
+		ID:        b.ID,
+		Start:     b.Start.Format(time.RFC3339),
+		End:       b.End.Format(time.RFC3339),
+		Reason:    b.Reason,
+		CreatedBy: b.CreatedBy,
+		CreatedAt: b.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+// handleBlackouts lists (GET) or creates (POST, admins only) blackouts.
+func (s *Server) handleBlackouts(w http.ResponseWriter, r *http.Request) {
+	member, ok := s.currentMember(r)
+	if !ok {
+		s.writeUnauthorized(w)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		blackouts, err := s.store.ListBlackouts(r.Context())
+		if err != nil {
+			http.Error(w, "failed to list blackouts", http.StatusInternalServerError)
+			return
+		}
+		out := make([]blackoutResponse, 0, len(blackouts))
+		for _, b := range blackouts {
+			out = append(out, newBlackoutResponse(b))
+		}
+		writeJSON(w, http.StatusOK, out)
+	case http.MethodPost:
+		if !s.isAdmin(member) {
+			http.Error(w, "admin rights required", http.StatusForbidden)
+			return
+		}
+		s.createBlackout(w, r, member)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) createBlackout(w http.ResponseWriter, r *http.Request, member string) {
+	var payload struct {
+		Start  string `json:"start"`
+		End    string `json:"end"`
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	start, err := time.Parse(time.RFC3339, payload.Start)
+	if err != nil {
+		http.Error(w, "invalid start", http.StatusBadRequest)
+		return
+	}
+	end, err := time.Parse(time.RFC3339, payload.End)
+	if err != nil || !end.After(start) {
+		http.Error(w, "invalid end", http.StatusBadRequest)
+		return
+	}
+	reason := strings.TrimSpace(payload.Reason)
+	if reason == "" {
+		http.Error(w, "reason is required", http.StatusBadRequest)
+		return
+	}
+
+	blackout := storage.Blackout{
+		Start:     start,
+		End:       end,
+		Reason:    reason,
+		CreatedBy: member,
+		CreatedAt: time.Now(),
+	}
+	id, err := s.store.CreateBlackout(r.Context(), blackout)
+	if err != nil {
+		http.Error(w, "failed to create", http.StatusInternalServerError)
+		return
+	}
+
+	blackout.ID = id
+	writeJSON(w, http.StatusCreated, newBlackoutResponse(blackout))
+}
+
+// handleBlackout deletes a blackout (admins only).
+func (s *Server) handleBlackout(w http.ResponseWriter, r *http.Request) {
+	member, ok := s.currentMember(r)
+	if !ok {
+		s.writeUnauthorized(w)
+		return
+	}
+
+	id, err := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, "/api/blackouts/"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	if r.Method != http.MethodDelete {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.isAdmin(member) {
+		http.Error(w, "admin rights required", http.StatusForbidden)
+		return
+	}
+
+	err = s.store.DeleteBlackout(r.Context(), id)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, "failed to delete", http.StatusInternalServerError)
+		return
+	}
+	s.processWaitlist(r.Context())
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// blackoutFor returns the first blackout overlapping [start, end), if any.
+func (s *Server) blackoutFor(ctx context.Context, start, end time.Time) (*storage.Blackout, error) {
+	blackouts, err := s.store.ListBlackoutsBetween(ctx, start, end)
+	if err != nil || len(blackouts) == 0 {
+		return nil, err
+	}
+	return &blackouts[0], nil
+}
+
+func writeBlackoutConflict(w http.ResponseWriter, blackout *storage.Blackout) {
+	writeJSON(w, http.StatusConflict, map[string]any{
+		"error":    "period is blocked",
+		"blackout": newBlackoutResponse(*blackout),
+	})
+}
+
+// writeBlackoutEvents exports the blackouts as opaque events.
+func (s *Server) writeBlackoutEvents(builder *strings.Builder, blackouts []storage.Blackout, now time.Time) {
+	for _, b := range blackouts {
+		summary := escapeICS("Indisponible : " + b.Reason)
+		description := summary
+		if b.CreatedBy != "" {
+			description = escapeICS(fmt.Sprintf("%s\nBloque par %s", "Indisponible : "+b.Reason, b.CreatedBy))
+		}
+		lines := []string{
+			"BEGIN:VEVENT",
+			"UID:" + s.icsUID(fmt.Sprintf("blackout-%d", b.ID)),
+			"DTSTAMP:" + formatICSTime(now),
+			"DTSTART:" + formatICSTime(b.Start),
+			"DTEND:" + formatICSTime(b.End),
+			"SUMMARY:" + summary,
+			"DESCRIPTION:" + description,
+			"CATEGORIES:BLACKOUT",
+			"TRANSP:OPAQUE",
+			"STATUS:CONFIRMED",
+			"END:VEVENT",
+		}
+		for _, line := range lines {
+			builder.WriteString(line)
+			builder.WriteString("\r\n")
+		}
+	}
+}
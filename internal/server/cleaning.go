@@ -0,0 +1,118 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"AppartmentBooker/internal/storage"
+)
+
+// cleaningBlock is the turnover buffer following a departure, when the
+// apartment is kept free for cleaning.
+type cleaningBlock struct {
+	ReservationID int64    `json:"reservation_id,omitempty"`
+	SeriesID      int64    `json:"series_id,omitempty"`
+	Person        string   `json:"person"`
+	Rooms         []string `json:"rooms"`
+	Start         string   `json:"start"`
+	End           string   `json:"end"`
+}
+
+// cleaningBlocks returns the buffer following each active reservation.
+func (s *Server) cleaningBlocks(reservations []storage.Reservation) []cleaningBlock {
+	buffer := s.store.Turnover()
+	out := []cleaningBlock{}
+	if buffer <= 0 {
+		return out
+	}
+	for _, res := range reservations {
+		if !res.Active() {
+			continue
+		}
+		out = append(out, cleaningBlock{
+			ReservationID: res.ID,
+			SeriesID:      res.SeriesID,
+			Person:        res.Person,
+			Rooms:         append([]string{}, res.Rooms...),
+			Start:         res.End.Format(time.RFC3339),
+			End:           res.End.Add(buffer).Format(time.RFC3339),
+		})
+	}
+	return out
+}
+
+// handleCleaning lists the cleaning blocks following the departures between
+// from and to (default: every reservation). It is only served when cleaning
+// blocks are enabled.
+func (s *Server) handleCleaning(w http.ResponseWriter, r *http.Request) {
+	if !s.isAuthenticated(r) {
+		s.writeUnauthorized(w)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !s.cleaningBlocksEnabled {
+		http.NotFound(w, r)
+		return
+	}
+
+	var (
+		reservations []storage.Reservation
+		err          error
+	)
+	query := r.URL.Query()
+	if query.Get("from") == "" && query.Get("to") == "" {
+		reservations, err = s.store.ListReservations(r.Context())
+	} else {
+		from, errFrom := time.Parse(time.RFC3339, query.Get("from"))
+		to, errTo := time.Parse(time.RFC3339, query.Get("to"))
+		if errFrom != nil || errTo != nil || !to.After(from) {
+			http.Error(w, "invalid range", http.StatusBadRequest)
+			return
+		}
+		reservations, err = s.store.ListReservationsBetween(r.Context(), from.Add(-s.store.Turnover()), to)
+	}
+	if err != nil {
+		http.Error(w, "failed to list reservations", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, s.cleaningBlocks(reservations))
+}
+
+// writeCleaningEvents adds the cleaning blocks to the calendar feed.
+func (s *Server) writeCleaningEvents(builder *strings.Builder, reservations []storage.Reservation, now time.Time) {
+	buffer := s.store.Turnover()
+	for _, res := range reservations {
+		if !res.Active() {
+			continue
+		}
+		uid := s.icsUID(fmt.Sprintf("cleaning-%d", res.ID))
+		if res.SeriesID != 0 {
+			uid = s.icsUID(fmt.Sprintf("cleaning-%d-%s", res.SeriesID, formatICSTime(res.Occurrence)))
+		}
+		summary := escapeICS("Menage apres " + res.Person)
+		lines := []string{
+			"BEGIN:VEVENT",
+			"UID:" + uid,
+			"DTSTAMP:" + formatICSTime(now),
+			"DTSTART:" + formatICSTime(res.End),
+			"DTEND:" + formatICSTime(res.End.Add(buffer)),
+			"SUMMARY:" + summary,
+		}
+		if location := s.roomNames(res.Rooms); location != "" {
+			lines = append(lines, "LOCATION:"+escapeICS(location))
+		}
+		lines = append(lines, "CATEGORIES:CLEANING", "TRANSP:OPAQUE", "STATUS:CONFIRMED", "END:VEVENT")
+		for _, line := range lines {
+			builder.WriteString(line)
+			builder.WriteString("\r\n")
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"AppartmentBooker/internal/storage"
+)
+
+// maxCommentLength caps the size of a thread message, in bytes.
+const maxCommentLength = 4000
+
+// handleComments serves /api/reservations/{id}/comments (GET lists the
+// thread, POST adds to it) and /api/reservations/{id}/comments/{comment}
+// (PATCH edits, DELETE removes a message of the member, or any message for
+// admins).
+func (s *Server) handleComments(w http.ResponseWriter, r *http.Request, reservationID int64, sub string) {
+	res, err := s.store.GetReservation(r.Context(), reservationID)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, "failed to load reservation", http.StatusInternalServerError)
+		return
+	}
+
+	if sub == "" {
+		switch r.Method {
+		case http.MethodGet:
+			comments, err := s.store.ListComments(r.Context(), reservationID)
+			if err != nil {
+				http.Error(w, "failed to list comments", http.StatusInternalServerError)
+				return
+			}
+			writeJSON(w, http.StatusOK, comments)
+		case http.MethodPost:
+			s.postComment(w, r, res)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+		return
+	}
+
+	id, err := strconv.ParseInt(sub, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid comment id", http.StatusBadRequest)
+		return
+	}
+	comment, err := s.store.GetComment(r.Context(), id)
+	if errors.Is(err, storage.ErrNotFound) || (err == nil && comment.ReservationID != reservationID) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, "failed to load comment", http.StatusInternalServerError)
+		return
+	}
+
+	member, _ := s.currentMember(r)
+	if member == "" {
+		http.Error(w, "a member login is required", http.StatusForbidden)
+		return
+	}
+	if member != comment.Author && !s.isAdmin(member) {
+		http.Error(w, "only the author may change this comment", http.StatusForbidden)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPatch:
+		body, ok := readCommentBody(w, r)
+		if !ok {
+			return
+		}
+		if err := s.store.UpdateComment(r.Context(), id, body); err != nil {
+			http.Error(w, "failed to update", http.StatusInternalServerError)
+			return
+		}
+		s.recordMentions(r.Context(), res, id, comment.Author, body)
+		comment.Body = body
+		comment.EditedAt = time.Now().UTC().Truncate(time.Second)
+		writeJSON(w, http.StatusOK, comment)
+	case http.MethodDelete:
+		if err := s.store.DeleteComment(r.Context(), id); err != nil {
+			http.Error(w, "failed to delete", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// postComment adds a message of the logged-in member to the thread.
+func (s *Server) postComment(w http.ResponseWriter, r *http.Request, res storage.Reservation) {
+	member, _ := s.currentMember(r)
+	if member == "" {
+		http.Error(w, "a member login is required", http.StatusForbidden)
+		return
+	}
+	body, ok := readCommentBody(w, r)
+	if !ok {
+		return
+	}
+
+	comment := storage.Comment{
+		ReservationID: res.ID,
+		Author:        member,
+		Person:        s.households[member],
+		Body:          body,
+		CreatedAt:     time.Now().UTC().Truncate(time.Second),
+	}
+	id, err := s.store.CreateComment(r.Context(), comment)
+	if err != nil {
+		http.Error(w, "failed to create", http.StatusInternalServerError)
+		return
+	}
+	comment.ID = id
+	s.recordMentions(r.Context(), res, id, member, body)
+	writeJSON(w, http.StatusCreated, comment)
+}
+
+// readCommentBody decodes {"body": ...}, writing the error and returning
+// false when it is empty or too long.
+func readCommentBody(w http.ResponseWriter, r *http.Request) (string, bool) {
+	var payload struct {
+		Body string `json:"body"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return "", false
+	}
+	body := strings.TrimSpace(payload.Body)
+	if body == "" {
+		http.Error(w, "comment is empty", http.StatusBadRequest)
+		return "", false
+	}
+	if len(body) > maxCommentLength {
+		http.Error(w, "comment is too long", http.StatusBadRequest)
+		return "", false
+	}
+	return body, true
+}
@@ -0,0 +1,391 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"AppartmentBooker/internal/lottery"
+	"AppartmentBooker/internal/notify"
+	"AppartmentBooker/internal/storage"
+)
+
+// LotteryRound is an allocation round for peak periods: households rank the
+// periods they wish between Opens and Closes, then an admin draws the round.
+type LotteryRound struct {
+	ID     string
+	Name   string
+	Opens  time.Time
+	Closes time.Time
+	// Seed feeds the shuffle of the draw, which is fully determined by it
+	// and the wishes.
+	Seed int64
+	// MaxPerHousehold caps the periods a household may win; zero means no
+	// limit.
+	MaxPerHousehold int
+	Periods         []LotteryPeriod
+}
+
+// LotteryPeriod is a stay allocated by a round.
+type LotteryPeriod struct {
+	Name  string
+	Start time.Time
+	End   time.Time
+}
+
+// Round statuses.
+const (
+	roundUpcoming = "upcoming"
+	roundOpen     = "open"
+	roundClosed   = "closed"
+	roundDrawn    = "drawn"
+)
+
+type lotteryPeriodResponse struct {
+	Name  string `json:"name"`
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+type lotteryRoundResponse struct {
+	ID      string                  `json:"id"`
+	Name    string                  `json:"name"`
+	Method  string                  `json:"method"`
+	Opens   string                  `json:"opens"`
+	Closes  string                  `json:"closes"`
+	Status  string                  `json:"status"`
+	Periods []lotteryPeriodResponse `json:"periods"`
+	// Wishes are limited to the member's household until the round is
+	// drawn; admins see them all.
+	Wishes []storage.LotteryWish `json:"wishes"`
+	Draw   *storage.LotteryDraw  `json:"draw,omitempty"`
+}
+
+func (s *Server) findRound(id string) (LotteryRound, bool) {
+	for _, round := range s.lottery {
+		if round.ID == id {
+			return round, true
+		}
+	}
+	return LotteryRound{}, false
+}
+
+func (round LotteryRound) period(name string) (LotteryPeriod, bool) {
+	for _, period := range round.Periods {
+		if period.Name == name {
+			return period, true
+		}
+	}
+	return LotteryPeriod{}, false
+}
+
+// Overlap returns the period of the round overlapping period, if any. A
+// household could otherwise win two stays at once, so rounds are built
+// without overlapping periods.
+func (round LotteryRound) Overlap(period LotteryPeriod) (LotteryPeriod, bool) {
+	for _, other := range round.Periods {
+		if other.Start.Before(period.End) && period.Start.Before(other.End) {
+			return other, true
+		}
+	}
+	return LotteryPeriod{}, false
+}
+
+// roundResponse describes the round as seen by member.
+func (s *Server) roundResponse(r *http.Request, round LotteryRound, member string) (lotteryRoundResponse, error) {
+	out := lotteryRoundResponse{
+		ID:      round.ID,
+		Name:    round.Name,
+		Method:  s.lotteryMethod,
+		Opens:   round.Opens.Format(time.RFC3339),
+		Closes:  round.Closes.Format(time.RFC3339),
+		Periods: make([]lotteryPeriodResponse, 0, len(round.Periods)),
+		Wishes:  []storage.LotteryWish{},
+	}
+	for _, period := range round.Periods {
+		out.Periods = append(out.Periods, lotteryPeriodResponse{
+			Name:  period.Name,
+			Start: period.Start.Format(time.RFC3339),
+			End:   period.End.Format(time.RFC3339),
+		})
+	}
+
+	now := time.Now()
+	switch {
+	case now.Before(round.Opens):
+		out.Status = roundUpcoming
+	case now.Before(round.Closes):
+		out.Status = roundOpen
+	default:
+		out.Status = roundClosed
+	}
+
+	draw, err := s.store.GetLotteryDraw(r.Context(), round.ID)
+	switch {
+	case err == nil:
+		out.Status = roundDrawn
+		out.Method = draw.Method
+		out.Draw = &draw
+	case !errors.Is(err, storage.ErrNotFound):
+		return lotteryRoundResponse{}, err
+	}
+
+	wishes, err := s.store.ListLotteryWishes(r.Context(), round.ID)
+	if err != nil {
+		return lotteryRoundResponse{}, err
+	}
+	for _, wish := range wishes {
+		if out.Draw != nil || s.isAdmin(member) || (member != "" && s.households[member] == wish.Person) {
+			out.Wishes = append(out.Wishes, wish)
+		}
+	}
+	return out, nil
+}
+
+// handleLotteryRounds lists the allocation rounds.
+func (s *Server) handleLotteryRounds(w http.ResponseWriter, r *http.Request) {
+	if !s.isAuthenticated(r) {
+		s.writeUnauthorized(w)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	member, _ := s.currentMember(r)
+	out := make([]lotteryRoundResponse, 0, len(s.lottery))
+	for _, round := range s.lottery {
+		item, err := s.roundResponse(r, round, member)
+		if err != nil {
+			http.Error(w, "failed to load lottery", http.StatusInternalServerError)
+			return
+		}
+		out = append(out, item)
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// handleLotteryRound serves /api/lottery/{id} (GET), /api/lottery/{id}/wishes
+// (PUT) and /api/lottery/{id}/draw (POST).
+func (s *Server) handleLotteryRound(w http.ResponseWriter, r *http.Request) {
+	if !s.isAuthenticated(r) {
+		s.writeUnauthorized(w)
+		return
+	}
+
+	id, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/lottery/"), "/")
+	round, ok := s.findRound(id)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	member, _ := s.currentMember(r)
+	switch action {
+	case "":
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		out, err := s.roundResponse(r, round, member)
+		if err != nil {
+			http.Error(w, "failed to load lottery", http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, out)
+	case "wishes":
+		if r.Method != http.MethodPut {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		s.setWishes(w, r, round, member)
+	case "draw":
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		s.drawRound(w, r, round, member)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// setWishes replaces the ranked wishes of a household while the round is
+// open.
+func (s *Server) setWishes(w http.ResponseWriter, r *http.Request, round LotteryRound, member string) {
+	var payload struct {
+		Person  string   `json:"person"`
+		Periods []string `json:"periods"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	if payload.Person == "" && member != "" {
+		payload.Person = s.households[member]
+	}
+	if !isKnownPerson(payload.Person, s.people) {
+		http.Error(w, "unknown person", http.StatusBadRequest)
+		return
+	}
+	if member != "" && s.households[member] != payload.Person {
+		http.Error(w, "member does not belong to this household", http.StatusForbidden)
+		return
+	}
+
+	now := time.Now()
+	if now.Before(round.Opens) || !now.Before(round.Closes) {
+		http.Error(w, "round is not open", http.StatusConflict)
+		return
+	}
+
+	seen := make(map[string]bool, len(payload.Periods))
+	for _, name := range payload.Periods {
+		if _, ok := round.period(name); !ok {
+			http.Error(w, "unknown period", http.StatusBadRequest)
+			return
+		}
+		if seen[name] {
+			http.Error(w, "duplicate period", http.StatusBadRequest)
+			return
+		}
+		seen[name] = true
+	}
+
+	if err := s.store.SetLotteryWishes(r.Context(), round.ID, payload.Person, member, payload.Periods); err != nil {
+		http.Error(w, "failed to save wishes", http.StatusInternalServerError)
+		return
+	}
+
+	out, err := s.roundResponse(r, round, member)
+	if err != nil {
+		http.Error(w, "failed to load lottery", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// drawRound allocates the periods of a closed round and books them as
+// confirmed reservations. Periods that can no longer be booked are left out
+// of the draw.
+func (s *Server) drawRound(w http.ResponseWriter, r *http.Request, round LotteryRound, member string) {
+	if !s.isAdmin(member) {
+		http.Error(w, "admin rights required", http.StatusForbidden)
+		return
+	}
+	if time.Now().Before(round.Closes) {
+		http.Error(w, "round is still open", http.StatusConflict)
+		return
+	}
+
+	wishes, err := s.store.ListLotteryWishes(r.Context(), round.ID)
+	if err != nil {
+		http.Error(w, "failed to list wishes", http.StatusInternalServerError)
+		return
+	}
+	wins, err := s.store.LotteryWins(r.Context())
+	if err != nil {
+		http.Error(w, "failed to load past rounds", http.StatusInternalServerError)
+		return
+	}
+
+	available := make(map[string]bool, len(round.Periods))
+	for _, period := range round.Periods {
+		blocked, err := s.isBlocked(r.Context(), storage.Reservation{Start: period.Start, End: period.End, Adults: 1})
+		if err != nil {
+			http.Error(w, "failed to check availability", http.StatusInternalServerError)
+			return
+		}
+		available[period.Name] = !blocked
+	}
+
+	households := make([]string, 0, len(s.people))
+	for _, person := range s.people {
+		households = append(households, person.Name)
+	}
+	input := make([]lottery.Wish, 0, len(wishes))
+	wisher := make(map[string]string)
+	for _, wish := range wishes {
+		input = append(input, lottery.Wish{Household: wish.Person, Rank: wish.Rank, Period: wish.Period})
+		wisher[wish.Person] = wish.Member
+	}
+
+	order := lottery.Priority(s.lotteryMethod, round.Seed, households, wins)
+	draw := storage.LotteryDraw{
+		Round:    round.ID,
+		Method:   s.lotteryMethod,
+		Seed:     round.Seed,
+		Priority: order,
+		DrawnBy:  member,
+		DrawnAt:  time.Now(),
+	}
+	var reservations []storage.Reservation
+	for _, allocation := range lottery.Allocate(order, input, available, round.MaxPerHousehold) {
+		period, _ := round.period(allocation.Period)
+		draw.Results = append(draw.Results, storage.LotteryResult{
+			Period: allocation.Period,
+			Person: allocation.Household,
+			Rank:   allocation.Rank,
+		})
+		reservations = append(reservations, storage.Reservation{
+			Person:  allocation.Household,
+			Member:  wisher[allocation.Household],
+			Start:   period.Start,
+			End:     period.End,
+			Comment: fmt.Sprintf("%s (%s)", round.Name, period.Name),
+			Adults:  1,
+			Status:  storage.StatusConfirmed,
+		})
+	}
+
+	draw, err = s.store.SaveLotteryDraw(r.Context(), draw, reservations)
+	if errors.Is(err, storage.ErrAlreadyDrawn) {
+		http.Error(w, "round already drawn", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		if writeStoreConflict(w, err) {
+			return
+		}
+		http.Error(w, "failed to save draw", http.StatusInternalServerError)
+		return
+	}
+
+	s.notifyDraw(round, draw, wishes)
+
+	out, err := s.roundResponse(r, round, member)
+	if err != nil {
+		http.Error(w, "failed to load lottery", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// notifyDraw tells every household that took part what it received.
+func (s *Server) notifyDraw(round LotteryRound, draw storage.LotteryDraw, wishes []storage.LotteryWish) {
+	won := make(map[string][]string)
+	for _, result := range draw.Results {
+		period, _ := round.period(result.Period)
+		won[result.Person] = append(won[result.Person], fmt.Sprintf("%s (%s)", period.Name, s.describeStay(period.Start, period.End)))
+	}
+
+	notified := make(map[string]bool)
+	for _, wish := range wishes {
+		if notified[wish.Person] {
+			continue
+		}
+		notified[wish.Person] = true
+
+		body := fmt.Sprintf("Le tirage \"%s\" ne vous a attribue aucune periode.", round.Name)
+		if periods := won[wish.Person]; len(periods) > 0 {
+			body = fmt.Sprintf("Le tirage \"%s\" vous a attribue :\n- %s\nLes reservations sont confirmees dans le planning.", round.Name, strings.Join(periods, "\n- "))
+		}
+		s.notifyHousehold(wish.Person, notify.Message{Subject: "Resultat du tirage " + round.Name, Body: body})
+	}
+}
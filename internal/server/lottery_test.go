@@ -0,0 +1,35 @@
+package server
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRoundOverlap(t *testing.T) {
+	noon := func(month time.Month, day int) time.Time {
+		return time.Date(2027, month, day, 12, 0, 0, 0, time.UTC)
+	}
+	round := LotteryRound{Periods: []LotteryPeriod{
+		{Name: "Noel", Start: noon(time.December, 20), End: noon(time.December, 27)},
+		{Name: "Nouvel an", Start: noon(time.December, 27), End: noon(time.January, 3).AddDate(1, 0, 0)},
+	}}
+	tests := []struct {
+		name       string
+		start, end time.Time
+		want       string
+	}{
+		{"before", noon(time.December, 13), noon(time.December, 20), ""},
+		{"inside", noon(time.December, 22), noon(time.December, 24), "Noel"},
+		{"straddling", noon(time.December, 26), noon(time.December, 28), "Noel"},
+		{"covering", noon(time.December, 1), noon(time.December, 31), "Noel"},
+		{"after", noon(time.January, 3).AddDate(1, 0, 0), noon(time.January, 10).AddDate(1, 0, 0), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			other, ok := round.Overlap(LotteryPeriod{Name: "test", Start: tt.start, End: tt.end})
+			if ok != (tt.want != "") || other.Name != tt.want {
+				t.Fatalf("Overlap = %q, %v, want %q", other.Name, ok, tt.want)
+			}
+		})
+	}
+}
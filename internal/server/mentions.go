@@ -0,0 +1,117 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"AppartmentBooker/internal/mention"
+	"AppartmentBooker/internal/notify"
+	"AppartmentBooker/internal/storage"
+)
+
+// mentionNames lists the names that may be mentioned: households and their
+// members.
+func (s *Server) mentionNames() []string {
+	var names []string
+	for _, person := range s.people {
+		names = append(names, person.Name)
+		for _, member := range person.Members {
+			names = append(names, member.Name)
+		}
+	}
+	return names
+}
+
+// recordMentions stores the names mentioned in a message of the reservation
+// (commentID zero for its description) and notifies those newly mentioned.
+// Failures are logged: the message itself is already saved.
+func (s *Server) recordMentions(ctx context.Context, res storage.Reservation, commentID int64, author, text string) {
+	if author == "" {
+		author = res.Person
+	}
+	added, err := s.store.SetMentions(ctx, res.ID, commentID, author, mention.Find(text, s.mentionNames()))
+	if err != nil {
+		log.Printf("warning: failed to record mentions of reservation %d: %v", res.ID, err)
+		return
+	}
+
+	msg := notify.Message{
+		Subject: fmt.Sprintf("%s vous a mentionne", author),
+		Body:    fmt.Sprintf("%s vous a mentionne a propos du sejour de %s (%s) :\n%s", author, res.Person, s.describeStay(res.Start, res.End), text),
+	}
+	for _, name := range added {
+		s.notifyMention(name, author, msg)
+	}
+}
+
+// notifyMention notifies the mentioned member, or every member of the
+// mentioned household, the author aside.
+func (s *Server) notifyMention(name, author string, msg notify.Message) {
+	for _, person := range s.people {
+		for _, member := range person.Members {
+			if member.Name == author || (member.Name != name && person.Name != name) {
+				continue
+			}
+			s.notifier.Send(recipientOf(member), msg)
+		}
+	}
+}
+
+// handleMentions serves the inbox of the logged-in member: GET /api/mentions
+// lists the messages mentioning them or their household (unread=1 keeps the
+// unread ones), POST /api/mentions/read marks them read, all of them or the
+// given ids.
+func (s *Server) handleMentions(w http.ResponseWriter, r *http.Request) {
+	if !s.isAuthenticated(r) {
+		s.writeUnauthorized(w)
+		return
+	}
+
+	member, _ := s.currentMember(r)
+	if member == "" {
+		http.Error(w, "a member login is required", http.StatusForbidden)
+		return
+	}
+	names := []string{member}
+	if household := s.households[member]; household != "" && household != member {
+		names = append(names, household)
+	}
+
+	switch r.URL.Path {
+	case "/api/mentions":
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		mentions, err := s.store.ListMentions(r.Context(), names, r.URL.Query().Get("unread") == "1")
+		if err != nil {
+			http.Error(w, "failed to list mentions", http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, mentions)
+	case "/api/mentions/read":
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var payload struct {
+			IDs []int64 `json:"ids"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil && !errors.Is(err, io.EOF) {
+			http.Error(w, "invalid body", http.StatusBadRequest)
+			return
+		}
+		if err := s.store.MarkMentionsRead(r.Context(), names, payload.IDs); err != nil {
+			http.Error(w, "failed to update", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.NotFound(w, r)
+	}
+}
@@ -0,0 +1,163 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"AppartmentBooker/internal/report"
+	"AppartmentBooker/internal/storage"
+)
+
+type metersResponse struct {
+	Readings []storage.MeterReading `json:"readings"`
+	Usage    []report.Usage         `json:"usage"`
+	Cost     float64                `json:"cost"`
+}
+
+// handleMeters serves /api/reservations/{id}/meters (GET lists the meter
+// readings of the stay with its consumption, POST records a reading) and
+// /api/reservations/{id}/meters/{reading} (DELETE removes a reading). Only
+// the occupants and admins record readings.
+func (s *Server) handleMeters(w http.ResponseWriter, r *http.Request, reservationID int64, sub string) {
+	res, err := s.store.GetReservation(r.Context(), reservationID)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, "failed to load reservation", http.StatusInternalServerError)
+		return
+	}
+
+	member, _ := s.currentMember(r)
+	if r.Method != http.MethodGet && member != "" && s.households[member] != res.Person && !s.isAdmin(member) {
+		http.Error(w, "member does not belong to this household", http.StatusForbidden)
+		return
+	}
+
+	if sub != "" {
+		id, err := strconv.ParseInt(sub, 10, 64)
+		if err != nil {
+			http.Error(w, "invalid reading id", http.StatusBadRequest)
+			return
+		}
+		if r.Method != http.MethodDelete {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		readings, err := s.store.ListMeterReadings(r.Context(), reservationID)
+		if err != nil {
+			http.Error(w, "failed to list readings", http.StatusInternalServerError)
+			return
+		}
+		found := false
+		for _, reading := range readings {
+			found = found || reading.ID == id
+		}
+		if !found {
+			http.NotFound(w, r)
+			return
+		}
+		if err := s.store.DeleteMeterReading(r.Context(), id); err != nil {
+			http.Error(w, "failed to delete", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var payload struct {
+			Meter string   `json:"meter"`
+			Phase string   `json:"phase"`
+			Value *float64 `json:"value"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, "invalid body", http.StatusBadRequest)
+			return
+		}
+		switch payload.Meter {
+		case storage.MeterElectricity, storage.MeterWater, storage.MeterGas:
+		default:
+			http.Error(w, "invalid meter", http.StatusBadRequest)
+			return
+		}
+		if payload.Phase != storage.PhaseArrival && payload.Phase != storage.PhaseDeparture {
+			http.Error(w, "invalid phase", http.StatusBadRequest)
+			return
+		}
+		if payload.Value == nil || *payload.Value < 0 {
+			http.Error(w, "invalid value", http.StatusBadRequest)
+			return
+		}
+		if _, err := s.store.SetMeterReading(r.Context(), storage.MeterReading{
+			ReservationID: reservationID,
+			Meter:         payload.Meter,
+			Phase:         payload.Phase,
+			Value:         *payload.Value,
+			RecordedBy:    member,
+			RecordedAt:    time.Now(),
+		}); err != nil {
+			http.Error(w, "failed to record reading", http.StatusInternalServerError)
+			return
+		}
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	readings, err := s.store.ListMeterReadings(r.Context(), reservationID)
+	if err != nil {
+		http.Error(w, "failed to list readings", http.StatusInternalServerError)
+		return
+	}
+	usage, cost := report.StayUsage(readings, s.meterPrices)
+	writeJSON(w, http.StatusOK, metersResponse{Readings: readings, Usage: usage, Cost: cost})
+}
+
+// handleConsumption serves the meter consumption of the stays starting in a
+// year, per stay and per household, as JSON or as CSV with format=csv.
+func (s *Server) handleConsumption(w http.ResponseWriter, r *http.Request) {
+	if !s.isAuthenticated(r) {
+		s.writeUnauthorized(w)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	year, ok := s.statsYear(r)
+	if !ok {
+		http.Error(w, "invalid year", http.StatusBadRequest)
+		return
+	}
+
+	people := make([]string, 0, len(s.people))
+	for _, person := range s.people {
+		people = append(people, person.Name)
+	}
+	consumption, err := report.BuildConsumption(r.Context(), s.store, year, s.meterPrices, report.Options{
+		People:   people,
+		Location: s.location,
+	})
+	if err != nil {
+		http.Error(w, "failed to compute consumption", http.StatusInternalServerError)
+		return
+	}
+
+	if r.URL.Query().Get("format") == "csv" {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=consommation-%d.csv", year))
+		_ = consumption.WriteCSV(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, consumption)
+}
@@ -0,0 +1,310 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"AppartmentBooker/internal/storage"
+)
+
+// maxNoteLength caps the text of a day note.
+const maxNoteLength = 500
+
+type noteResponse struct {
+	ID         int64  `json:"id"`
+	Start      string `json:"start"`
+	End        string `json:"end"`
+	Text       string `json:"text"`
+	Author     string `json:"author"`
+	Person     string `json:"person"`
+	Visibility string `json:"visibility"`
+	CreatedAt  string `json:"created_at"`
+}
+
+func newNoteResponse(n storage.DayNote) noteResponse {
+	return noteResponse{
+		ID:         n.ID,
+		Start:      n.Start.Format(time.RFC3339),
+		End:        n.End.Format(time.RFC3339),
+		Text:       n.Text,
+		Author:     n.Author,
+		Person:     n.Person,
+		Visibility: n.Visibility,
+		CreatedAt:  n.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+type notePayload struct {
+	Start      string `json:"start"`
+	End        string `json:"end"`
+	Day        string `json:"day"`
+	Half       string `json:"half"`
+	Text       string `json:"text"`
+	Visibility string `json:"visibility"`
+}
+
+// noteVisible reports whether member may see the note: notes for everybody
+// are shown to all, household notes to the members of the author's household
+// and private notes to their author only.
+func (s *Server) noteVisible(member string, n storage.DayNote) bool {
+	switch n.Visibility {
+	case storage.NoteVisibilityAll:
+		return true
+	case storage.NoteVisibilityHousehold:
+		return member != "" && s.households[member] == n.Person
+	default:
+		return member != "" && member == n.Author
+	}
+}
+
+// visibleNotes keeps the notes member may see.
+func (s *Server) visibleNotes(member string, notes []storage.DayNote) []storage.DayNote {
+	out := notes[:0:0]
+	for _, n := range notes {
+		if s.noteVisible(member, n) {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+// handleNotes lists the day notes (GET, optionally between from and to) or
+// creates one (POST, members only).
+func (s *Server) handleNotes(w http.ResponseWriter, r *http.Request) {
+	member, ok := s.currentMember(r)
+	if !ok {
+		s.writeUnauthorized(w)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		s.listNotes(w, r, member)
+	case http.MethodPost:
+		if member == "" {
+			http.Error(w, "a member login is required", http.StatusForbidden)
+			return
+		}
+		s.createNote(w, r, member)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) listNotes(w http.ResponseWriter, r *http.Request, member string) {
+	var (
+		notes []storage.DayNote
+		err   error
+	)
+	query := r.URL.Query()
+	if query.Get("from") != "" || query.Get("to") != "" {
+		from, errFrom := s.parseDay(query.Get("from"))
+		to, errTo := s.parseDay(query.Get("to"))
+		if errFrom != nil || errTo != nil || !to.After(from) {
+			http.Error(w, "invalid range", http.StatusBadRequest)
+			return
+		}
+		notes, err = s.store.ListNotesBetween(r.Context(), from, to)
+	} else {
+		notes, err = s.store.ListNotes(r.Context())
+	}
+	if err != nil {
+		http.Error(w, "failed to list notes", http.StatusInternalServerError)
+		return
+	}
+
+	out := []noteResponse{}
+	for _, n := range s.visibleNotes(member, notes) {
+		out = append(out, newNoteResponse(n))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) createNote(w http.ResponseWriter, r *http.Request, member string) {
+	var payload notePayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	note := storage.DayNote{
+		Author:    member,
+		Person:    s.households[member],
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+	}
+	if err := s.applyNotePayload(&note, payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	id, err := s.store.CreateNote(r.Context(), note)
+	if err != nil {
+		http.Error(w, "failed to create", http.StatusInternalServerError)
+		return
+	}
+
+	note.ID = id
+	writeJSON(w, http.StatusCreated, newNoteResponse(note))
+}
+
+// applyNotePayload validates the payload into the note. The period is either
+// start and end, on half-day boundaries, or a day with an optional half
+// ("morning" or "afternoon").
+func (s *Server) applyNotePayload(note *storage.DayNote, payload notePayload) error {
+	if payload.Day != "" {
+		day, err := s.parseDay(payload.Day)
+		if err != nil {
+			return errors.New("invalid day")
+		}
+		noon := day.Add(storage.HalfDay)
+		switch payload.Half {
+		case "":
+			note.Start, note.End = day, day.AddDate(0, 0, 1)
+		case "morning":
+			note.Start, note.End = day, noon
+		case "afternoon":
+			note.Start, note.End = noon, day.AddDate(0, 0, 1)
+		default:
+			return errors.New("invalid half")
+		}
+	} else {
+		start, err := time.Parse(time.RFC3339, payload.Start)
+		if err != nil || !s.onHalfDayBoundary(start) {
+			return errors.New("invalid start")
+		}
+		end, err := time.Parse(time.RFC3339, payload.End)
+		if err != nil || !end.After(start) || !s.onHalfDayBoundary(end) {
+			return errors.New("invalid end")
+		}
+		note.Start, note.End = start, end
+	}
+
+	note.Start, note.End = note.Start.UTC(), note.End.UTC()
+
+	note.Text = strings.TrimSpace(payload.Text)
+	if note.Text == "" {
+		return errors.New("text is required")
+	}
+	if len(note.Text) > maxNoteLength {
+		return fmt.Errorf("text exceeds %d characters", maxNoteLength)
+	}
+
+	note.Visibility = payload.Visibility
+	switch note.Visibility {
+	case "":
+		note.Visibility = storage.NoteVisibilityAll
+	case storage.NoteVisibilityAll, storage.NoteVisibilityHousehold, storage.NoteVisibilityPrivate:
+	default:
+		return errors.New("invalid visibility")
+	}
+	return nil
+}
+
+// onHalfDayBoundary reports whether t falls at midnight or noon, local time.
+func (s *Server) onHalfDayBoundary(t time.Time) bool {
+	local := t.In(s.location)
+	return (local.Hour() == 0 || local.Hour() == 12) && local.Minute() == 0 && local.Second() == 0
+}
+
+// handleNote updates (PATCH) or deletes (DELETE) a day note; only its author
+// or an admin may.
+func (s *Server) handleNote(w http.ResponseWriter, r *http.Request) {
+	member, ok := s.currentMember(r)
+	if !ok {
+		s.writeUnauthorized(w)
+		return
+	}
+
+	id, err := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, "/api/notes/"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	note, err := s.store.GetNote(r.Context(), id)
+	if errors.Is(err, storage.ErrNotFound) || (err == nil && !s.noteVisible(member, note) && !s.isAdmin(member)) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, "failed to load note", http.StatusInternalServerError)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, newNoteResponse(note))
+		return
+	case http.MethodPatch, http.MethodDelete:
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if member == "" || (member != note.Author && !s.isAdmin(member)) {
+		http.Error(w, "only the author may change this note", http.StatusForbidden)
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		if err := s.store.DeleteNote(r.Context(), id); err != nil {
+			http.Error(w, "failed to delete", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	// Fields left out keep their value.
+	payload := notePayload{
+		Start:      note.Start.Format(time.RFC3339),
+		End:        note.End.Format(time.RFC3339),
+		Text:       note.Text,
+		Visibility: note.Visibility,
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	if err := s.applyNotePayload(&note, payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := s.store.UpdateNote(r.Context(), note); err != nil {
+		http.Error(w, "failed to update", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, newNoteResponse(note))
+}
+
+// writeNoteEvents exports the notes meant for everybody as transparent
+// events: they do not take the apartment.
+func (s *Server) writeNoteEvents(builder *strings.Builder, notes []storage.DayNote, now time.Time) {
+	for _, n := range notes {
+		if n.Visibility != storage.NoteVisibilityAll {
+			continue
+		}
+		lines := []string{
+			"BEGIN:VEVENT",
+			"UID:" + s.icsUID(fmt.Sprintf("note-%d", n.ID)),
+			"DTSTAMP:" + formatICSTime(now),
+			"DTSTART:" + formatICSTime(n.Start),
+			"DTEND:" + formatICSTime(n.End),
+			"SUMMARY:" + escapeICS("Note : "+n.Text),
+			"DESCRIPTION:" + escapeICS(fmt.Sprintf("%s\nAjoutee par %s", n.Text, n.Author)),
+			"CATEGORIES:NOTE",
+			"TRANSP:TRANSPARENT",
+			"STATUS:CONFIRMED",
+			"END:VEVENT",
+		}
+		for _, line := range lines {
+			builder.WriteString(line)
+			builder.WriteString("\r\n")
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package server
+
+import (
+	"fmt"
+	"time"
+
+	"AppartmentBooker/internal/notify"
+)
+
+// notifyMember sends the message to the member on the channels they chose.
+func (s *Server) notifyMember(name string, msg notify.Message) {
+	for _, person := range s.people {
+		for _, member := range person.Members {
+			if member.Name == name {
+				s.notifier.Send(recipientOf(member), msg)
+				return
+			}
+		}
+	}
+}
+
+// notifyHousehold sends the message to every member of the household.
+func (s *Server) notifyHousehold(household string, msg notify.Message) {
+	for _, person := range s.people {
+		if person.Name != household {
+			continue
+		}
+		for _, member := range person.Members {
+			s.notifier.Send(recipientOf(member), msg)
+		}
+	}
+}
+
+func recipientOf(member Member) notify.Recipient {
+	return notify.Recipient{
+		Name:     member.Name,
+		Email:    member.Email,
+		Webhook:  member.Webhook,
+		Channels: member.Notify,
+	}
+}
+
+// describeStay formats a stay for notifications, in the time zone of the
+// property.
+func (s *Server) describeStay(start, end time.Time) string {
+	const layout = "02/01/2006 15h04"
+	return fmt.Sprintf("du %s au %s", start.In(s.location).Format(layout), end.In(s.location).Format(layout))
+}
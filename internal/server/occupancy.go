@@ -0,0 +1,145 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"AppartmentBooker/internal/storage"
+)
+
+const (
+	capacityReject = "reject"
+	capacityWarn   = "warn"
+
+	// maxOccupancyRange bounds the window accepted by /api/occupancy.
+	maxOccupancyRange = 2 * 366 * 24 * time.Hour
+)
+
+type slotOccupancy struct {
+	Start    string `json:"start"`
+	End      string `json:"end"`
+	Adults   int    `json:"adults"`
+	Children int    `json:"children"`
+	// Attendees counts the households coming to an event.
+	Attendees int `json:"attendees,omitempty"`
+	Total     int `json:"total"`
+}
+
+// computeOccupancy sums the guests, and event attendees, present during each
+// half-day slot of [from, to).
+func computeOccupancy(reservations []storage.Reservation, from, to time.Time) []slotOccupancy {
+	slots := storage.HalfDaySlots(from, to)
+	out := make([]slotOccupancy, 0, len(slots))
+	for _, start := range slots {
+		end := start.Add(storage.HalfDay)
+		slot := slotOccupancy{
+			Start: start.Format(time.RFC3339),
+			End:   end.Format(time.RFC3339),
+		}
+		for _, res := range reservations {
+			if !res.Active() || !storage.Overlaps(res.Start, res.End, start, end) {
+				continue
+			}
+			slot.Adults += res.Adults
+			slot.Children += res.Children
+			slot.Attendees += res.Attendees
+		}
+		slot.Total = slot.Adults + slot.Children + slot.Attendees
+		out = append(out, slot)
+	}
+	return out
+}
+
+// exceededSlots returns the half-day slots in which adding the reservation
+// would go beyond the configured capacity.
+func (s *Server) exceededSlots(ctx context.Context, res storage.Reservation) ([]slotOccupancy, error) {
+	// A reservation being edited is counted with its new dates only.
+	return s.exceededSlotsFor(ctx, res, func(other storage.Reservation) bool {
+		return replaces(res, other)
+	})
+}
+
+// exceededSlotsFor is exceededSlots leaving out the stored reservations for
+// which replaced returns true.
+func (s *Server) exceededSlotsFor(ctx context.Context, res storage.Reservation, replaced func(storage.Reservation) bool) ([]slotOccupancy, error) {
+	if s.capacity <= 0 {
+		return nil, nil
+	}
+
+	existing, err := s.store.ListReservationsBetween(ctx, res.Start, res.End)
+	if err != nil {
+		return nil, err
+	}
+
+	others := existing[:0]
+	for _, other := range existing {
+		if !replaced(other) {
+			others = append(others, other)
+		}
+	}
+
+	var over []slotOccupancy
+	for _, slot := range computeOccupancy(append(others, res), res.Start, res.End) {
+		if slot.Total > s.capacity {
+			over = append(over, slot)
+		}
+	}
+	return over, nil
+}
+
+// replaces reports whether saving res supersedes the stored reservation
+// other: res is an edit of it, or the same occurrence of a series moved.
+func replaces(res, other storage.Reservation) bool {
+	if res.ID != 0 {
+		return other.ID == res.ID
+	}
+	return res.SeriesID != 0 && other.SeriesID == res.SeriesID && other.Occurrence.Equal(res.Occurrence)
+}
+
+func capacityWarning(capacity int, over []slotOccupancy) string {
+	return fmt.Sprintf("capacity of %d exceeded on %d half-day(s)", capacity, len(over))
+}
+
+func (s *Server) handleOccupancy(w http.ResponseWriter, r *http.Request) {
+	if !s.isAuthenticated(r) {
+		s.writeUnauthorized(w)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	from, err := time.Parse(time.RFC3339, r.URL.Query().Get("from"))
+	if err != nil {
+		http.Error(w, "invalid from", http.StatusBadRequest)
+		return
+	}
+	to, err := time.Parse(time.RFC3339, r.URL.Query().Get("to"))
+	if err != nil {
+		http.Error(w, "invalid to", http.StatusBadRequest)
+		return
+	}
+	if !to.After(from) || to.Sub(from) > maxOccupancyRange {
+		http.Error(w, "invalid range", http.StatusBadRequest)
+		return
+	}
+
+	reservations, err := s.store.ListReservationsBetween(r.Context(), from, to)
+	if err != nil {
+		http.Error(w, "failed to list reservations", http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Capacity int             `json:"capacity,omitempty"`
+		Slots    []slotOccupancy `json:"slots"`
+	}{
+		Capacity: s.capacity,
+		Slots:    computeOccupancy(reservations, from, to),
+	}
+	writeJSON(w, http.StatusOK, response)
+}
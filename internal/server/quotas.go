@@ -0,0 +1,248 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"AppartmentBooker/internal/storage"
+)
+
+const (
+	quotaReject = "reject"
+	quotaWarn   = "warn"
+
+	quotaNights     = "nights"
+	quotaHighSeason = "high_season_weeks"
+)
+
+// Quota limits how much a household uses the property over a calendar year.
+// Zero values mean no limit.
+type Quota struct {
+	Nights          int
+	HighSeasonWeeks int
+}
+
+// quotaUsage is the use of the property by a household over a year, in
+// half-day slots.
+type quotaUsage struct {
+	halfDays           int
+	highSeasonHalfDays int
+}
+
+func (u quotaUsage) nights() float64 {
+	return float64(u.halfDays) / 2
+}
+
+// highSeasonWeeks returns the high season use in weeks, rounded to the
+// hundredth.
+func (u quotaUsage) highSeasonWeeks() float64 {
+	return math.Round(float64(u.highSeasonHalfDays)/14*100) / 100
+}
+
+// yearBounds returns the first instant of the year and of the next one in
+// the time zone of the property.
+func (s *Server) yearBounds(year int) (time.Time, time.Time) {
+	return time.Date(year, time.January, 1, 0, 0, 0, 0, s.location),
+		time.Date(year+1, time.January, 1, 0, 0, 0, 0, s.location)
+}
+
+// isHighSeason reports whether the slot falls in a high season period.
+func (s *Server) isHighSeason(slot time.Time) bool {
+	for _, period := range s.highSeason {
+		if period.Contains(slot.In(s.location)) {
+			return true
+		}
+	}
+	return false
+}
+
+// addUsage counts the half-day slots of the reservation falling in the year.
+// Slots follow the local calendar, as in the yearly report.
+func (s *Server) addUsage(usage *quotaUsage, res storage.Reservation, from, to time.Time) {
+	for _, slot := range storage.HalfDaysIn(res.Start, res.End, from, to, s.location) {
+		usage.halfDays++
+		if s.isHighSeason(slot) {
+			usage.highSeasonHalfDays++
+		}
+	}
+}
+
+// yearUsage returns the use of the property per household over the year.
+// Declined reservations are not counted, nor those for which skip, when not
+// nil, returns true.
+func (s *Server) yearUsage(ctx context.Context, year int, skip func(storage.Reservation) bool) (map[string]*quotaUsage, error) {
+	from, to := s.yearBounds(year)
+	reservations, err := s.store.ListReservationsBetween(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := make(map[string]*quotaUsage, len(s.people))
+	for _, person := range s.people {
+		usage[person.Name] = &quotaUsage{}
+	}
+	for _, res := range reservations {
+		item, ok := usage[res.Person]
+		if !ok || !res.Active() || (skip != nil && skip(res)) {
+			continue
+		}
+		s.addUsage(item, res, from, to)
+	}
+	return usage, nil
+}
+
+// quotaExcess describes a quota a new reservation would break.
+type quotaExcess struct {
+	Person string  `json:"person"`
+	Year   int     `json:"year"`
+	Quota  string  `json:"quota"`
+	Used   float64 `json:"used"`
+	Limit  int     `json:"limit"`
+}
+
+// exceededQuotas returns the quotas of the household that adding the
+// reservation would break, for every year it touches. When res is an edit,
+// its stored version is not counted.
+func (s *Server) exceededQuotas(ctx context.Context, res storage.Reservation) ([]quotaExcess, error) {
+	return s.exceededQuotasFor(ctx, res.Person, []storage.Reservation{res}, func(other storage.Reservation) bool {
+		return replaces(res, other)
+	})
+}
+
+// exceededQuotasFor returns the quotas of person that adding all the pending
+// reservations would break, for every year they touch. Stored reservations
+// for which replaced returns true are not counted.
+func (s *Server) exceededQuotasFor(ctx context.Context, person string, pending []storage.Reservation, replaced func(storage.Reservation) bool) ([]quotaExcess, error) {
+	quota, ok := s.quotas[person]
+	if !ok || (quota.Nights == 0 && quota.HighSeasonWeeks == 0) || len(pending) == 0 {
+		return nil, nil
+	}
+
+	first, last := pending[0].Start.In(s.location).Year(), 0
+	for _, res := range pending {
+		first = min(first, res.Start.In(s.location).Year())
+		last = max(last, res.End.Add(-time.Nanosecond).In(s.location).Year())
+	}
+
+	var out []quotaExcess
+	for year := first; year <= last; year++ {
+		usage, err := s.yearUsage(ctx, year, replaced)
+		if err != nil {
+			return nil, err
+		}
+		from, to := s.yearBounds(year)
+		total := *usage[person]
+		for _, res := range pending {
+			if res.Active() {
+				s.addUsage(&total, res, from, to)
+			}
+		}
+
+		if quota.Nights > 0 && total.nights() > float64(quota.Nights) {
+			out = append(out, quotaExcess{Person: person, Year: year, Quota: quotaNights, Used: total.nights(), Limit: quota.Nights})
+		}
+		if quota.HighSeasonWeeks > 0 && total.highSeasonWeeks() > float64(quota.HighSeasonWeeks) {
+			out = append(out, quotaExcess{Person: person, Year: year, Quota: quotaHighSeason, Used: total.highSeasonWeeks(), Limit: quota.HighSeasonWeeks})
+		}
+	}
+	return out, nil
+}
+
+func quotaWarning(excess []quotaExcess) string {
+	return fmt.Sprintf("%d quota(s) exceeded", len(excess))
+}
+
+// overrideQuotas applies the quota policy to the quotas a booking breaks:
+// without a reason the booking is refused, and under the reject policy only
+// admins may give one. It returns the override to record along with a
+// warning, or writes the rejection itself and reports false.
+func (s *Server) overrideQuotas(w http.ResponseWriter, member string, excess []quotaExcess, reason string) ([]storage.Override, []string, bool) {
+	if len(excess) == 0 {
+		return nil, nil, true
+	}
+	switch {
+	case reason == "":
+		writeJSON(w, http.StatusConflict, map[string]any{
+			"error":  "quota exceeded",
+			"quotas": excess,
+		})
+		return nil, nil, false
+	case s.quotaPolicy == quotaReject && !s.isAdmin(member):
+		http.Error(w, "only admins may override quotas", http.StatusForbidden)
+		return nil, nil, false
+	}
+	override := storage.Override{Rule: "quota", Reason: reason, Member: member, CreatedAt: time.Now()}
+	return []storage.Override{override}, []string{quotaWarning(excess)}, true
+}
+
+type quotaCounter struct {
+	Used      float64  `json:"used"`
+	Limit     *int     `json:"limit"`
+	Remaining *float64 `json:"remaining"`
+}
+
+func newQuotaCounter(used float64, limit int) quotaCounter {
+	counter := quotaCounter{Used: used}
+	if limit > 0 {
+		remaining := math.Round((float64(limit)-used)*100) / 100
+		counter.Limit = &limit
+		counter.Remaining = &remaining
+	}
+	return counter
+}
+
+type quotaResponse struct {
+	Person          string       `json:"person"`
+	Nights          quotaCounter `json:"nights"`
+	HighSeasonWeeks quotaCounter `json:"high_season_weeks"`
+}
+
+// handleQuotas reports, for the requested year (default: the current one),
+// the nights and high season weeks used and remaining per household.
+func (s *Server) handleQuotas(w http.ResponseWriter, r *http.Request) {
+	if !s.isAuthenticated(r) {
+		s.writeUnauthorized(w)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	year := time.Now().In(s.location).Year()
+	if value := r.URL.Query().Get("year"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > 9999 {
+			http.Error(w, "invalid year", http.StatusBadRequest)
+			return
+		}
+		year = parsed
+	}
+
+	usage, err := s.yearUsage(r.Context(), year, nil)
+	if err != nil {
+		http.Error(w, "failed to compute quotas", http.StatusInternalServerError)
+		return
+	}
+
+	out := make([]quotaResponse, 0, len(s.people))
+	for _, person := range s.people {
+		used := usage[person.Name]
+		quota := s.quotas[person.Name]
+		out = append(out, quotaResponse{
+			Person:          person.Name,
+			Nights:          newQuotaCounter(used.nights(), quota.Nights),
+			HighSeasonWeeks: newQuotaCounter(used.highSeasonWeeks(), quota.HighSeasonWeeks),
+		})
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"year":   year,
+		"quotas": out,
+	})
+}
@@ -0,0 +1,186 @@
+package server
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"AppartmentBooker/internal/season"
+	"AppartmentBooker/internal/storage"
+)
+
+func TestQuotaUsage(t *testing.T) {
+	tests := []struct {
+		usage quotaUsage
+		night float64
+		weeks float64
+	}{
+		{quotaUsage{}, 0, 0},
+		{quotaUsage{halfDays: 3}, 1.5, 0},
+		{quotaUsage{halfDays: 14, highSeasonHalfDays: 14}, 7, 1},
+		{quotaUsage{halfDays: 20, highSeasonHalfDays: 5}, 10, 0.36},
+	}
+	for _, tt := range tests {
+		if got := tt.usage.nights(); got != tt.night {
+			t.Errorf("%+v: nights = %v, want %v", tt.usage, got, tt.night)
+		}
+		if got := tt.usage.highSeasonWeeks(); got != tt.weeks {
+			t.Errorf("%+v: high season weeks = %v, want %v", tt.usage, got, tt.weeks)
+		}
+	}
+}
+
+func TestExceededQuotasFor(t *testing.T) {
+	store, err := storage.New(filepath.Join(t.TempDir(), "test.db"), time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	august, err := season.Parse("Aout", "08-01", "08-31")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{
+		store:      store,
+		location:   time.UTC,
+		people:     []Person{{Name: "Manon"}, {Name: "Noel"}},
+		quotas:     map[string]Quota{"Manon": {Nights: 10, HighSeasonWeeks: 1}},
+		highSeason: []season.Period{august},
+	}
+	at := func(year int, month time.Month, day, hour int) time.Time {
+		return time.Date(year, month, day, hour, 0, 0, 0, time.UTC)
+	}
+	stay := func(person string, start, end time.Time) storage.Reservation {
+		return storage.Reservation{Person: person, Start: start, End: end, Adults: 2, Status: storage.StatusConfirmed}
+	}
+
+	ctx := context.Background()
+	stored := stay("Manon", at(2027, time.June, 1, 12), at(2027, time.June, 7, 12))
+	stored.ID, err = store.CreateReservation(ctx, stored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	declined := stay("Manon", at(2027, time.July, 1, 12), at(2027, time.July, 11, 12))
+	declined.Status = storage.StatusDeclined
+	if _, err := store.CreateReservation(ctx, declined); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.CreateReservation(ctx, stay("Noel", at(2027, time.September, 1, 12), at(2027, time.September, 20, 12))); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		person   string
+		pending  []storage.Reservation
+		replaced func(storage.Reservation) bool
+		want     []quotaExcess
+	}{
+		{
+			name:    "within quota",
+			person:  "Manon",
+			pending: []storage.Reservation{stay("Manon", at(2027, time.October, 1, 12), at(2027, time.October, 5, 12))},
+		},
+		{
+			name:    "nights exceeded",
+			person:  "Manon",
+			pending: []storage.Reservation{stay("Manon", at(2027, time.October, 1, 12), at(2027, time.October, 6, 0))},
+			want:    []quotaExcess{{Person: "Manon", Year: 2027, Quota: quotaNights, Used: 10.5, Limit: 10}},
+		},
+		{
+			name:     "replaced stay is not counted",
+			person:   "Manon",
+			pending:  []storage.Reservation{stay("Manon", at(2027, time.October, 1, 12), at(2027, time.October, 10, 12))},
+			replaced: func(other storage.Reservation) bool { return other.ID == stored.ID },
+		},
+		{
+			name:    "declined pending stay is not counted",
+			person:  "Manon",
+			pending: []storage.Reservation{{Person: "Manon", Start: at(2027, time.October, 1, 12), End: at(2027, time.October, 30, 12), Status: storage.StatusDeclined}},
+		},
+		{
+			name:   "high season and nights together",
+			person: "Manon",
+			pending: []storage.Reservation{
+				stay("Manon", at(2027, time.August, 1, 12), at(2027, time.August, 4, 12)),
+				stay("Manon", at(2027, time.August, 20, 12), at(2027, time.August, 25, 12)),
+			},
+			want: []quotaExcess{
+				{Person: "Manon", Year: 2027, Quota: quotaNights, Used: 14, Limit: 10},
+				{Person: "Manon", Year: 2027, Quota: quotaHighSeason, Used: 1.14, Limit: 1},
+			},
+		},
+		{
+			name:    "each year is counted on its own",
+			person:  "Manon",
+			pending: []storage.Reservation{stay("Manon", at(2027, time.December, 29, 12), at(2028, time.January, 11, 12))},
+			want:    []quotaExcess{{Person: "Manon", Year: 2028, Quota: quotaNights, Used: 10.5, Limit: 10}},
+		},
+		{
+			name:    "household without quota",
+			person:  "Noel",
+			pending: []storage.Reservation{stay("Noel", at(2027, time.October, 1, 12), at(2027, time.December, 1, 12))},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.exceededQuotasFor(ctx, tt.person, tt.pending, tt.replaced)
+			if err != nil {
+				t.Fatalf("exceededQuotasFor: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("excess %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestExceededQuotasAcrossDST(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skipf("time zone Europe/Paris unavailable: %v", err)
+	}
+	store, err := storage.New(filepath.Join(t.TempDir(), "test.db"), paris)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	s := &Server{
+		store:    store,
+		location: paris,
+		people:   []Person{{Name: "Manon"}},
+		quotas:   map[string]Quota{"Manon": {Nights: 6}},
+	}
+	local := func(month time.Month, day, hour int) time.Time {
+		return time.Date(2027, month, day, hour, 0, 0, 0, paris)
+	}
+
+	// A week from Saturday noon to Saturday noon is seven nights, although
+	// it lasts an hour less in March and an hour more in October.
+	tests := []struct {
+		name       string
+		start, end time.Time
+	}{
+		{"spring forward", local(time.March, 27, 12), local(time.April, 3, 12)},
+		{"fall back", local(time.October, 30, 12), local(time.November, 6, 12)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pending := []storage.Reservation{{Person: "Manon", Start: tt.start, End: tt.end, Status: storage.StatusConfirmed}}
+			got, err := s.exceededQuotasFor(context.Background(), "Manon", pending, nil)
+			if err != nil {
+				t.Fatalf("exceededQuotasFor: %v", err)
+			}
+			if len(got) != 1 || got[0].Used != 7 {
+				t.Fatalf("got %+v, want 7 nights used", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+)
+
+func (s *Server) handleRooms(w http.ResponseWriter, r *http.Request) {
+	if !s.isAuthenticated(r) {
+		s.writeUnauthorized(w)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.rooms)
+}
+
+// normaliseRooms trims, deduplicates and sorts the requested room
+// identifiers. It reports false when one of them is not configured.
+func (s *Server) normaliseRooms(requested []string) ([]string, bool) {
+	seen := make(map[string]bool, len(requested))
+	out := make([]string, 0, len(requested))
+	for _, raw := range requested {
+		id := strings.TrimSpace(raw)
+		if id == "" || seen[id] {
+			continue
+		}
+		if _, ok := s.room(id); !ok {
+			return nil, false
+		}
+		seen[id] = true
+		out = append(out, id)
+	}
+	sort.Strings(out)
+	return out, true
+}
+
+func (s *Server) room(id string) (Room, bool) {
+	for _, room := range s.rooms {
+		if room.ID == id {
+			return room, true
+		}
+	}
+	return Room{}, false
+}
+
+func (s *Server) bedCount(ids []string) int {
+	total := 0
+	for _, id := range ids {
+		if room, ok := s.room(id); ok {
+			total += room.Beds
+		}
+	}
+	return total
+}
+
+// roomNames returns the display names of the rooms, comma separated.
+func (s *Server) roomNames(ids []string) string {
+	names := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if room, ok := s.room(id); ok {
+			names = append(names, room.Name)
+			continue
+		}
+		names = append(names, id)
+	}
+	return strings.Join(names, ", ")
+}
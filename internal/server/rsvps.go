@@ -0,0 +1,211 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"AppartmentBooker/internal/notify"
+	"AppartmentBooker/internal/storage"
+)
+
+// maxRSVPHeadcount caps the number of people a household may announce.
+const maxRSVPHeadcount = 50
+
+type rsvpResponse struct {
+	Attendees int            `json:"attendees"`
+	RSVPs     []storage.RSVP `json:"rsvps"`
+	Warnings  []string       `json:"warnings,omitempty"`
+}
+
+// handleRSVP lists (GET) the answers to a family event, or records (POST)
+// the answer of the caller's household: yes, no or maybe, with the number of
+// people coming. The organiser's household comes with the guests of the
+// reservation and does not answer.
+func (s *Server) handleRSVP(w http.ResponseWriter, r *http.Request, id int64) {
+	if !s.isAuthenticated(r) {
+		s.writeUnauthorized(w)
+		return
+	}
+
+	res, err := s.store.GetReservation(r.Context(), id)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, "failed to load reservation", http.StatusInternalServerError)
+		return
+	}
+	if res.Kind != storage.KindEvent {
+		http.Error(w, "reservation is not an event", http.StatusConflict)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		s.writeRSVPs(w, r, res, nil)
+	case http.MethodPost:
+		s.answerEvent(w, r, res)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) answerEvent(w http.ResponseWriter, r *http.Request, res storage.Reservation) {
+	member, _ := s.currentMember(r)
+	if member == "" {
+		http.Error(w, "a member login is required", http.StatusForbidden)
+		return
+	}
+	household := s.households[member]
+	if household == res.Person {
+		http.Error(w, "the organiser's household does not answer its own event", http.StatusConflict)
+		return
+	}
+	if !res.Active() || !res.End.After(time.Now()) {
+		http.Error(w, "event is over or cancelled", http.StatusConflict)
+		return
+	}
+
+	var payload struct {
+		Answer    string `json:"answer"`
+		Headcount *int   `json:"headcount"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	rsvp := storage.RSVP{
+		Household: household,
+		Member:    member,
+		Answer:    payload.Answer,
+		UpdatedAt: time.Now().UTC().Truncate(time.Second),
+	}
+	switch payload.Answer {
+	case storage.RSVPYes, storage.RSVPMaybe:
+		rsvp.Headcount = 1
+		if payload.Headcount != nil {
+			rsvp.Headcount = *payload.Headcount
+		}
+		if rsvp.Headcount < 0 || rsvp.Headcount > maxRSVPHeadcount || (rsvp.Answer == storage.RSVPYes && rsvp.Headcount == 0) {
+			http.Error(w, "invalid headcount", http.StatusBadRequest)
+			return
+		}
+	case storage.RSVPNo:
+	default:
+		http.Error(w, "invalid answer", http.StatusBadRequest)
+		return
+	}
+
+	rsvps, err := s.store.ListRSVPs(r.Context(), res.ID)
+	if err != nil {
+		http.Error(w, "failed to list rsvps", http.StatusInternalServerError)
+		return
+	}
+	var previous *storage.RSVP
+	for i := range rsvps {
+		if rsvps[i].Household == household {
+			previous = &rsvps[i]
+		}
+	}
+	if previous != nil && previous.Answer == rsvp.Answer && previous.Headcount == rsvp.Headcount {
+		s.writeRSVPs(w, r, res, nil)
+		return
+	}
+
+	// Only the confirmed attendees take room in the apartment.
+	before := res.Attendees
+	if previous != nil && previous.Answer == storage.RSVPYes {
+		res.Attendees -= previous.Headcount
+	}
+	if rsvp.Answer == storage.RSVPYes {
+		res.Attendees += rsvp.Headcount
+	}
+	var warnings []string
+	if res.Attendees > before {
+		over, err := s.exceededSlots(r.Context(), res)
+		if err != nil {
+			http.Error(w, "failed to check capacity", http.StatusInternalServerError)
+			return
+		}
+		if len(over) > 0 {
+			if s.capPolicy != capacityWarn {
+				writeJSON(w, http.StatusConflict, map[string]any{
+					"error":    "capacity exceeded",
+					"capacity": s.capacity,
+					"slots":    over,
+				})
+				return
+			}
+			warnings = append(warnings, capacityWarning(s.capacity, over))
+		}
+	}
+
+	if err := s.store.SetRSVP(r.Context(), res.ID, rsvp); err != nil {
+		http.Error(w, "failed to record answer", http.StatusInternalServerError)
+		return
+	}
+	s.notifyOrganiser(res, rsvp)
+	s.writeRSVPs(w, r, res, warnings)
+}
+
+func (s *Server) writeRSVPs(w http.ResponseWriter, r *http.Request, res storage.Reservation, warnings []string) {
+	rsvps, err := s.store.ListRSVPs(r.Context(), res.ID)
+	if err != nil {
+		http.Error(w, "failed to list rsvps", http.StatusInternalServerError)
+		return
+	}
+	attendees := 0
+	for _, rsvp := range rsvps {
+		if rsvp.Answer == storage.RSVPYes {
+			attendees += rsvp.Headcount
+		}
+	}
+	writeJSON(w, http.StatusOK, rsvpResponse{Attendees: attendees, RSVPs: rsvps, Warnings: warnings})
+}
+
+// inviteHouseholds tells the other households about a new event so that they
+// answer it.
+func (s *Server) inviteHouseholds(res storage.Reservation) {
+	msg := notify.Message{
+		Subject: "Invitation a un rassemblement",
+		Body:    fmt.Sprintf("%s organise un rassemblement %s.\nRepondez depuis le planning.", res.Member, s.describeStay(res.Start, res.End)),
+	}
+	for _, person := range s.people {
+		if person.Name != res.Person {
+			s.notifyHousehold(person.Name, msg)
+		}
+	}
+}
+
+// notifyOrganiser tells the member who organised the event, or their
+// household when the event was booked without a member login, about an
+// answer.
+func (s *Server) notifyOrganiser(res storage.Reservation, rsvp storage.RSVP) {
+	answers := map[string]string{
+		storage.RSVPYes:   "vient",
+		storage.RSVPNo:    "ne vient pas",
+		storage.RSVPMaybe: "viendra peut-etre",
+	}
+	who := rsvp.Household
+	if rsvp.Member != rsvp.Household {
+		who = fmt.Sprintf("%s (%s)", rsvp.Household, rsvp.Member)
+	}
+	body := fmt.Sprintf("%s %s", who, answers[rsvp.Answer])
+	if rsvp.Answer != storage.RSVPNo {
+		body += fmt.Sprintf(", %d personne(s)", rsvp.Headcount)
+	}
+	msg := notify.Message{
+		Subject: "Reponse a votre rassemblement",
+		Body:    fmt.Sprintf("%s : rassemblement %s.", body, s.describeStay(res.Start, res.End)),
+	}
+	if res.Member != "" {
+		s.notifyMember(res.Member, msg)
+		return
+	}
+	s.notifyHousehold(res.Person, msg)
+}
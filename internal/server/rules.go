@@ -0,0 +1,45 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"AppartmentBooker/internal/rules"
+	"AppartmentBooker/internal/storage"
+)
+
+// checkRules evaluates the booking rules against the stay [start, end). When
+// rules are broken, the stay is refused with the list of violations unless an
+// admin gives a reason to override them, in which case the overrides to
+// record are returned along with a warning. It writes the rejection itself
+// and reports false when the stay must not be saved.
+func (s *Server) checkRules(w http.ResponseWriter, member string, start, end time.Time, reason string) ([]storage.Override, []string, bool) {
+	violations := rules.Evaluate(s.rules, start, end, time.Now(), s.location)
+	if len(violations) == 0 {
+		return nil, nil, true
+	}
+
+	switch {
+	case reason == "":
+		writeJSON(w, http.StatusConflict, map[string]any{
+			"error":      "booking rules violated",
+			"violations": violations,
+		})
+		return nil, nil, false
+	case !s.isAdmin(member):
+		http.Error(w, "only admins may override booking rules", http.StatusForbidden)
+		return nil, nil, false
+	}
+
+	now := time.Now()
+	overrides := make([]storage.Override, 0, len(violations))
+	for _, violation := range violations {
+		rule := violation.Check
+		if violation.Rule != "" {
+			rule += " (" + violation.Rule + ")"
+		}
+		overrides = append(overrides, storage.Override{Rule: rule, Reason: reason, Member: member, CreatedAt: now})
+	}
+	return overrides, []string{fmt.Sprintf("%d booking rule(s) overridden", len(violations))}, true
+}
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"AppartmentBooker/internal/storage"
+)
+
+const (
+	// searchHitLimit caps the matching texts read from the index.
+	searchHitLimit = 200
+	// searchResultLimit caps the results returned.
+	searchResultLimit = 50
+)
+
+type searchResult struct {
+	Type        string               `json:"type"`
+	Reservation *reservationResponse `json:"reservation,omitempty"`
+	Note        *noteResponse        `json:"note,omitempty"`
+	Score       float64              `json:"score"`
+	Matches     []storage.SearchHit  `json:"matches"`
+}
+
+// handleSearch answers /api/search?q=: the reservations whose description or
+// discussion matches every word of q, names of their household and authors
+// included, and the day notes the caller may see, best first, each with the
+// matching texts as highlighted snippets.
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	member, ok := s.currentMember(r)
+	if !ok {
+		s.writeUnauthorized(w)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" || len(query) > 200 {
+		http.Error(w, "invalid q", http.StatusBadRequest)
+		return
+	}
+
+	hits, err := s.store.Search(r.Context(), query, searchHitLimit)
+	if err != nil {
+		http.Error(w, "failed to search", http.StatusInternalServerError)
+		return
+	}
+
+	out := []searchResult{}
+	byReservation := make(map[int64]int)
+	for _, hit := range hits {
+		if hit.Kind == storage.SearchNote {
+			if len(out) == searchResultLimit {
+				continue
+			}
+			note, err := s.store.GetNote(r.Context(), hit.ID)
+			if errors.Is(err, storage.ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				http.Error(w, "failed to load note", http.StatusInternalServerError)
+				return
+			}
+			if !s.noteVisible(member, note) {
+				continue
+			}
+			item := newNoteResponse(note)
+			out = append(out, searchResult{
+				Type:    "note",
+				Note:    &item,
+				Score:   hit.Score,
+				Matches: []storage.SearchHit{hit},
+			})
+			continue
+		}
+		if index, ok := byReservation[hit.ReservationID]; ok {
+			out[index].Matches = append(out[index].Matches, hit)
+			continue
+		}
+		if len(out) == searchResultLimit {
+			continue
+		}
+
+		res, err := s.store.GetReservation(r.Context(), hit.ReservationID)
+		if errors.Is(err, storage.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			http.Error(w, "failed to load reservation", http.StatusInternalServerError)
+			return
+		}
+		item := newReservationResponse(res)
+		byReservation[hit.ReservationID] = len(out)
+		out = append(out, searchResult{
+			Type:        "reservation",
+			Reservation: &item,
+			Score:       hit.Score,
+			Matches:     []storage.SearchHit{hit},
+		})
+	}
+
+	writeJSON(w, http.StatusOK, out)
+}
@@ -0,0 +1,87 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"AppartmentBooker/internal/storage"
+)
+
+func TestSearchAcrossHouseholds(t *testing.T) {
+	store, err := storage.New(filepath.Join(t.TempDir(), "test.db"), time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	s := New(store, nil, nil, Config{
+		People: []Person{
+			{Name: "Joëlle et Yves", Members: []Member{{Name: "Joëlle"}, {Name: "Yves"}}},
+			{Name: "Manon"},
+		},
+		Password:        "shared",
+		MemberPasswords: map[string]string{"Joëlle": "x", "Yves": "x", "Manon": "x"},
+		Location:        time.UTC,
+	})
+
+	ctx := context.Background()
+	day := time.Date(2027, time.March, 1, 0, 0, 0, 0, time.UTC)
+	if _, err := store.CreateReservation(ctx, storage.Reservation{
+		Person: "Manon", Member: "Manon", Start: day.Add(12 * time.Hour), End: day.Add(60 * time.Hour), Comment: "Le plombier passe", Adults: 1,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	for _, visibility := range []string{storage.NoteVisibilityAll, storage.NoteVisibilityHousehold, storage.NoteVisibilityPrivate} {
+		if _, err := store.CreateNote(ctx, storage.DayNote{
+			Start: day, End: day.Add(24 * time.Hour), Text: "Appeler le plombier (" + visibility + ")",
+			Author: "Joëlle", Person: "Joëlle et Yves", Visibility: visibility, CreatedAt: day,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		member string
+		want   []string
+	}{
+		{"Joëlle", []string{"note:all", "note:household", "note:private", "reservation:Manon"}},
+		{"Yves", []string{"note:all", "note:household", "reservation:Manon"}},
+		{"Manon", []string{"note:all", "reservation:Manon"}},
+		{"", []string{"note:all", "reservation:Manon"}},
+	}
+	for _, tt := range tests {
+		token, err := s.sessions.Create(tt.member)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := httptest.NewRequest(http.MethodGet, "/api/search?q=plombier", nil)
+		req.AddCookie(&http.Cookie{Name: s.cookieName(), Value: token})
+		rec := httptest.NewRecorder()
+		s.handleSearch(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("search as %q: status %d", tt.member, rec.Code)
+		}
+
+		var results []searchResult
+		if err := json.NewDecoder(rec.Body).Decode(&results); err != nil {
+			t.Fatal(err)
+		}
+		got := []string{}
+		for _, result := range results {
+			if result.Note != nil {
+				got = append(got, "note:"+result.Note.Visibility)
+			} else {
+				got = append(got, "reservation:"+result.Reservation.Person)
+			}
+		}
+		slices.Sort(got)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("search as %q = %v, want %v", tt.member, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,563 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"AppartmentBooker/internal/recurrence"
+	"AppartmentBooker/internal/storage"
+)
+
+type seriesExceptionResponse struct {
+	Occurrence string `json:"occurrence"`
+	Cancelled  bool   `json:"cancelled"`
+	Start      string `json:"start,omitempty"`
+	End        string `json:"end,omitempty"`
+	Comment    string `json:"comment,omitempty"`
+}
+
+type seriesResponse struct {
+	ID         int64                     `json:"id"`
+	Person     string                    `json:"person"`
+	Member     string                    `json:"member,omitempty"`
+	Start      string                    `json:"start"`
+	End        string                    `json:"end"`
+	RRule      string                    `json:"rrule"`
+	Comment    string                    `json:"comment"`
+	Adults     int                       `json:"adults"`
+	Children   int                       `json:"children"`
+	Status     string                    `json:"status"`
+	Exceptions []seriesExceptionResponse `json:"exceptions"`
+	Votes      []storage.Vote            `json:"votes,omitempty"`
+	Overrides  []storage.Override        `json:"overrides,omitempty"`
+	Warnings   []string                  `json:"warnings,omitempty"`
+}
+
+func newSeriesResponse(series storage.Series) seriesResponse {
+	out := seriesResponse{
+		ID:         series.ID,
+		Person:     series.Person,
+		Member:     series.Member,
+		Start:      series.Start.Format(time.RFC3339),
+		End:        series.End.Format(time.RFC3339),
+		RRule:      series.RRule,
+		Comment:    series.Comment,
+		Adults:     series.Adults,
+		Children:   series.Children,
+		Status:     series.Status,
+		Exceptions: make([]seriesExceptionResponse, 0, len(series.Exceptions)),
+		Overrides:  series.Overrides,
+	}
+	for _, exc := range series.Exceptions {
+		item := seriesExceptionResponse{
+			Occurrence: exc.Occurrence.Format(time.RFC3339),
+			Cancelled:  exc.Cancelled,
+			Comment:    exc.Comment,
+		}
+		if !exc.Cancelled {
+			item.Start = exc.Start.Format(time.RFC3339)
+			item.End = exc.End.Format(time.RFC3339)
+		}
+		out.Exceptions = append(out.Exceptions, item)
+	}
+	return out
+}
+
+func (s *Server) handleSeriesCollection(w http.ResponseWriter, r *http.Request) {
+	if !s.isAuthenticated(r) {
+		s.writeUnauthorized(w)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		series, err := s.store.ListSeries(r.Context())
+		if err != nil {
+			http.Error(w, "failed to list series", http.StatusInternalServerError)
+			return
+		}
+		out := make([]seriesResponse, 0, len(series))
+		for _, item := range series {
+			out = append(out, newSeriesResponse(item))
+		}
+		writeJSON(w, http.StatusOK, out)
+	case http.MethodPost:
+		s.createSeries(w, r)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleSeries serves /api/series/{id} (the whole series),
+// /api/series/{id}/approve and /decline, and
+// /api/series/{id}/occurrences/{start} (a single occurrence, identified by its
+// original RFC 3339 start).
+func (s *Server) handleSeries(w http.ResponseWriter, r *http.Request) {
+	if !s.isAuthenticated(r) {
+		s.writeUnauthorized(w)
+		return
+	}
+
+	rest := strings.TrimPrefix(r.URL.Path, "/api/series/")
+	idStr, occurrencePath, hasOccurrence := strings.Cut(rest, "/occurrences/")
+	idStr, action, _ := strings.Cut(idStr, "/")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	series, err := s.store.GetSeries(r.Context(), id)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, "failed to load series", http.StatusInternalServerError)
+		return
+	}
+
+	if !hasOccurrence {
+		switch action {
+		case "":
+		case "approve", "decline":
+			if r.Method != http.MethodPost {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			s.decideSeries(w, r, series, action == "approve")
+			return
+		default:
+			http.NotFound(w, r)
+			return
+		}
+	}
+
+	member, _ := s.currentMember(r)
+	if r.Method != http.MethodGet && member != "" && s.households[member] != series.Person && !s.isAdmin(member) {
+		http.Error(w, "member does not belong to this household", http.StatusForbidden)
+		return
+	}
+
+	if hasOccurrence {
+		occurrence, err := time.Parse(time.RFC3339, occurrencePath)
+		if err != nil || !series.HasOccurrence(s.location, occurrence.In(s.location)) {
+			http.Error(w, "invalid occurrence", http.StatusBadRequest)
+			return
+		}
+		s.handleOccurrence(w, r, series, occurrence.In(s.location))
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, newSeriesResponse(series))
+	case http.MethodPatch:
+		s.updateSeries(w, r, series)
+	case http.MethodDelete:
+		if err := s.store.DeleteSeries(r.Context(), id); err != nil {
+			http.Error(w, "failed to delete", http.StatusInternalServerError)
+			return
+		}
+		s.processWaitlist(r.Context())
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) createSeries(w http.ResponseWriter, r *http.Request) {
+	member, _ := s.currentMember(r)
+
+	var payload struct {
+		Person   string `json:"person"`
+		Start    string `json:"start"`
+		End      string `json:"end"`
+		RRule    string `json:"rrule"`
+		Comment  string `json:"comment"`
+		Adults   *int   `json:"adults"`
+		Children int    `json:"children"`
+		Override string `json:"override_reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	start, err := time.Parse(time.RFC3339, payload.Start)
+	if err != nil {
+		http.Error(w, "invalid start", http.StatusBadRequest)
+		return
+	}
+	end, err := time.Parse(time.RFC3339, payload.End)
+	if err != nil || !end.After(start) {
+		http.Error(w, "invalid end", http.StatusBadRequest)
+		return
+	}
+
+	rule, err := recurrence.Parse(payload.RRule, s.location)
+	if err != nil {
+		http.Error(w, "invalid rrule: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	adults := 1
+	if payload.Adults != nil {
+		adults = *payload.Adults
+	}
+	if adults < 0 || payload.Children < 0 || adults+payload.Children == 0 {
+		http.Error(w, "invalid guest count", http.StatusBadRequest)
+		return
+	}
+
+	if payload.Person == "" && member != "" {
+		payload.Person = s.households[member]
+	}
+	if !isKnownPerson(payload.Person, s.people) {
+		http.Error(w, "unknown person", http.StatusBadRequest)
+		return
+	}
+	if member != "" && s.households[member] != payload.Person {
+		http.Error(w, "member does not belong to this household", http.StatusForbidden)
+		return
+	}
+
+	series := storage.Series{
+		Person:   payload.Person,
+		Member:   member,
+		Start:    start.In(s.location),
+		End:      end.In(s.location),
+		RRule:    rule.String(),
+		Comment:  strings.TrimSpace(payload.Comment),
+		Adults:   adults,
+		Children: payload.Children,
+	}
+
+	occurrences, ok := s.upcomingOccurrences(w, series)
+	if !ok {
+		return
+	}
+	overrides, warnings, ok := s.checkOccurrences(w, r, member, occurrences, nil, strings.TrimSpace(payload.Override), nil)
+	if !ok {
+		return
+	}
+	series.Overrides = overrides
+	series.Status = s.seriesStatus(member, occurrences)
+
+	id, err := s.store.CreateSeries(r.Context(), series)
+	if err != nil {
+		if errors.Is(err, context.Canceled) || writeStoreConflict(w, err) {
+			return
+		}
+		http.Error(w, "failed to create", http.StatusInternalServerError)
+		return
+	}
+
+	series.ID = id
+	response := newSeriesResponse(series)
+	response.Warnings = warnings
+	writeJSON(w, http.StatusCreated, response)
+}
+
+// updateSeries edits the whole series. Omitted fields keep their value.
+// Changes to the dates, the rule or the guests go through the checks and the
+// approval of a new series again.
+func (s *Server) updateSeries(w http.ResponseWriter, r *http.Request, series storage.Series) {
+	member, _ := s.currentMember(r)
+
+	var payload struct {
+		Start    *string `json:"start"`
+		End      *string `json:"end"`
+		RRule    *string `json:"rrule"`
+		Comment  *string `json:"comment"`
+		Adults   *int    `json:"adults"`
+		Children *int    `json:"children"`
+		Override string  `json:"override_reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	stored := series
+	if payload.Start != nil {
+		start, err := time.Parse(time.RFC3339, *payload.Start)
+		if err != nil {
+			http.Error(w, "invalid start", http.StatusBadRequest)
+			return
+		}
+		series.Start = start.In(s.location)
+	}
+	if payload.End != nil {
+		end, err := time.Parse(time.RFC3339, *payload.End)
+		if err != nil {
+			http.Error(w, "invalid end", http.StatusBadRequest)
+			return
+		}
+		series.End = end.In(s.location)
+	}
+	if !series.End.After(series.Start) {
+		http.Error(w, "invalid end", http.StatusBadRequest)
+		return
+	}
+	if payload.RRule != nil {
+		rule, err := recurrence.Parse(*payload.RRule, s.location)
+		if err != nil {
+			http.Error(w, "invalid rrule: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		series.RRule = rule.String()
+	}
+	if payload.Comment != nil {
+		series.Comment = strings.TrimSpace(*payload.Comment)
+	}
+	if payload.Adults != nil {
+		series.Adults = *payload.Adults
+	}
+	if payload.Children != nil {
+		series.Children = *payload.Children
+	}
+	if series.Adults < 0 || series.Children < 0 || series.Adults+series.Children == 0 {
+		http.Error(w, "invalid guest count", http.StatusBadRequest)
+		return
+	}
+
+	var warnings []string
+	if payload.Start != nil || payload.End != nil || payload.RRule != nil || payload.Adults != nil || payload.Children != nil {
+		occurrences, ok := s.upcomingOccurrences(w, series)
+		if !ok {
+			return
+		}
+		previous, ok := s.upcomingOccurrences(w, stored)
+		if !ok {
+			return
+		}
+		now := time.Now()
+		series.Overrides, warnings, ok = s.checkOccurrences(w, r, member, occurrences, previous, strings.TrimSpace(payload.Override), func(other storage.Reservation) bool {
+			// Past occurrences stay as they were; the upcoming ones are replaced.
+			return other.SeriesID == series.ID && other.End.After(now)
+		})
+		if !ok {
+			return
+		}
+		series.Status = s.seriesStatus(member, occurrences)
+	}
+
+	if err := s.store.UpdateSeries(r.Context(), series); err != nil {
+		if errors.Is(err, context.Canceled) || writeStoreConflict(w, err) {
+			return
+		}
+		http.Error(w, "failed to update", http.StatusInternalServerError)
+		return
+	}
+	s.processWaitlist(r.Context())
+	response := newSeriesResponse(series)
+	response.Warnings = warnings
+	writeJSON(w, http.StatusOK, response)
+}
+
+// handleOccurrence edits (PATCH) or cancels (DELETE) a single occurrence.
+// Moving an occurrence goes through the checks of a new reservation; when it
+// then needs approval, the whole series waits for it again.
+func (s *Server) handleOccurrence(w http.ResponseWriter, r *http.Request, series storage.Series, occurrence time.Time) {
+	member, _ := s.currentMember(r)
+
+	current := series.Occurrence(occurrence)
+	for _, exc := range series.Exceptions {
+		if exc.Occurrence.Equal(occurrence) && !exc.Cancelled {
+			current.Start, current.End, current.Comment = exc.Start, exc.End, exc.Comment
+		}
+	}
+
+	exc := storage.SeriesException{Occurrence: occurrence}
+	var (
+		overrides []storage.Override
+		warnings  []string
+	)
+	switch r.Method {
+	case http.MethodDelete:
+		exc.Cancelled = true
+	case http.MethodPatch:
+		var payload struct {
+			Start    *string `json:"start"`
+			End      *string `json:"end"`
+			Comment  *string `json:"comment"`
+			Override string  `json:"override_reason"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, "invalid body", http.StatusBadRequest)
+			return
+		}
+
+		exc.Start, exc.End, exc.Comment = current.Start, current.End, current.Comment
+		if payload.Start != nil {
+			start, err := time.Parse(time.RFC3339, *payload.Start)
+			if err != nil {
+				http.Error(w, "invalid start", http.StatusBadRequest)
+				return
+			}
+			exc.Start = start
+		}
+		if payload.End != nil {
+			end, err := time.Parse(time.RFC3339, *payload.End)
+			if err != nil {
+				http.Error(w, "invalid end", http.StatusBadRequest)
+				return
+			}
+			exc.End = end
+		}
+		if !exc.End.After(exc.Start) {
+			http.Error(w, "invalid end", http.StatusBadRequest)
+			return
+		}
+		if payload.Comment != nil {
+			exc.Comment = strings.TrimSpace(*payload.Comment)
+		}
+
+		if !exc.Start.Equal(current.Start) || !exc.End.Equal(current.End) {
+			moved := current
+			moved.Start, moved.End = exc.Start, exc.End
+			var ok bool
+			overrides, warnings, ok = s.checkOccurrences(w, r, member, []storage.Reservation{moved}, nil, strings.TrimSpace(payload.Override), func(other storage.Reservation) bool {
+				return replaces(moved, other)
+			})
+			if !ok {
+				return
+			}
+			if series.Status == storage.StatusConfirmed && s.initialStatus(member, moved) == storage.StatusTentative {
+				series.Status = storage.StatusTentative
+			}
+		}
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := s.store.SetSeriesException(r.Context(), series.ID, exc, overrides); err != nil {
+		if errors.Is(err, context.Canceled) || writeStoreConflict(w, err) {
+			return
+		}
+		http.Error(w, "failed to update", http.StatusInternalServerError)
+		return
+	}
+	if series.Status != current.Status {
+		if err := s.store.SetSeriesStatus(r.Context(), series.ID, series.Status); err != nil {
+			http.Error(w, "failed to update", http.StatusInternalServerError)
+			return
+		}
+	}
+	s.processWaitlist(r.Context())
+
+	if exc.Cancelled {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	current.Start, current.End, current.Comment = exc.Start, exc.End, exc.Comment
+	current.Status = series.Status
+	current.Overrides = overrides
+	response := newReservationResponse(current)
+	response.Warnings = warnings
+	writeJSON(w, http.StatusOK, response)
+}
+
+// upcomingOccurrences expands the series over the occurrences not yet over,
+// up to the expansion horizon.
+func (s *Server) upcomingOccurrences(w http.ResponseWriter, series storage.Series) ([]storage.Reservation, bool) {
+	now := time.Now()
+	occurrences, err := series.Expand(s.location, now, now.Add(storage.SeriesHorizon))
+	if err != nil {
+		http.Error(w, "invalid rrule", http.StatusBadRequest)
+		return nil, false
+	}
+	return occurrences, true
+}
+
+// checkOccurrences runs the checks of createReservation on occurrences of a
+// series: blackouts, booking rules, capacity and quotas. Occurrences found
+// unchanged in previous are not judged against blackouts and rules again,
+// and stored reservations for which replaced returns true are left out of
+// the capacity and quotas. It returns the overrides to record and the
+// warnings, or writes the rejection itself and reports false.
+func (s *Server) checkOccurrences(w http.ResponseWriter, r *http.Request, member string, occurrences, previous []storage.Reservation, reason string, replaced func(storage.Reservation) bool) ([]storage.Override, []string, bool) {
+	if replaced == nil {
+		replaced = func(storage.Reservation) bool { return false }
+	}
+	unchanged := make(map[[2]int64]bool, len(previous))
+	for _, occurrence := range previous {
+		unchanged[[2]int64{occurrence.Start.Unix(), occurrence.End.Unix()}] = true
+	}
+
+	var (
+		overrides []storage.Override
+		warnings  []string
+		overruled = make(map[string]bool)
+		over      []slotOccupancy
+	)
+	for _, occurrence := range occurrences {
+		if !unchanged[[2]int64{occurrence.Start.Unix(), occurrence.End.Unix()}] {
+			blackout, err := s.blackoutFor(r.Context(), occurrence.Start, occurrence.End)
+			if err != nil {
+				http.Error(w, "failed to check blackouts", http.StatusInternalServerError)
+				return nil, nil, false
+			}
+			if blackout != nil {
+				writeBlackoutConflict(w, blackout)
+				return nil, nil, false
+			}
+
+			ruleOverrides, _, ok := s.checkRules(w, member, occurrence.Start, occurrence.End, reason)
+			if !ok {
+				return nil, nil, false
+			}
+			for _, override := range ruleOverrides {
+				if !overruled[override.Rule] {
+					overruled[override.Rule] = true
+					overrides = append(overrides, override)
+				}
+			}
+		}
+
+		slots, err := s.exceededSlotsFor(r.Context(), occurrence, replaced)
+		if err != nil {
+			http.Error(w, "failed to check capacity", http.StatusInternalServerError)
+			return nil, nil, false
+		}
+		over = append(over, slots...)
+	}
+	if len(overrides) > 0 {
+		warnings = append(warnings, fmt.Sprintf("%d booking rule(s) overridden", len(overrides)))
+	}
+
+	if len(over) > 0 {
+		if s.capPolicy != capacityWarn {
+			writeJSON(w, http.StatusConflict, map[string]any{
+				"error":    "capacity exceeded",
+				"capacity": s.capacity,
+				"slots":    over,
+			})
+			return nil, nil, false
+		}
+		warnings = append(warnings, capacityWarning(s.capacity, over))
+	}
+
+	if len(occurrences) > 0 {
+		excess, err := s.exceededQuotasFor(r.Context(), occurrences[0].Person, occurrences, replaced)
+		if err != nil {
+			http.Error(w, "failed to check quotas", http.StatusInternalServerError)
+			return nil, nil, false
+		}
+		quotaOverrides, quotaWarnings, ok := s.overrideQuotas(w, member, excess, reason)
+		if !ok {
+			return nil, nil, false
+		}
+		overrides = append(overrides, quotaOverrides...)
+		warnings = append(warnings, quotaWarnings...)
+	}
+	return overrides, warnings, true
+}
@@ -7,32 +7,142 @@ import (
 	"errors"
 	"fmt"
 	"html/template"
+	"io"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
+	"AppartmentBooker/internal/notify"
+	"AppartmentBooker/internal/report"
+	"AppartmentBooker/internal/rules"
+	"AppartmentBooker/internal/season"
 	"AppartmentBooker/internal/storage"
 )
 
-// Person identifies a resident and its associated colour.
+// Person identifies a household and its associated colour. Reservations are
+// made on behalf of a Person by one of its members.
 type Person struct {
-	Name  string `json:"name"`
-	Color string `json:"color"`
+	Name    string   `json:"name"`
+	Color   string   `json:"color"`
+	Short   string   `json:"short,omitempty"`
+	Email   string   `json:"-"`
+	Members []Member `json:"members,omitempty"`
+}
+
+// Member is an individual belonging to a household. Members log in under
+// their own name and carry their own notification preferences.
+type Member struct {
+	Name    string   `json:"name"`
+	Email   string   `json:"-"`
+	Webhook string   `json:"-"`
+	Notify  []string `json:"-"`
+}
+
+// Room is a bedroom of the property that reservations may claim.
+type Room struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Beds int    `json:"beds"`
+}
+
+// Config gathers the settings of a server instance.
+type Config struct {
+	// ID and Name identify the property when several are served by one
+	// process; ID is left empty for a single property.
+	ID              string
+	Name            string
+	People          []Person
+	PageTitle       string
+	BannerTitle     string
+	BasePath        string
+	Password        string
+	PasswordHint    string
+	MemberPasswords map[string]string
+	// Capacity is the number of guests the property sleeps; zero disables
+	// the check. CapacityPolicy is either "reject" (default) or "warn".
+	Capacity       int
+	CapacityPolicy string
+	Rooms          []Room
+	// Location is the time zone of the property, used to expand recurring
+	// series and in the calendar feed.
+	Location *time.Location
+	// Admins lists the members with administrative rights. Bookings touching
+	// one of the ApprovalPeriods stay tentative until approved according to
+	// ApprovalMode, "admin" or "majority".
+	Admins          []string
+	ApprovalMode    string
+	ApprovalPeriods []season.Period
+	// Notifier delivers notifications to members; nil disables them.
+	Notifier *notify.Notifier
+	// Quotas limit the yearly use of each household. QuotaPolicy is either
+	// "warn" (default: an override reason lets the booking through) or
+	// "reject" (only admins may override). HighSeason lists the periods
+	// counted by the high season quota.
+	Quotas      map[string]Quota
+	QuotaPolicy string
+	HighSeason  []season.Period
+	// SchoolHolidays are used by the yearly report.
+	SchoolHolidays []report.Holiday
+	// Rules are the booking rules new stays and date changes must follow.
+	Rules []rules.Rule
+	// Lottery lists the allocation rounds of peak periods, drawn according
+	// to LotteryMethod, "lottery" (default) or "rotation".
+	Lottery       []LotteryRound
+	LotteryMethod string
+	// CleaningBlocks exposes the turnover buffer kept by the store after
+	// each departure as cleaning blocks in the API and the feed.
+	CleaningBlocks bool
+	// AttachmentsDir holds the files attached to reservations, which may
+	// weigh up to AttachmentMaxBytes and be of one of AttachmentTypes.
+	AttachmentsDir     string
+	AttachmentMaxBytes int64
+	AttachmentTypes    []string
+	// Checklist is the routine every stay goes through, instantiated as
+	// tasks of each reservation.
+	Checklist []storage.TaskTemplate
+	// MeterPrices is the unit price of each meter, used to turn the
+	// consumption of stays into costs.
+	MeterPrices map[string]float64
 }
 
 // Server wires HTTP handlers against the storage backend.
 type Server struct {
-	store        *storage.Store
-	template     *template.Template
-	static       http.Handler
-	people       []Person
-	pageTitle    string
-	bannerTitle  string
-	basePath     string
-	password     string
-	passwordHint string
-	sessions     *sessionManager
+	id                    string
+	name                  string
+	store                 *storage.Store
+	template              *template.Template
+	static                http.Handler
+	people                []Person
+	households            map[string]string
+	pageTitle             string
+	bannerTitle           string
+	basePath              string
+	password              string
+	passwordHint          string
+	memberPass            map[string]string
+	capacity              int
+	capPolicy             string
+	rooms                 []Room
+	location              *time.Location
+	admins                map[string]bool
+	approval              string
+	periods               []season.Period
+	notifier              *notify.Notifier
+	quotas                map[string]Quota
+	quotaPolicy           string
+	highSeason            []season.Period
+	holidays              []report.Holiday
+	rules                 []rules.Rule
+	lottery               []LotteryRound
+	lotteryMethod         string
+	cleaningBlocksEnabled bool
+	attachmentsDir        string
+	attachmentMaxBytes    int64
+	attachmentTypes       []string
+	checklist             []storage.TaskTemplate
+	meterPrices           map[string]float64
+	sessions              *sessionManager
 }
 
 const (
@@ -41,18 +151,70 @@ const (
 )
 
 // New builds a server around the provided dependencies.
-func New(store *storage.Store, tpl *template.Template, static http.Handler, people []Person, pageTitle, bannerTitle, basePath, password, passwordHint string) *Server {
+func New(store *storage.Store, tpl *template.Template, static http.Handler, cfg Config) *Server {
+	households := make(map[string]string)
+	for _, person := range cfg.People {
+		for _, member := range person.Members {
+			households[member.Name] = person.Name
+		}
+	}
+
+	location := cfg.Location
+	if location == nil {
+		location = time.Local
+	}
+
+	admins := make(map[string]bool, len(cfg.Admins))
+	for _, name := range cfg.Admins {
+		admins[name] = true
+	}
+
+	quotas := make(map[string]Quota, len(cfg.Quotas))
+	for person, quota := range cfg.Quotas {
+		quotas[person] = quota
+	}
+
+	memberPass := make(map[string]string, len(cfg.MemberPasswords))
+	for name, password := range cfg.MemberPasswords {
+		memberPass[name] = password
+	}
+
 	return &Server{
-		store:        store,
-		template:     tpl,
-		static:       static,
-		people:       append([]Person(nil), people...),
-		pageTitle:    pageTitle,
-		bannerTitle:  bannerTitle,
-		basePath:     basePath,
-		password:     password,
-		passwordHint: passwordHint,
-		sessions:     newSessionManager(sessionLifetime),
+		id:                    cfg.ID,
+		name:                  cfg.Name,
+		store:                 store,
+		template:              tpl,
+		static:                static,
+		people:                append([]Person(nil), cfg.People...),
+		households:            households,
+		pageTitle:             cfg.PageTitle,
+		bannerTitle:           cfg.BannerTitle,
+		basePath:              cfg.BasePath,
+		password:              cfg.Password,
+		passwordHint:          cfg.PasswordHint,
+		memberPass:            memberPass,
+		capacity:              cfg.Capacity,
+		capPolicy:             cfg.CapacityPolicy,
+		rooms:                 append([]Room(nil), cfg.Rooms...),
+		location:              location,
+		admins:                admins,
+		approval:              cfg.ApprovalMode,
+		periods:               append([]season.Period(nil), cfg.ApprovalPeriods...),
+		notifier:              cfg.Notifier,
+		quotas:                quotas,
+		quotaPolicy:           cfg.QuotaPolicy,
+		highSeason:            append([]season.Period(nil), cfg.HighSeason...),
+		holidays:              append([]report.Holiday(nil), cfg.SchoolHolidays...),
+		rules:                 append([]rules.Rule(nil), cfg.Rules...),
+		lottery:               append([]LotteryRound(nil), cfg.Lottery...),
+		lotteryMethod:         cfg.LotteryMethod,
+		cleaningBlocksEnabled: cfg.CleaningBlocks && store.Turnover() > 0,
+		attachmentsDir:        cfg.AttachmentsDir,
+		attachmentMaxBytes:    cfg.AttachmentMaxBytes,
+		attachmentTypes:       append([]string(nil), cfg.AttachmentTypes...),
+		checklist:             append([]storage.TaskTemplate(nil), cfg.Checklist...),
+		meterPrices:           cfg.MeterPrices,
+		sessions:              newSessionManager(sessionLifetime),
 	}
 }
 
@@ -65,6 +227,30 @@ func (s *Server) Routes() http.Handler {
 	mux.HandleFunc("/api/reservations", s.handleReservations)
 	mux.HandleFunc("/api/reservations/", s.handleReservation)
 	mux.HandleFunc("/api/people", s.handlePeople)
+	mux.HandleFunc("/api/occupancy", s.handleOccupancy)
+	mux.HandleFunc("/api/rooms", s.handleRooms)
+	mux.HandleFunc("/api/series", s.handleSeriesCollection)
+	mux.HandleFunc("/api/series/", s.handleSeries)
+	mux.HandleFunc("/api/waitlist", s.handleWaitlistCollection)
+	mux.HandleFunc("/api/waitlist/", s.handleWaitlistEntry)
+	mux.HandleFunc("/api/quotas", s.handleQuotas)
+	mux.HandleFunc("/api/blackouts", s.handleBlackouts)
+	mux.HandleFunc("/api/blackouts/", s.handleBlackout)
+	mux.HandleFunc("/api/notes", s.handleNotes)
+	mux.HandleFunc("/api/notes/", s.handleNote)
+	mux.HandleFunc("/api/lottery", s.handleLotteryRounds)
+	mux.HandleFunc("/api/lottery/", s.handleLotteryRound)
+	mux.HandleFunc("/api/cleaning", s.handleCleaning)
+	mux.HandleFunc("/api/availability", s.handleAvailability)
+	mux.HandleFunc("/api/transfers", s.handleTransfers)
+	mux.HandleFunc("/api/transfers/", s.handleTransfer)
+	mux.HandleFunc("/api/mentions", s.handleMentions)
+	mux.HandleFunc("/api/mentions/", s.handleMentions)
+	mux.HandleFunc("/api/attachments/", s.handleAttachment)
+	mux.HandleFunc("/api/search", s.handleSearch)
+	mux.HandleFunc("/api/stats", s.handleStats)
+	mux.HandleFunc("/api/consumption", s.handleConsumption)
+	mux.HandleFunc("/stats", s.handleReportPage)
 	mux.HandleFunc("/cal.ics", s.handleCalendar)
 	if s.basePath == "" {
 		return mux
@@ -97,7 +283,8 @@ func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !s.isAuthenticated(r) {
+	member, ok := s.currentMember(r)
+	if !ok {
 		s.renderLogin(w, http.StatusOK, "")
 		return
 	}
@@ -107,17 +294,32 @@ func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "failed to encode data", http.StatusInternalServerError)
 		return
 	}
+	roomsJSON, err := json.Marshal(s.rooms)
+	if err != nil {
+		http.Error(w, "failed to encode data", http.StatusInternalServerError)
+		return
+	}
 
 	data := struct {
-		PeopleJSON  template.JS
-		PageTitle   string
-		BannerTitle string
-		BasePath    string
+		PeopleJSON   template.JS
+		RoomsJSON    template.JS
+		PageTitle    string
+		BannerTitle  string
+		BasePath     string
+		Member       string
+		Household    string
+		Admin        bool
+		ApprovalMode string
 	}{
-		PeopleJSON:  template.JS(peopleJSON),
-		PageTitle:   s.pageTitle,
-		BannerTitle: s.bannerTitle,
-		BasePath:    s.basePath,
+		PeopleJSON:   template.JS(peopleJSON),
+		RoomsJSON:    template.JS(roomsJSON),
+		PageTitle:    s.pageTitle,
+		BannerTitle:  s.bannerTitle,
+		BasePath:     s.basePath,
+		Member:       member,
+		Household:    s.households[member],
+		Admin:        s.isAdmin(member),
+		ApprovalMode: s.approval,
 	}
 
 	if err := s.template.ExecuteTemplate(w, "index.html", data); err != nil {
@@ -139,20 +341,40 @@ func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		member := strings.TrimSpace(r.PostFormValue("member"))
+		if member != "" {
+			if _, known := s.households[member]; !known {
+				s.renderLogin(w, http.StatusUnauthorized, "Identifiant inconnu.")
+				return
+			}
+		}
+
 		password := strings.TrimSpace(r.PostFormValue("password"))
-		if password != s.password {
+		expected := s.password
+		if member != "" {
+			// A member session speaks for a household, so it requires the
+			// member's own password; the shared one only opens a session
+			// without a member.
+			own, ok := s.memberPass[member]
+			if !ok {
+				s.renderLogin(w, http.StatusUnauthorized, "Ce membre n'a pas de mot de passe personnel.")
+				return
+			}
+			expected = own
+		}
+		if password != expected {
 			s.renderLogin(w, http.StatusUnauthorized, "Mot de passe incorrect.")
 			return
 		}
 
-		token, err := s.sessions.Create()
+		token, err := s.sessions.Create(member)
 		if err != nil {
 			http.Error(w, "failed to create session", http.StatusInternalServerError)
 			return
 		}
 
 		http.SetCookie(w, &http.Cookie{
-			Name:     sessionCookieName,
+			Name:     s.cookieName(),
 			Value:    token,
 			Path:     "/",
 			Expires:  time.Now().Add(sessionLifetime),
@@ -193,22 +415,83 @@ func (s *Server) handleReservation(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	idStr := strings.TrimPrefix(r.URL.Path, "/api/reservations/")
+	idStr, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/reservations/"), "/")
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
 		http.Error(w, "invalid id", http.StatusBadRequest)
 		return
 	}
 
+	if action == "comments" || strings.HasPrefix(action, "comments/") {
+		s.handleComments(w, r, id, strings.TrimPrefix(strings.TrimPrefix(action, "comments"), "/"))
+		return
+	}
+	if action == "tasks" || strings.HasPrefix(action, "tasks/") {
+		s.handleTasks(w, r, id, strings.TrimPrefix(strings.TrimPrefix(action, "tasks"), "/"))
+		return
+	}
+	if action == "meters" || strings.HasPrefix(action, "meters/") {
+		s.handleMeters(w, r, id, strings.TrimPrefix(strings.TrimPrefix(action, "meters"), "/"))
+		return
+	}
+
+	switch action {
+	case "":
+	case "approve", "decline":
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		s.decideReservation(w, r, id, action == "approve")
+		return
+	case "attachments":
+		s.handleAttachments(w, r, id)
+		return
+	case "rsvp":
+		s.handleRSVP(w, r, id)
+		return
+	case "history":
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		s.writeHistory(w, r, id)
+		return
+	case "split", "trim":
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if action == "split" {
+			s.splitReservation(w, r, id)
+		} else {
+			s.trimReservation(w, r, id)
+		}
+		return
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
 	switch r.Method {
 	case http.MethodDelete:
+		if _, _, ok := s.ownedReservation(w, r, id); !ok {
+			return
+		}
+		attachments, err := s.store.ListAttachments(r.Context(), id)
+		if err != nil {
+			http.Error(w, "failed to list attachments", http.StatusInternalServerError)
+			return
+		}
 		if err := s.store.DeleteReservation(r.Context(), id); err != nil {
 			http.Error(w, "failed to delete", http.StatusInternalServerError)
 			return
 		}
+		s.removeAttachmentFiles(attachments...)
+		s.processWaitlist(r.Context())
 		w.WriteHeader(http.StatusNoContent)
 	case http.MethodPatch:
-		s.updateReservationComment(w, r, id)
+		s.updateReservation(w, r, id)
 	default:
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 	}
@@ -249,7 +532,26 @@ func (s *Server) handleCalendar(w http.ResponseWriter, r *http.Request) {
 	builder.WriteString("CALSCALE:GREGORIAN\r\n")
 	builder.WriteString("METHOD:PUBLISH\r\n")
 
+	series, err := s.store.ListSeries(r.Context())
+	if err != nil {
+		http.Error(w, "failed to list series", http.StatusInternalServerError)
+		return
+	}
+	if len(series) > 0 && s.usesTZID() {
+		first := series[0].Start
+		for _, item := range series {
+			if item.Start.Before(first) {
+				first = item.Start
+			}
+		}
+		s.writeICSTimezone(&builder, first.In(s.location).Year())
+	}
+
 	for _, res := range reservations {
+		if res.SeriesID != 0 {
+			// Occurrences are exported natively with their series below.
+			continue
+		}
 		dtStamp := formatICSTime(now)
 		dtStart := formatICSTime(res.Start.UTC())
 		dtEnd := formatICSTime(res.End.UTC())
@@ -259,13 +561,23 @@ func (s *Server) handleCalendar(w http.ResponseWriter, r *http.Request) {
 		}
 		summary := escapeICS(person)
 		description := summary
+		if member := strings.TrimSpace(res.Member); member != "" && member != person {
+			summary = escapeICS(fmt.Sprintf("%s (%s)", person, member))
+			description = escapeICS(fmt.Sprintf("%s\nReserve par %s", person, member))
+		}
+		if res.Kind == storage.KindEvent {
+			summary = escapeICS("Rassemblement : ") + summary
+			description = fmt.Sprintf("%s\\n%s", description, escapeICS(fmt.Sprintf("Rassemblement, %d participant(s) annonce(s)", res.Occupants())))
+		}
+		location := s.roomNames(res.Rooms)
 		if trimmed := strings.TrimSpace(res.Comment); trimmed != "" {
-			description = fmt.Sprintf("%s\\n%s", summary, escapeICS(trimmed))
+			description = fmt.Sprintf("%s\\n%s", description, escapeICS(trimmed))
 		}
 
 		builder.WriteString("BEGIN:VEVENT\r\n")
 		builder.WriteString("UID:")
-		builder.WriteString(fmt.Sprintf("%d@AppartmentBooker\r\n", res.ID))
+		builder.WriteString(s.icsUID(strconv.FormatInt(res.ID, 10)))
+		builder.WriteString("\r\n")
 		builder.WriteString("DTSTAMP:")
 		builder.WriteString(dtStamp)
 		builder.WriteString("\r\n")
@@ -281,9 +593,38 @@ func (s *Server) handleCalendar(w http.ResponseWriter, r *http.Request) {
 		builder.WriteString("DESCRIPTION:")
 		builder.WriteString(description)
 		builder.WriteString("\r\n")
+		if location != "" {
+			builder.WriteString("LOCATION:")
+			builder.WriteString(escapeICS(location))
+			builder.WriteString("\r\n")
+		}
+		builder.WriteString("STATUS:")
+		builder.WriteString(icsStatus(res.Status))
+		builder.WriteString("\r\n")
 		builder.WriteString("END:VEVENT\r\n")
 	}
 
+	for _, item := range series {
+		s.writeSeriesEvents(&builder, item, now)
+	}
+
+	blackouts, err := s.store.ListBlackouts(r.Context())
+	if err != nil {
+		http.Error(w, "failed to list blackouts", http.StatusInternalServerError)
+		return
+	}
+	s.writeBlackoutEvents(&builder, blackouts, now)
+
+	notes, err := s.store.ListNotes(r.Context())
+	if err != nil {
+		http.Error(w, "failed to list notes", http.StatusInternalServerError)
+		return
+	}
+	s.writeNoteEvents(&builder, notes, now)
+	if s.cleaningBlocksEnabled {
+		s.writeCleaningEvents(&builder, reservations, now)
+	}
+
 	builder.WriteString("END:VCALENDAR\r\n")
 
 	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
@@ -298,88 +639,324 @@ func (s *Server) listReservations(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	type reservationResponse struct {
-		ID      int64  `json:"id"`
-		Person  string `json:"person"`
-		Start   string `json:"start"`
-		End     string `json:"end"`
-		Comment string `json:"comment"`
+	waitlist, err := s.store.ListWaitlist(r.Context())
+	if err != nil {
+		http.Error(w, "failed to list waitlist", http.StatusInternalServerError)
+		return
+	}
+	offers := make(map[int64]int64)
+	for _, entry := range waitlist {
+		if entry.Status == storage.WaitlistOffered && entry.ReservationID != 0 {
+			offers[entry.ReservationID] = entry.ID
+		}
+	}
+
+	attachments, err := s.store.AttachmentsByReservation(r.Context())
+	if err != nil {
+		http.Error(w, "failed to list attachments", http.StatusInternalServerError)
+		return
 	}
 
 	out := make([]reservationResponse, 0, len(reservations))
 	for _, res := range reservations {
-		out = append(out, reservationResponse{
-			ID:      res.ID,
-			Person:  res.Person,
-			Start:   res.Start.Format(time.RFC3339),
-			End:     res.End.Format(time.RFC3339),
-			Comment: res.Comment,
-		})
+		item := newReservationResponse(res)
+		if res.SeriesID == 0 {
+			item.WaitlistID = offers[res.ID]
+			if files := attachments[res.ID]; len(files) > 0 {
+				item.Attachments = newAttachmentResponses(files)
+			}
+		}
+		if res.Status == storage.StatusTentative {
+			if item.Votes, err = s.store.ListVotes(r.Context(), res.ID); err != nil {
+				http.Error(w, "failed to list votes", http.StatusInternalServerError)
+				return
+			}
+		}
+		if res.Kind == storage.KindEvent {
+			if item.RSVPs, err = s.store.ListRSVPs(r.Context(), res.ID); err != nil {
+				http.Error(w, "failed to list rsvps", http.StatusInternalServerError)
+				return
+			}
+		}
+		out = append(out, item)
 	}
 
 	writeJSON(w, http.StatusOK, out)
 }
 
+type reservationResponse struct {
+	ID       int64    `json:"id"`
+	Person   string   `json:"person"`
+	Member   string   `json:"member,omitempty"`
+	Start    string   `json:"start"`
+	End      string   `json:"end"`
+	Comment  string   `json:"comment"`
+	Adults   int      `json:"adults"`
+	Children int      `json:"children"`
+	Rooms    []string `json:"rooms"`
+	Status   string   `json:"status"`
+	Kind     string   `json:"kind"`
+	Warnings []string `json:"warnings,omitempty"`
+	// Attendees and RSVPs are the headcount coming to an event and the
+	// answers of the households.
+	Attendees int            `json:"attendees,omitempty"`
+	RSVPs     []storage.RSVP `json:"rsvps,omitempty"`
+	// Overrides record the rules a new reservation breaks on purpose.
+	Overrides []storage.Override `json:"overrides,omitempty"`
+	// Votes are the opinions cast on a tentative reservation.
+	Votes []storage.Vote `json:"votes,omitempty"`
+	// WaitlistID is set while the reservation holds a slot offered to a
+	// waitlist entry.
+	WaitlistID int64 `json:"waitlist_id,omitempty"`
+	// Attachments are the files joined to the reservation.
+	Attachments []attachmentResponse `json:"attachments,omitempty"`
+	// SeriesID and Occurrence are set on occurrences of recurring series.
+	SeriesID   int64  `json:"series_id,omitempty"`
+	Occurrence string `json:"occurrence,omitempty"`
+}
+
+func newReservationResponse(res storage.Reservation) reservationResponse {
+	out := reservationResponse{
+		ID:        res.ID,
+		Person:    res.Person,
+		Member:    res.Member,
+		Start:     res.Start.Format(time.RFC3339),
+		End:       res.End.Format(time.RFC3339),
+		Comment:   res.Comment,
+		Adults:    res.Adults,
+		Children:  res.Children,
+		Rooms:     append([]string{}, res.Rooms...),
+		Status:    res.Status,
+		Kind:      res.Kind,
+		Attendees: res.Attendees,
+		Overrides: res.Overrides,
+	}
+	if out.Kind == "" {
+		out.Kind = storage.KindStay
+	}
+	if res.SeriesID != 0 {
+		out.SeriesID = res.SeriesID
+		out.Occurrence = res.Occurrence.Format(time.RFC3339)
+	}
+	return out
+}
+
 func (s *Server) createReservation(w http.ResponseWriter, r *http.Request) {
+	member, _ := s.currentMember(r)
+	req, ok := s.decodeReservation(w, r, member)
+	if !ok {
+		return
+	}
+	res := req.Reservation
+	res.Status = s.initialStatus(member, res)
+
+	blackout, err := s.blackoutFor(r.Context(), res.Start, res.End)
+	if err != nil {
+		http.Error(w, "failed to check blackouts", http.StatusInternalServerError)
+		return
+	}
+	if blackout != nil {
+		writeBlackoutConflict(w, blackout)
+		return
+	}
+
+	overrides, warnings, ok := s.checkRules(w, member, res.Start, res.End, req.OverrideReason)
+	if !ok {
+		return
+	}
+	res.Overrides = overrides
+
+	if beds := s.bedCount(res.Rooms); len(res.Rooms) > 0 && res.Guests() > beds {
+		warnings = append(warnings, fmt.Sprintf("%d guest(s) for %d bed(s) in the selected rooms", res.Guests(), beds))
+	}
+
+	over, err := s.exceededSlots(r.Context(), res)
+	if err != nil {
+		http.Error(w, "failed to check capacity", http.StatusInternalServerError)
+		return
+	}
+	if len(over) > 0 {
+		if s.capPolicy != capacityWarn {
+			writeJSON(w, http.StatusConflict, map[string]any{
+				"error":    "capacity exceeded",
+				"capacity": s.capacity,
+				"slots":    over,
+			})
+			return
+		}
+		warnings = append(warnings, capacityWarning(s.capacity, over))
+	}
+
+	excess, err := s.exceededQuotas(r.Context(), res)
+	if err != nil {
+		http.Error(w, "failed to check quotas", http.StatusInternalServerError)
+		return
+	}
+	quotaOverrides, quotaWarnings, ok := s.overrideQuotas(w, member, excess, req.OverrideReason)
+	if !ok {
+		return
+	}
+	res.Overrides = append(res.Overrides, quotaOverrides...)
+	warnings = append(warnings, quotaWarnings...)
+
+	id, err := s.store.CreateReservation(r.Context(), res)
+	if err != nil {
+		if errors.Is(err, context.Canceled) || writeStoreConflict(w, err) {
+			return
+		}
+		http.Error(w, "failed to create", http.StatusInternalServerError)
+		return
+	}
+
+	res.ID = id
+	if res.Comment != "" {
+		s.recordMentions(r.Context(), res, 0, member, res.Comment)
+	}
+	if res.Kind == storage.KindEvent {
+		s.inviteHouseholds(res)
+	}
+	response := newReservationResponse(res)
+	response.Warnings = warnings
+	writeJSON(w, http.StatusCreated, response)
+}
+
+// writeStoreConflict answers the conflicts detected by the store: a room
+// already claimed or a turnover buffer not respected. It reports whether err
+// was such a conflict.
+func writeStoreConflict(w http.ResponseWriter, err error) bool {
+	var conflict *storage.RoomConflictError
+	if errors.As(err, &conflict) {
+		writeJSON(w, http.StatusConflict, map[string]any{
+			"error":       "room already booked",
+			"room":        conflict.Room,
+			"reservation": conflict.ReservationID,
+		})
+		return true
+	}
+	var turnover *storage.TurnoverConflictError
+	if errors.As(err, &turnover) {
+		body := map[string]any{"error": "turnover buffer"}
+		if turnover.SeriesID != 0 {
+			body["series"] = turnover.SeriesID
+		} else {
+			body["reservation"] = turnover.ReservationID
+		}
+		writeJSON(w, http.StatusConflict, body)
+		return true
+	}
+	return false
+}
+
+// reservationRequest is a decoded reservation request body. OverrideReason
+// explains why the booking should be accepted although it breaks a rule.
+type reservationRequest struct {
+	storage.Reservation
+	OverrideReason string
+}
+
+// decodeReservation reads a reservation request body, defaulting the
+// household to the member's own. It writes the error and returns false when
+// the request is invalid.
+func (s *Server) decodeReservation(w http.ResponseWriter, r *http.Request, member string) (reservationRequest, bool) {
 	var payload struct {
-		Person  string `json:"person"`
-		Start   string `json:"start"`
-		End     string `json:"end"`
-		Comment string `json:"comment"`
+		Person   string   `json:"person"`
+		Start    string   `json:"start"`
+		End      string   `json:"end"`
+		Comment  string   `json:"comment"`
+		Adults   *int     `json:"adults"`
+		Children int      `json:"children"`
+		Rooms    []string `json:"rooms"`
+		Kind     string   `json:"kind"`
+		Override string   `json:"override_reason"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
 		http.Error(w, "invalid body", http.StatusBadRequest)
-		return
+		return reservationRequest{}, false
+	}
+
+	adults := 1
+	if payload.Adults != nil {
+		adults = *payload.Adults
+	}
+	if adults < 0 || payload.Children < 0 || adults+payload.Children == 0 {
+		http.Error(w, "invalid guest count", http.StatusBadRequest)
+		return reservationRequest{}, false
 	}
 
 	start, err := time.Parse(time.RFC3339, payload.Start)
 	if err != nil {
 		http.Error(w, "invalid start", http.StatusBadRequest)
-		return
+		return reservationRequest{}, false
 	}
 	end, err := time.Parse(time.RFC3339, payload.End)
 	if err != nil {
 		http.Error(w, "invalid end", http.StatusBadRequest)
-		return
+		return reservationRequest{}, false
+	}
+
+	if payload.Person == "" && member != "" {
+		payload.Person = s.households[member]
 	}
 
 	if !isKnownPerson(payload.Person, s.people) {
 		http.Error(w, "unknown person", http.StatusBadRequest)
-		return
+		return reservationRequest{}, false
 	}
 
-	res := storage.Reservation{
-		Person:  payload.Person,
-		Start:   start,
-		End:     end,
-		Comment: strings.TrimSpace(payload.Comment),
+	if member != "" && s.households[member] != payload.Person {
+		http.Error(w, "member does not belong to this household", http.StatusForbidden)
+		return reservationRequest{}, false
 	}
 
-	id, err := s.store.CreateReservation(r.Context(), res)
-	if err != nil {
-		if errors.Is(err, context.Canceled) {
-			return
-		}
-		http.Error(w, "failed to create", http.StatusInternalServerError)
-		return
+	rooms, ok := s.normaliseRooms(payload.Rooms)
+	if !ok {
+		http.Error(w, "unknown room", http.StatusBadRequest)
+		return reservationRequest{}, false
 	}
 
-	response := struct {
-		ID      int64  `json:"id"`
-		Person  string `json:"person"`
-		Start   string `json:"start"`
-		End     string `json:"end"`
-		Comment string `json:"comment"`
-	}{
-		ID:      id,
-		Person:  res.Person,
-		Start:   res.Start.Format(time.RFC3339),
-		End:     res.End.Format(time.RFC3339),
-		Comment: res.Comment,
+	switch payload.Kind {
+	case "":
+		payload.Kind = storage.KindStay
+	case storage.KindStay:
+	case storage.KindEvent:
+		if member == "" {
+			http.Error(w, "a member login is required to organise an event", http.StatusForbidden)
+			return reservationRequest{}, false
+		}
+	default:
+		http.Error(w, "invalid kind", http.StatusBadRequest)
+		return reservationRequest{}, false
 	}
 
-	writeJSON(w, http.StatusCreated, response)
+	return reservationRequest{
+		Reservation: storage.Reservation{
+			Person:   payload.Person,
+			Member:   member,
+			Start:    start,
+			End:      end,
+			Comment:  strings.TrimSpace(payload.Comment),
+			Adults:   adults,
+			Children: payload.Children,
+			Rooms:    rooms,
+			Kind:     payload.Kind,
+		},
+		OverrideReason: strings.TrimSpace(payload.Override),
+	}, true
+}
+
+// decodeOverrideReason reads the optional {"override_reason": ...} body of
+// an answer to a proposal; an empty body gives no reason. It writes the error
+// and returns false when the body is invalid.
+func decodeOverrideReason(w http.ResponseWriter, r *http.Request) (string, bool) {
+	var payload struct {
+		Override string `json:"override_reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return "", false
+	}
+	return strings.TrimSpace(payload.Override), true
 }
 
 func isKnownPerson(person string, people []Person) bool {
@@ -397,10 +974,39 @@ func writeJSON(w http.ResponseWriter, status int, payload any) {
 	_ = json.NewEncoder(w).Encode(payload)
 }
 
-func (s *Server) updateReservationComment(w http.ResponseWriter, r *http.Request, id int64) {
-	var payload struct {
-		Comment string `json:"comment"`
+// ownedReservation loads a reservation that the member may change: one of
+// their household, or any when they are an admin. It writes the error and
+// returns false otherwise.
+func (s *Server) ownedReservation(w http.ResponseWriter, r *http.Request, id int64) (storage.Reservation, string, bool) {
+	res, err := s.store.GetReservation(r.Context(), id)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.NotFound(w, r)
+		return storage.Reservation{}, "", false
+	}
+	if err != nil {
+		http.Error(w, "failed to load reservation", http.StatusInternalServerError)
+		return storage.Reservation{}, "", false
+	}
+
+	member, _ := s.currentMember(r)
+	if member != "" && s.households[member] != res.Person && !s.isAdmin(member) {
+		http.Error(w, "member does not belong to this household", http.StatusForbidden)
+		return storage.Reservation{}, "", false
 	}
+	return res, member, true
+}
+
+// reservationPatch is the body of a reservation edit. Omitted dates keep
+// their value; a comment-only edit leaves the dates untouched.
+type reservationPatch struct {
+	Comment  *string `json:"comment"`
+	Start    *string `json:"start"`
+	End      *string `json:"end"`
+	Override string  `json:"override_reason"`
+}
+
+func (s *Server) updateReservation(w http.ResponseWriter, r *http.Request, id int64) {
+	var payload reservationPatch
 
 	if !s.isAuthenticated(r) {
 		s.writeUnauthorized(w)
@@ -412,11 +1018,24 @@ func (s *Server) updateReservationComment(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	comment := strings.TrimSpace(payload.Comment)
+	if payload.Start != nil || payload.End != nil {
+		s.updateReservationDates(w, r, id, payload)
+		return
+	}
+
+	res, member, ok := s.ownedReservation(w, r, id)
+	if !ok {
+		return
+	}
+	comment := ""
+	if payload.Comment != nil {
+		comment = strings.TrimSpace(*payload.Comment)
+	}
 	if err := s.store.UpdateReservationComment(r.Context(), id, comment); err != nil {
 		http.Error(w, "failed to update", http.StatusInternalServerError)
 		return
 	}
+	s.recordMentions(r.Context(), res, 0, member, comment)
 
 	response := struct {
 		ID      int64  `json:"id"`
@@ -428,10 +1047,207 @@ func (s *Server) updateReservationComment(w http.ResponseWriter, r *http.Request
 	writeJSON(w, http.StatusOK, response)
 }
 
+// updateReservationDates moves or resizes a reservation. The new dates go
+// through the same checks and the same approval as a new booking.
+func (s *Server) updateReservationDates(w http.ResponseWriter, r *http.Request, id int64, payload reservationPatch) {
+	res, member, ok := s.ownedReservation(w, r, id)
+	if !ok {
+		return
+	}
+
+	if payload.Start != nil {
+		start, err := time.Parse(time.RFC3339, *payload.Start)
+		if err != nil {
+			http.Error(w, "invalid start", http.StatusBadRequest)
+			return
+		}
+		res.Start = start
+	}
+	if payload.End != nil {
+		end, err := time.Parse(time.RFC3339, *payload.End)
+		if err != nil {
+			http.Error(w, "invalid end", http.StatusBadRequest)
+			return
+		}
+		res.End = end
+	}
+	if !res.End.After(res.Start) {
+		http.Error(w, "end must be after start", http.StatusBadRequest)
+		return
+	}
+	if payload.Comment != nil {
+		res.Comment = strings.TrimSpace(*payload.Comment)
+	}
+
+	blackout, err := s.blackoutFor(r.Context(), res.Start, res.End)
+	if err != nil {
+		http.Error(w, "failed to check blackouts", http.StatusInternalServerError)
+		return
+	}
+	if blackout != nil {
+		writeBlackoutConflict(w, blackout)
+		return
+	}
+
+	overrides, warnings, ok := s.checkRules(w, member, res.Start, res.End, strings.TrimSpace(payload.Override))
+	if !ok {
+		return
+	}
+
+	over, err := s.exceededSlots(r.Context(), res)
+	if err != nil {
+		http.Error(w, "failed to check capacity", http.StatusInternalServerError)
+		return
+	}
+	if len(over) > 0 {
+		if s.capPolicy != capacityWarn {
+			writeJSON(w, http.StatusConflict, map[string]any{
+				"error":    "capacity exceeded",
+				"capacity": s.capacity,
+				"slots":    over,
+			})
+			return
+		}
+		warnings = append(warnings, capacityWarning(s.capacity, over))
+	}
+
+	excess, err := s.exceededQuotas(r.Context(), res)
+	if err != nil {
+		http.Error(w, "failed to check quotas", http.StatusInternalServerError)
+		return
+	}
+	quotaOverrides, quotaWarnings, ok := s.overrideQuotas(w, member, excess, strings.TrimSpace(payload.Override))
+	if !ok {
+		return
+	}
+	overrides = append(overrides, quotaOverrides...)
+	warnings = append(warnings, quotaWarnings...)
+
+	// A declined reservation stays declined, and a pending waitlist offer
+	// is settled by its household, not by approval.
+	if res.Status != storage.StatusDeclined {
+		offered := false
+		if _, err := s.store.WaitlistOffer(r.Context(), id); err == nil {
+			offered = true
+		} else if !errors.Is(err, storage.ErrNotFound) {
+			http.Error(w, "failed to load reservation", http.StatusInternalServerError)
+			return
+		}
+		if !offered {
+			res.Status = s.initialStatus(member, res)
+		}
+	}
+
+	err = s.store.UpdateReservationDates(r.Context(), id, res.Start, res.End, res.Status, overrides)
+	if err != nil {
+		if writeStoreConflict(w, err) {
+			return
+		}
+		http.Error(w, "failed to update", http.StatusInternalServerError)
+		return
+	}
+	if payload.Comment != nil {
+		if err := s.store.UpdateReservationComment(r.Context(), id, res.Comment); err != nil {
+			http.Error(w, "failed to update", http.StatusInternalServerError)
+			return
+		}
+		s.recordMentions(r.Context(), res, 0, member, res.Comment)
+	}
+	s.processWaitlist(r.Context())
+
+	res.Overrides = overrides
+	response := newReservationResponse(res)
+	response.Warnings = warnings
+	writeJSON(w, http.StatusOK, response)
+}
+
 func formatICSTime(t time.Time) string {
 	return t.UTC().Format("20060102T150405Z")
 }
 
+// formatICSDateTime formats a property carrying a date-time in the time zone
+// of the property, e.g. "DTSTART;TZID=Europe/Paris:20270206T120000", which
+// the VTIMEZONE of the feed describes. UTC times keep the "Z" form.
+func (s *Server) formatICSDateTime(name string, t time.Time) string {
+	if !s.usesTZID() {
+		return name + ":" + formatICSTime(t)
+	}
+	return name + ";TZID=" + s.location.String() + ":" + t.In(s.location).Format("20060102T150405")
+}
+
+// icsUID qualifies a local event identifier with the property, so that the
+// feeds of a multi-property site never share UIDs.
+func (s *Server) icsUID(local string) string {
+	if s.id == "" {
+		return local + "@AppartmentBooker"
+	}
+	return local + "@" + s.id + ".AppartmentBooker"
+}
+
+// writeSeriesEvents exports a recurring series as a VEVENT carrying its RRULE
+// and EXDATEs, followed by one VEVENT per overridden occurrence.
+func (s *Server) writeSeriesEvents(builder *strings.Builder, series storage.Series, now time.Time) {
+	uid := s.icsUID(fmt.Sprintf("series-%d", series.ID))
+	person := strings.TrimSpace(series.Person)
+	summary := escapeICS(person)
+	if member := strings.TrimSpace(series.Member); member != "" && member != person {
+		summary = escapeICS(fmt.Sprintf("%s (%s)", person, member))
+	}
+
+	writeEvent := func(start, end time.Time, comment string, extra []string) {
+		description := summary
+		if trimmed := strings.TrimSpace(comment); trimmed != "" {
+			description = fmt.Sprintf("%s\\n%s", summary, escapeICS(trimmed))
+		}
+
+		lines := []string{
+			"BEGIN:VEVENT",
+			"UID:" + uid,
+			"DTSTAMP:" + formatICSTime(now),
+			s.formatICSDateTime("DTSTART", start),
+			s.formatICSDateTime("DTEND", end),
+			"STATUS:" + icsStatus(series.Status),
+		}
+		lines = append(lines, extra...)
+		lines = append(lines,
+			"SUMMARY:"+summary,
+			"DESCRIPTION:"+description,
+			"END:VEVENT",
+		)
+		for _, line := range lines {
+			builder.WriteString(line)
+			builder.WriteString("\r\n")
+		}
+	}
+
+	extra := []string{"RRULE:" + series.RRule}
+	for _, exc := range series.Exceptions {
+		if exc.Cancelled {
+			extra = append(extra, s.formatICSDateTime("EXDATE", exc.Occurrence))
+		}
+	}
+	writeEvent(series.Start, series.End, series.Comment, extra)
+
+	for _, exc := range series.Exceptions {
+		if exc.Cancelled {
+			continue
+		}
+		writeEvent(exc.Start, exc.End, exc.Comment, []string{s.formatICSDateTime("RECURRENCE-ID", exc.Occurrence)})
+	}
+}
+
+// icsStatus maps a reservation status to the VEVENT STATUS property.
+func icsStatus(status string) string {
+	switch status {
+	case storage.StatusTentative:
+		return "TENTATIVE"
+	case storage.StatusDeclined:
+		return "CANCELLED"
+	default:
+		return "CONFIRMED"
+	}
+}
+
 func escapeICS(value string) string {
 	escaped := strings.ReplaceAll(value, "\\", "\\\\")
 	escaped = strings.ReplaceAll(escaped, "\n", "\\n")
@@ -441,15 +1257,23 @@ func escapeICS(value string) string {
 }
 
 func (s *Server) isAuthenticated(r *http.Request) bool {
+	_, ok := s.currentMember(r)
+	return ok
+}
+
+// currentMember reports whether the request carries a valid session and, if
+// so, the member it was opened for. The member is empty for sessions opened
+// with the shared password.
+func (s *Server) currentMember(r *http.Request) (string, bool) {
 	if s.password == "" {
-		return true
+		return "", true
 	}
 
-	cookie, err := r.Cookie(sessionCookieName)
+	cookie, err := r.Cookie(s.cookieName())
 	if err != nil {
-		return false
+		return "", false
 	}
-	return s.sessions.Validate(cookie.Value)
+	return s.sessions.Lookup(cookie.Value)
 }
 
 func (s *Server) renderLogin(w http.ResponseWriter, status int, errorMessage string) {
@@ -458,11 +1282,13 @@ func (s *Server) renderLogin(w http.ResponseWriter, status int, errorMessage str
 		Hint      string
 		Error     string
 		PageTitle string
+		People    []Person
 	}{
 		BasePath:  s.basePath,
 		Hint:      s.passwordHint,
 		Error:     errorMessage,
 		PageTitle: s.pageTitle,
+		People:    s.loginPeople(),
 	}
 
 	var buf bytes.Buffer
@@ -476,12 +1302,41 @@ func (s *Server) renderLogin(w http.ResponseWriter, status int, errorMessage str
 	_, _ = w.Write(buf.Bytes())
 }
 
+// loginPeople returns the households offered on the login screen, keeping
+// only the members who have a password of their own.
+func (s *Server) loginPeople() []Person {
+	var people []Person
+	for _, person := range s.people {
+		var members []Member
+		for _, member := range person.Members {
+			if _, ok := s.memberPass[member.Name]; ok {
+				members = append(members, member)
+			}
+		}
+		if len(members) == 0 {
+			continue
+		}
+		person.Members = members
+		people = append(people, person)
+	}
+	return people
+}
+
 func (s *Server) writeUnauthorized(w http.ResponseWriter) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusUnauthorized)
 	_ = json.NewEncoder(w).Encode(map[string]string{"error": "unauthorized"})
 }
 
+// cookieName returns the session cookie of the property. Each property of a
+// multi-property site keeps its own cookie so that sessions do not clash.
+func (s *Server) cookieName() string {
+	if s.id == "" {
+		return sessionCookieName
+	}
+	return sessionCookieName + "_" + s.id
+}
+
 func (s *Server) rootPath() string {
 	if s.basePath == "" {
 		return "/"
@@ -9,18 +9,23 @@ import (
 
 type sessionManager struct {
 	mu       sync.Mutex
-	sessions map[string]time.Time
+	sessions map[string]session
 	lifetime time.Duration
 }
 
+type session struct {
+	expiry time.Time
+	member string
+}
+
 func newSessionManager(lifetime time.Duration) *sessionManager {
 	return &sessionManager{
-		sessions: make(map[string]time.Time),
+		sessions: make(map[string]session),
 		lifetime: lifetime,
 	}
 }
 
-func (m *sessionManager) Create() (string, error) {
+func (m *sessionManager) Create(member string) (string, error) {
 	token, err := generateToken(32)
 	if err != nil {
 		return "", err
@@ -29,15 +34,17 @@ func (m *sessionManager) Create() (string, error) {
 	expiry := time.Now().Add(m.lifetime)
 
 	m.mu.Lock()
-	m.sessions[token] = expiry
+	m.sessions[token] = session{expiry: expiry, member: member}
 	m.mu.Unlock()
 
 	return token, nil
 }
 
-func (m *sessionManager) Validate(token string) bool {
+// Lookup reports whether the token is valid and returns the member the session
+// was opened for.
+func (m *sessionManager) Lookup(token string) (string, bool) {
 	if token == "" {
-		return false
+		return "", false
 	}
 
 	now := time.Now()
@@ -45,17 +52,17 @@ func (m *sessionManager) Validate(token string) bool {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	expiry, ok := m.sessions[token]
+	current, ok := m.sessions[token]
 	if !ok {
-		return false
+		return "", false
 	}
 
-	if now.After(expiry) {
+	if now.After(current.expiry) {
 		delete(m.sessions, token)
-		return false
+		return "", false
 	}
 
-	return true
+	return current.member, true
 }
 
 func generateToken(size int) (string, error) {
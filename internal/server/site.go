@@ -0,0 +1,94 @@
+package server
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+)
+
+// Site serves several properties from a single process. Requests are routed
+// to the property whose base path prefixes the URL; the root lists the
+// properties the visitor may already open.
+type Site struct {
+	template   *template.Template
+	title      string
+	properties []*Server
+}
+
+// NewSite builds a site around already configured property servers. Every
+// property must have a distinct, non-empty base path.
+func NewSite(tpl *template.Template, title string, properties []*Server) *Site {
+	return &Site{
+		template:   tpl,
+		title:      title,
+		properties: append([]*Server(nil), properties...),
+	}
+}
+
+// Routes exposes the HTTP routes of every property plus the landing page.
+func (s *Site) Routes() http.Handler {
+	handlers := make([]http.Handler, len(s.properties))
+	for i, prop := range s.properties {
+		handlers[i] = prop.Routes()
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i, prop := range s.properties {
+			if prop.hasBasePathPrefix(r.URL.Path) {
+				handlers[i].ServeHTTP(w, r)
+				return
+			}
+		}
+
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		s.handleLanding(w, r)
+	})
+}
+
+func (s *Site) handleLanding(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	type propertyEntry struct {
+		Name   string
+		Path   string
+		Member string
+	}
+
+	// Only properties without a password or with a valid session are
+	// listed, so that the landing page does not disclose the others.
+	entries := make([]propertyEntry, 0, len(s.properties))
+	for _, prop := range s.properties {
+		member, ok := prop.currentMember(r)
+		if !ok {
+			continue
+		}
+		entries = append(entries, propertyEntry{
+			Name:   prop.name,
+			Path:   prop.rootPath(),
+			Member: member,
+		})
+	}
+
+	data := struct {
+		PageTitle  string
+		Properties []propertyEntry
+	}{
+		PageTitle:  s.title,
+		Properties: entries,
+	}
+
+	var buf bytes.Buffer
+	if err := s.template.ExecuteTemplate(&buf, "landing.html", data); err != nil {
+		http.Error(w, "template rendering failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write(buf.Bytes())
+}
@@ -0,0 +1,127 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"AppartmentBooker/internal/storage"
+)
+
+// resizableReservation loads an active reservation that the member may
+// change. It writes the error and returns false otherwise.
+func (s *Server) resizableReservation(w http.ResponseWriter, r *http.Request, id int64) (storage.Reservation, string, bool) {
+	res, member, ok := s.ownedReservation(w, r, id)
+	if !ok {
+		return storage.Reservation{}, "", false
+	}
+	if !res.Active() {
+		http.Error(w, "reservation is declined", http.StatusConflict)
+		return storage.Reservation{}, "", false
+	}
+	return res, member, true
+}
+
+// splitReservation answers POST /api/reservations/{id}/split: the reservation
+// is cut in two at a half-day boundary, both parts keeping the comment, so
+// that one of them can be cancelled or handed over on its own.
+func (s *Server) splitReservation(w http.ResponseWriter, r *http.Request, id int64) {
+	var payload struct {
+		At string `json:"at"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+	at, err := time.Parse(time.RFC3339, payload.At)
+	if err != nil {
+		http.Error(w, "invalid at", http.StatusBadRequest)
+		return
+	}
+
+	res, member, ok := s.resizableReservation(w, r, id)
+	if !ok {
+		return
+	}
+	if !at.After(res.Start) || !at.Before(res.End) || !s.onHalfDayBoundary(at) {
+		http.Error(w, "split must fall on a half-day within the reservation", http.StatusBadRequest)
+		return
+	}
+
+	newID, err := s.store.SplitReservation(r.Context(), id, at, member)
+	if err != nil {
+		http.Error(w, "failed to split", http.StatusInternalServerError)
+		return
+	}
+
+	first, err := s.store.GetReservation(r.Context(), id)
+	if err != nil {
+		http.Error(w, "failed to load reservation", http.StatusInternalServerError)
+		return
+	}
+	second, err := s.store.GetReservation(r.Context(), newID)
+	if err != nil {
+		http.Error(w, "failed to load reservation", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, []reservationResponse{newReservationResponse(first), newReservationResponse(second)})
+}
+
+// trimReservation answers POST /api/reservations/{id}/trim: the reservation
+// releases its start, its end or both, down to the new half-day boundaries,
+// and the freed slots are offered to the waitlist. Booking rules are not
+// checked, leaving early being the point.
+func (s *Server) trimReservation(w http.ResponseWriter, r *http.Request, id int64) {
+	var payload struct {
+		Start *string `json:"start"`
+		End   *string `json:"end"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	res, member, ok := s.resizableReservation(w, r, id)
+	if !ok {
+		return
+	}
+
+	start, end := res.Start, res.End
+	var err error
+	if payload.Start != nil {
+		if start, err = time.Parse(time.RFC3339, *payload.Start); err != nil {
+			http.Error(w, "invalid start", http.StatusBadRequest)
+			return
+		}
+	}
+	if payload.End != nil {
+		if end, err = time.Parse(time.RFC3339, *payload.End); err != nil {
+			http.Error(w, "invalid end", http.StatusBadRequest)
+			return
+		}
+	}
+	if !end.After(start) {
+		http.Error(w, "end must be after start", http.StatusBadRequest)
+		return
+	}
+	if start.Before(res.Start) || end.After(res.End) {
+		http.Error(w, "trim must not extend the reservation", http.StatusBadRequest)
+		return
+	}
+	if !s.onHalfDayBoundary(start) || !s.onHalfDayBoundary(end) {
+		http.Error(w, "trim must fall on a half-day", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.store.TrimReservation(r.Context(), id, start, end, member); err != nil {
+		http.Error(w, "failed to trim", http.StatusInternalServerError)
+		return
+	}
+	s.processWaitlist(r.Context())
+
+	if res, err = s.store.GetReservation(r.Context(), id); err != nil {
+		http.Error(w, "failed to load reservation", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, newReservationResponse(res))
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"AppartmentBooker/internal/storage"
+)
+
+func TestSplitAcrossDST(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skipf("time zone Europe/Paris unavailable: %v", err)
+	}
+	local := func(month time.Month, day, hour int) time.Time {
+		return time.Date(2027, month, day, hour, 0, 0, 0, paris)
+	}
+
+	tests := []struct {
+		name       string
+		start, end time.Time
+		at         time.Time
+		want       int
+	}{
+		// Summer time starts on 28 March 2027, autumn time on 31 October.
+		{"noon after spring forward", local(time.March, 27, 0), local(time.April, 2, 0), local(time.March, 30, 12), http.StatusOK},
+		{"midnight after spring forward", local(time.March, 27, 12), local(time.April, 2, 0), local(time.March, 29, 0), http.StatusOK},
+		{"off boundary after spring forward", local(time.March, 27, 0), local(time.April, 2, 0), local(time.March, 30, 13), http.StatusBadRequest},
+		{"noon after fall back", local(time.October, 30, 0), local(time.November, 5, 0), local(time.November, 1, 12), http.StatusOK},
+		{"off boundary after fall back", local(time.October, 30, 0), local(time.November, 5, 0), local(time.November, 1, 11), http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := storage.New(filepath.Join(t.TempDir(), "test.db"), paris)
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { store.Close() })
+			s := New(store, nil, nil, Config{People: []Person{{Name: "Manon"}}, Location: paris})
+
+			id, err := store.CreateReservation(context.Background(), storage.Reservation{Person: "Manon", Start: tt.start, End: tt.end})
+			if err != nil {
+				t.Fatal(err)
+			}
+			body := `{"at": "` + tt.at.Format(time.RFC3339) + `"}`
+			rec := httptest.NewRecorder()
+			s.splitReservation(rec, httptest.NewRequest(http.MethodPost, "/api/reservations/1/split", strings.NewReader(body)), id)
+			if rec.Code != tt.want {
+				t.Fatalf("split at %s: status %d (%s), want %d", tt.at, rec.Code, strings.TrimSpace(rec.Body.String()), tt.want)
+			}
+			if tt.want != http.StatusOK {
+				return
+			}
+			first, err := store.GetReservation(context.Background(), id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !first.End.Equal(tt.at) {
+				t.Errorf("first part ends at %s, want %s", first.End, tt.at)
+			}
+		})
+	}
+}
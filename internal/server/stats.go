@@ -0,0 +1,131 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"AppartmentBooker/internal/report"
+)
+
+var monthNames = [12]string{
+	"Janvier", "Fevrier", "Mars", "Avril", "Mai", "Juin",
+	"Juillet", "Aout", "Septembre", "Octobre", "Novembre", "Decembre",
+}
+
+// statsYear returns the year requested by the "year" query parameter, the
+// current one by default.
+func (s *Server) statsYear(r *http.Request) (int, bool) {
+	value := r.URL.Query().Get("year")
+	if value == "" {
+		return time.Now().In(s.location).Year(), true
+	}
+	year, err := strconv.Atoi(value)
+	if err != nil || year < 1 || year > 9999 {
+		return 0, false
+	}
+	return year, true
+}
+
+func (s *Server) buildReport(r *http.Request, year int) (report.Year, error) {
+	people := make([]string, 0, len(s.people))
+	for _, person := range s.people {
+		people = append(people, person.Name)
+	}
+	return report.Build(r.Context(), s.store, year, report.Options{
+		People:   people,
+		Location: s.location,
+		Holidays: s.holidays,
+	})
+}
+
+// handleStats serves the yearly report as JSON, or as CSV with format=csv.
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	if !s.isAuthenticated(r) {
+		s.writeUnauthorized(w)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	year, ok := s.statsYear(r)
+	if !ok {
+		http.Error(w, "invalid year", http.StatusBadRequest)
+		return
+	}
+
+	stats, err := s.buildReport(r, year)
+	if err != nil {
+		http.Error(w, "failed to compute statistics", http.StatusInternalServerError)
+		return
+	}
+
+	if r.URL.Query().Get("format") == "csv" {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=statistiques-%d.csv", year))
+		_ = stats.WriteCSV(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// handleReportPage renders the yearly report as an HTML page.
+func (s *Server) handleReportPage(w http.ResponseWriter, r *http.Request) {
+	if !s.isAuthenticated(r) {
+		http.Redirect(w, r, s.rootPath(), http.StatusSeeOther)
+		return
+	}
+
+	year, ok := s.statsYear(r)
+	if !ok {
+		http.Error(w, "invalid year", http.StatusBadRequest)
+		return
+	}
+
+	stats, err := s.buildReport(r, year)
+	if err != nil {
+		http.Error(w, "failed to compute statistics", http.StatusInternalServerError)
+		return
+	}
+
+	type monthRow struct {
+		Name    string
+		Percent float64
+	}
+	months := make([]monthRow, 0, len(stats.Months))
+	for _, month := range stats.Months {
+		months = append(months, monthRow{Name: monthNames[month.Month-1], Percent: month.Rate * 100})
+	}
+
+	data := struct {
+		PageTitle string
+		BasePath  string
+		Report    report.Year
+		Months    []monthRow
+		Occupancy float64
+		Weekend   float64
+		Weekday   float64
+		Holiday   float64
+		Previous  int
+		Next      int
+	}{
+		PageTitle: s.pageTitle,
+		BasePath:  s.basePath,
+		Report:    stats,
+		Months:    months,
+		Occupancy: stats.Occupancy * 100,
+		Weekend:   stats.WeekendRate * 100,
+		Weekday:   stats.WeekdayRate * 100,
+		Holiday:   stats.HolidayRate * 100,
+		Previous:  year - 1,
+		Next:      year + 1,
+	}
+
+	if err := s.template.ExecuteTemplate(w, "stats.html", data); err != nil {
+		http.Error(w, "template rendering failed", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,189 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"AppartmentBooker/internal/storage"
+)
+
+// maxTaskTitleLength caps the title of a custom task, in bytes.
+const maxTaskTitleLength = 200
+
+// handoverResponse lists the departure tasks the previous occupant left
+// open.
+type handoverResponse struct {
+	ReservationID int64          `json:"reservation_id"`
+	Person        string         `json:"person"`
+	End           string         `json:"end"`
+	Open          []storage.Task `json:"open"`
+}
+
+type tasksResponse struct {
+	Tasks    []storage.Task    `json:"tasks"`
+	Previous *handoverResponse `json:"previous,omitempty"`
+}
+
+// handleTasks serves /api/reservations/{id}/tasks (GET lists the checklist
+// of the stay along with the departure tasks the previous occupant left
+// open, POST adds a custom task) and /api/reservations/{id}/tasks/{task}
+// (PATCH ticks a task off or opens it again, DELETE removes a custom task).
+// Only the occupants and admins change tasks.
+func (s *Server) handleTasks(w http.ResponseWriter, r *http.Request, reservationID int64, sub string) {
+	res, err := s.store.GetReservation(r.Context(), reservationID)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, "failed to load reservation", http.StatusInternalServerError)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		member, _ := s.currentMember(r)
+		if member != "" && s.households[member] != res.Person && !s.isAdmin(member) {
+			http.Error(w, "member does not belong to this household", http.StatusForbidden)
+			return
+		}
+	}
+
+	if sub == "" {
+		switch r.Method {
+		case http.MethodGet:
+			s.listTasks(w, r, res)
+		case http.MethodPost:
+			s.createTask(w, r, res)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+		return
+	}
+
+	id, err := strconv.ParseInt(sub, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid task id", http.StatusBadRequest)
+		return
+	}
+	task, err := s.store.GetTask(r.Context(), id)
+	if errors.Is(err, storage.ErrNotFound) || (err == nil && task.ReservationID != reservationID) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, "failed to load task", http.StatusInternalServerError)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPatch:
+		var payload struct {
+			Done bool `json:"done"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, "invalid body", http.StatusBadRequest)
+			return
+		}
+		member, _ := s.currentMember(r)
+		if err := s.store.SetTaskDone(r.Context(), id, payload.Done, member); err != nil {
+			http.Error(w, "failed to update", http.StatusInternalServerError)
+			return
+		}
+		if task, err = s.store.GetTask(r.Context(), id); err != nil {
+			http.Error(w, "failed to load task", http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, task)
+	case http.MethodDelete:
+		if !task.Custom {
+			http.Error(w, "checklist tasks cannot be removed", http.StatusConflict)
+			return
+		}
+		if err := s.store.DeleteTask(r.Context(), id); err != nil {
+			http.Error(w, "failed to delete", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) listTasks(w http.ResponseWriter, r *http.Request, res storage.Reservation) {
+	tasks, err := s.store.EnsureTasks(r.Context(), res.ID, s.checklist)
+	if err != nil {
+		http.Error(w, "failed to list tasks", http.StatusInternalServerError)
+		return
+	}
+	out := tasksResponse{Tasks: tasks}
+
+	previous, err := s.store.PreviousReservation(r.Context(), res.Start, res.ID)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, "failed to load previous stay", http.StatusInternalServerError)
+		return
+	}
+	if err == nil {
+		previousTasks, err := s.store.EnsureTasks(r.Context(), previous.ID, s.checklist)
+		if err != nil {
+			http.Error(w, "failed to list tasks", http.StatusInternalServerError)
+			return
+		}
+		var open []storage.Task
+		for _, task := range previousTasks {
+			if !task.Done && task.Phase == storage.PhaseDeparture {
+				open = append(open, task)
+			}
+		}
+		if len(open) > 0 {
+			out.Previous = &handoverResponse{
+				ReservationID: previous.ID,
+				Person:        previous.Person,
+				End:           previous.End.Format(time.RFC3339),
+				Open:          open,
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) createTask(w http.ResponseWriter, r *http.Request, res storage.Reservation) {
+	var payload struct {
+		Title string `json:"title"`
+		Phase string `json:"phase"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	task := storage.Task{
+		ReservationID: res.ID,
+		Phase:         payload.Phase,
+		Title:         strings.TrimSpace(payload.Title),
+		Custom:        true,
+		CreatedAt:     time.Now().UTC().Truncate(time.Second),
+	}
+	if task.Phase == "" {
+		task.Phase = storage.PhaseDeparture
+	}
+	if task.Phase != storage.PhaseArrival && task.Phase != storage.PhaseDeparture {
+		http.Error(w, "invalid phase", http.StatusBadRequest)
+		return
+	}
+	if task.Title == "" || len(task.Title) > maxTaskTitleLength {
+		http.Error(w, "invalid title", http.StatusBadRequest)
+		return
+	}
+
+	id, err := s.store.CreateTask(r.Context(), task)
+	if err != nil {
+		http.Error(w, "failed to create", http.StatusInternalServerError)
+		return
+	}
+	task.ID = id
+	writeJSON(w, http.StatusCreated, task)
+}
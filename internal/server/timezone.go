@@ -0,0 +1,115 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// usesTZID reports whether ICS date-times are written in the time zone of
+// the property, with a TZID, rather than in UTC.
+func (s *Server) usesTZID() bool {
+	return s.location != time.UTC && s.location.String() != "Local"
+}
+
+// zoneTransition is a change of UTC offset: at is its instant, from and to
+// the times just before and after.
+type zoneTransition struct {
+	at       time.Time
+	from, to time.Time
+}
+
+// zoneTransitions returns the changes of UTC offset of loc during the year.
+func zoneTransitions(loc *time.Location, year int) []zoneTransition {
+	var out []zoneTransition
+	end := time.Date(year+1, time.January, 1, 0, 0, 0, 0, time.UTC)
+	previous := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC).In(loc)
+	for t := previous.Add(time.Hour); t.Before(end); t = t.Add(time.Hour) {
+		current := t.In(loc)
+		_, before := previous.Zone()
+		if _, after := current.Zone(); after != before {
+			// Changes happen on the minute: find the first one with the
+			// new offset.
+			at := previous
+			for ; at.Before(current); at = at.Add(time.Minute) {
+				if _, offset := at.Zone(); offset != before {
+					break
+				}
+			}
+			out = append(out, zoneTransition{at: at, from: at.Add(-time.Minute), to: at})
+		}
+		previous = current
+	}
+	return out
+}
+
+// writeICSTimezone writes the VTIMEZONE component that the TZID of the
+// property's date-times refer to, as RFC 5545 requires. Offset changes are
+// described from those of year, repeating yearly on the same weekday of the
+// month.
+func (s *Server) writeICSTimezone(builder *strings.Builder, year int) {
+	lines := []string{"BEGIN:VTIMEZONE", "TZID:" + s.location.String()}
+
+	transitions := zoneTransitions(s.location, year)
+	if len(transitions) == 0 {
+		name, offset := time.Date(year, time.January, 1, 0, 0, 0, 0, s.location).Zone()
+		lines = append(lines,
+			"BEGIN:STANDARD",
+			"DTSTART:19700101T000000",
+			"TZOFFSETFROM:"+formatICSOffset(offset),
+			"TZOFFSETTO:"+formatICSOffset(offset),
+			"TZNAME:"+name,
+			"END:STANDARD",
+		)
+	}
+	for _, transition := range transitions {
+		kind := "STANDARD"
+		if transition.to.IsDST() {
+			kind = "DAYLIGHT"
+		}
+		_, from := transition.from.Zone()
+		name, to := transition.to.Zone()
+		// DTSTART is the wall-clock time of the change under the former
+		// offset.
+		local := transition.at.In(time.FixedZone("", from))
+		lines = append(lines,
+			"BEGIN:"+kind,
+			"DTSTART:"+local.Format("20060102T150405"),
+			"RRULE:FREQ=YEARLY;BYMONTH="+fmt.Sprint(int(local.Month()))+";BYDAY="+monthWeekday(local),
+			"TZOFFSETFROM:"+formatICSOffset(from),
+			"TZOFFSETTO:"+formatICSOffset(to),
+			"TZNAME:"+name,
+			"END:"+kind,
+		)
+	}
+	lines = append(lines, "END:VTIMEZONE")
+
+	for _, line := range lines {
+		builder.WriteString(line)
+		builder.WriteString("\r\n")
+	}
+}
+
+// monthWeekday describes the day of t as a BYDAY entry: "2SU" for the
+// second Sunday of the month, "-1SU" for the last one.
+func monthWeekday(t time.Time) string {
+	day := strings.ToUpper(t.Weekday().String()[:2])
+	lastDay := time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	if t.Day()+7 > lastDay {
+		return "-1" + day
+	}
+	return fmt.Sprint((t.Day()-1)/7+1) + day
+}
+
+// formatICSOffset formats a UTC offset in seconds as "+0100".
+func formatICSOffset(offset int) string {
+	sign := "+"
+	if offset < 0 {
+		sign, offset = "-", -offset
+	}
+	out := fmt.Sprintf("%s%02d%02d", sign, offset/3600, offset/60%60)
+	if offset%60 != 0 {
+		out += fmt.Sprintf("%02d", offset%60)
+	}
+	return out
+}
@@ -0,0 +1,44 @@
+package server
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteICSTimezone(t *testing.T) {
+	tests := []struct {
+		zone string
+		want []string
+	}{
+		{"Europe/Paris", []string{
+			"BEGIN:VTIMEZONE\r\nTZID:Europe/Paris\r\n",
+			"BEGIN:DAYLIGHT\r\nDTSTART:20270328T020000\r\nRRULE:FREQ=YEARLY;BYMONTH=3;BYDAY=-1SU\r\nTZOFFSETFROM:+0100\r\nTZOFFSETTO:+0200\r\nTZNAME:CEST\r\nEND:DAYLIGHT\r\n",
+			"BEGIN:STANDARD\r\nDTSTART:20271031T030000\r\nRRULE:FREQ=YEARLY;BYMONTH=10;BYDAY=-1SU\r\nTZOFFSETFROM:+0200\r\nTZOFFSETTO:+0100\r\nTZNAME:CET\r\nEND:STANDARD\r\n",
+			"END:VTIMEZONE\r\n",
+		}},
+		{"America/New_York", []string{
+			"DTSTART:20270314T020000\r\nRRULE:FREQ=YEARLY;BYMONTH=3;BYDAY=2SU\r\nTZOFFSETFROM:-0500\r\nTZOFFSETTO:-0400\r\n",
+			"DTSTART:20271107T020000\r\nRRULE:FREQ=YEARLY;BYMONTH=11;BYDAY=1SU\r\nTZOFFSETFROM:-0400\r\nTZOFFSETTO:-0500\r\n",
+		}},
+		{"Asia/Tokyo", []string{
+			"BEGIN:STANDARD\r\nDTSTART:19700101T000000\r\nTZOFFSETFROM:+0900\r\nTZOFFSETTO:+0900\r\nTZNAME:JST\r\nEND:STANDARD\r\n",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.zone, func(t *testing.T) {
+			loc, err := time.LoadLocation(tt.zone)
+			if err != nil {
+				t.Skipf("time zone %s unavailable: %v", tt.zone, err)
+			}
+			s := &Server{location: loc}
+			var builder strings.Builder
+			s.writeICSTimezone(&builder, 2027)
+			for _, want := range tt.want {
+				if !strings.Contains(builder.String(), want) {
+					t.Errorf("VTIMEZONE lacks %q:\n%s", want, builder.String())
+				}
+			}
+		})
+	}
+}
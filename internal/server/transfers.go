@@ -0,0 +1,339 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"AppartmentBooker/internal/notify"
+	"AppartmentBooker/internal/storage"
+)
+
+type transferResponse struct {
+	ID          int64  `json:"id"`
+	FromPerson  string `json:"from_person"`
+	ToPerson    string `json:"to_person"`
+	ProposedBy  string `json:"proposed_by,omitempty"`
+	OfferedID   int64  `json:"offered_id"`
+	RequestedID int64  `json:"requested_id,omitempty"`
+	Message     string `json:"message"`
+	Status      string `json:"status"`
+	CreatedAt   string `json:"created_at"`
+	DecidedBy   string `json:"decided_by,omitempty"`
+	DecidedAt   string `json:"decided_at,omitempty"`
+}
+
+func newTransferResponse(t storage.Transfer) transferResponse {
+	out := transferResponse{
+		ID:          t.ID,
+		FromPerson:  t.FromPerson,
+		ToPerson:    t.ToPerson,
+		ProposedBy:  t.ProposedBy,
+		OfferedID:   t.OfferedID,
+		RequestedID: t.RequestedID,
+		Message:     t.Message,
+		Status:      t.Status,
+		CreatedAt:   t.CreatedAt.Format(time.RFC3339),
+		DecidedBy:   t.DecidedBy,
+	}
+	if !t.DecidedAt.IsZero() {
+		out.DecidedAt = t.DecidedAt.Format(time.RFC3339)
+	}
+	return out
+}
+
+// handleTransfers lists (GET) or proposes (POST) transfers.
+func (s *Server) handleTransfers(w http.ResponseWriter, r *http.Request) {
+	if !s.isAuthenticated(r) {
+		s.writeUnauthorized(w)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		transfers, err := s.store.ListTransfers(r.Context())
+		if err != nil {
+			http.Error(w, "failed to list transfers", http.StatusInternalServerError)
+			return
+		}
+		out := make([]transferResponse, 0, len(transfers))
+		for _, t := range transfers {
+			out = append(out, newTransferResponse(t))
+		}
+		writeJSON(w, http.StatusOK, out)
+	case http.MethodPost:
+		s.proposeTransfer(w, r)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// proposeTransfer offers a reservation of the member's household to another
+// household, as a gift or in exchange for one of theirs.
+func (s *Server) proposeTransfer(w http.ResponseWriter, r *http.Request) {
+	member, _ := s.currentMember(r)
+	if member == "" {
+		http.Error(w, "a member login is required", http.StatusForbidden)
+		return
+	}
+
+	var payload struct {
+		ReservationID int64  `json:"reservation_id"`
+		To            string `json:"to"`
+		RequestedID   int64  `json:"requested_id"`
+		Message       string `json:"message"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	offered, ok := s.transferableReservation(w, r, payload.ReservationID)
+	if !ok {
+		return
+	}
+	if s.households[member] != offered.Person && !s.isAdmin(member) {
+		http.Error(w, "member does not belong to this household", http.StatusForbidden)
+		return
+	}
+	if !isKnownPerson(payload.To, s.people) || payload.To == offered.Person {
+		http.Error(w, "invalid recipient", http.StatusBadRequest)
+		return
+	}
+	if payload.RequestedID != 0 {
+		requested, ok := s.transferableReservation(w, r, payload.RequestedID)
+		if !ok {
+			return
+		}
+		if requested.Person != payload.To {
+			http.Error(w, "requested reservation does not belong to the recipient", http.StatusBadRequest)
+			return
+		}
+	}
+
+	t := storage.Transfer{
+		FromPerson:  offered.Person,
+		ToPerson:    payload.To,
+		ProposedBy:  member,
+		OfferedID:   offered.ID,
+		RequestedID: payload.RequestedID,
+		Message:     strings.TrimSpace(payload.Message),
+		Status:      storage.TransferPending,
+		CreatedAt:   time.Now(),
+	}
+	id, err := s.store.CreateTransfer(r.Context(), t)
+	if err != nil {
+		http.Error(w, "failed to create", http.StatusInternalServerError)
+		return
+	}
+	t.ID = id
+
+	body := fmt.Sprintf("%s vous propose de reprendre son sejour (%s).", offered.Person, s.describeStay(offered.Start, offered.End))
+	if t.RequestedID != 0 {
+		requested, err := s.store.GetReservation(r.Context(), t.RequestedID)
+		if err == nil {
+			body = fmt.Sprintf("%s vous propose d'echanger son sejour (%s) contre le votre (%s).", offered.Person, s.describeStay(offered.Start, offered.End), s.describeStay(requested.Start, requested.End))
+		}
+	}
+	if t.Message != "" {
+		body += "\n" + t.Message
+	}
+	s.notifyHousehold(t.ToPerson, notify.Message{
+		Subject: "Proposition d'echange de sejour",
+		Body:    body + "\nAcceptez ou refusez depuis le planning.",
+	})
+
+	writeJSON(w, http.StatusCreated, newTransferResponse(t))
+}
+
+// transferableReservation loads a reservation that may change hands: an
+// active, stand-alone reservation. It writes the error and returns false
+// otherwise.
+func (s *Server) transferableReservation(w http.ResponseWriter, r *http.Request, id int64) (storage.Reservation, bool) {
+	res, err := s.store.GetReservation(r.Context(), id)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, "unknown reservation", http.StatusBadRequest)
+		return storage.Reservation{}, false
+	}
+	if err != nil {
+		http.Error(w, "failed to load reservation", http.StatusInternalServerError)
+		return storage.Reservation{}, false
+	}
+	if !res.Active() {
+		http.Error(w, "reservation is declined", http.StatusConflict)
+		return storage.Reservation{}, false
+	}
+	return res, true
+}
+
+// handleTransfer serves /api/transfers/{id} (DELETE, withdrawal by the
+// proposing household) and /api/transfers/{id}/accept|decline (POST, answer
+// of the receiving household).
+func (s *Server) handleTransfer(w http.ResponseWriter, r *http.Request) {
+	if !s.isAuthenticated(r) {
+		s.writeUnauthorized(w)
+		return
+	}
+
+	idStr, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/transfers/"), "/")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	t, err := s.store.GetTransfer(r.Context(), id)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, "failed to load transfer", http.StatusInternalServerError)
+		return
+	}
+
+	member, _ := s.currentMember(r)
+	if member == "" {
+		http.Error(w, "a member login is required", http.StatusForbidden)
+		return
+	}
+
+	var status, household string
+	switch action {
+	case "":
+		if r.Method != http.MethodDelete {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		status, household = storage.TransferCancelled, t.FromPerson
+	case "accept", "decline":
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		status, household = storage.TransferDeclined, t.ToPerson
+		if action == "accept" {
+			status = storage.TransferAccepted
+		}
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if s.households[member] != household && !s.isAdmin(member) {
+		http.Error(w, "member does not belong to this household", http.StatusForbidden)
+		return
+	}
+
+	var overrides map[int64][]storage.Override
+	if status == storage.TransferAccepted {
+		reason, ok := decodeOverrideReason(w, r)
+		if !ok {
+			return
+		}
+		if overrides, ok = s.checkTransferQuotas(w, r, t, member, reason); !ok {
+			return
+		}
+	}
+
+	err = s.store.SettleTransfer(r.Context(), id, status, member, overrides)
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		http.Error(w, "transfer is not pending", http.StatusConflict)
+		return
+	case errors.Is(err, storage.ErrTransferStale):
+		http.Error(w, "reservations changed since the proposal", http.StatusConflict)
+		return
+	case err != nil:
+		if writeStoreConflict(w, err) {
+			return
+		}
+		http.Error(w, "failed to update", http.StatusInternalServerError)
+		return
+	}
+
+	if t, err = s.store.GetTransfer(r.Context(), id); err != nil {
+		http.Error(w, "failed to load transfer", http.StatusInternalServerError)
+		return
+	}
+
+	switch status {
+	case storage.TransferAccepted:
+		s.notifyHousehold(t.FromPerson, notify.Message{
+			Subject: "Echange de sejour accepte",
+			Body:    fmt.Sprintf("%s a accepte votre proposition. Le planning est a jour.", t.ToPerson),
+		})
+	case storage.TransferDeclined:
+		s.notifyHousehold(t.FromPerson, notify.Message{
+			Subject: "Echange de sejour refuse",
+			Body:    fmt.Sprintf("%s a refuse votre proposition.", t.ToPerson),
+		})
+	}
+
+	if status == storage.TransferCancelled {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeJSON(w, http.StatusOK, newTransferResponse(t))
+}
+
+// checkTransferQuotas applies the quotas of the households receiving a
+// reservation through the transfer, the reservation an exchange takes away
+// from them no longer counting. It returns the overrides to record per
+// reservation, or writes the rejection itself and reports false.
+func (s *Server) checkTransferQuotas(w http.ResponseWriter, r *http.Request, t storage.Transfer, member, reason string) (map[int64][]storage.Override, bool) {
+	type ownership struct {
+		id       int64
+		from, to string
+	}
+	moves := []ownership{{t.OfferedID, t.FromPerson, t.ToPerson}}
+	if t.RequestedID != 0 {
+		moves = append(moves, ownership{t.RequestedID, t.ToPerson, t.FromPerson})
+	}
+	exchanged := func(other storage.Reservation) bool {
+		return other.ID != 0 && (other.ID == t.OfferedID || other.ID == t.RequestedID)
+	}
+
+	var excess []quotaExcess
+	over := make(map[int64]bool, len(moves))
+	for _, move := range moves {
+		res, err := s.store.GetReservation(r.Context(), move.id)
+		if err != nil || res.Person != move.from || !res.Active() {
+			// Settling the transfer reports it as stale.
+			continue
+		}
+		res.Person = move.to
+		broken, err := s.exceededQuotasFor(r.Context(), move.to, []storage.Reservation{res}, exchanged)
+		if err != nil {
+			http.Error(w, "failed to check quotas", http.StatusInternalServerError)
+			return nil, false
+		}
+		if len(broken) > 0 {
+			over[move.id] = true
+			excess = append(excess, broken...)
+		}
+	}
+
+	quotaOverrides, _, ok := s.overrideQuotas(w, member, excess, reason)
+	if !ok || len(quotaOverrides) == 0 {
+		return nil, ok
+	}
+	overrides := make(map[int64][]storage.Override, len(over))
+	for id := range over {
+		overrides[id] = quotaOverrides
+	}
+	return overrides, true
+}
+
+// writeHistory answers /api/reservations/{id}/history.
+func (s *Server) writeHistory(w http.ResponseWriter, r *http.Request, id int64) {
+	history, err := s.store.ListHistory(r.Context(), id)
+	if err != nil {
+		http.Error(w, "failed to load history", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, history)
+}
@@ -0,0 +1,36 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDecodeOverrideReason(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		length int64
+		want   string
+		ok     bool
+	}{
+		{"no body", "", 0, "", true},
+		{"empty body of unknown length", "", -1, "", true},
+		{"reason", `{"override_reason": "  accord familial "}`, -1, "accord familial", true},
+		{"empty object", `{}`, 2, "", true},
+		{"invalid JSON", `{"override_reason":`, -1, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/api/transfers/1/accept", io.NopCloser(strings.NewReader(tt.body)))
+			r.ContentLength = tt.length
+			rec := httptest.NewRecorder()
+			got, ok := decodeOverrideReason(rec, r)
+			if ok != tt.ok || got != tt.want {
+				t.Fatalf("decodeOverrideReason = %q, %v (status %d), want %q, %v", got, ok, rec.Code, tt.want, tt.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,354 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"AppartmentBooker/internal/notify"
+	"AppartmentBooker/internal/storage"
+)
+
+type waitlistResponse struct {
+	ID            int64    `json:"id"`
+	Person        string   `json:"person"`
+	Member        string   `json:"member,omitempty"`
+	Start         string   `json:"start"`
+	End           string   `json:"end"`
+	Comment       string   `json:"comment"`
+	Adults        int      `json:"adults"`
+	Children      int      `json:"children"`
+	Rooms         []string `json:"rooms"`
+	Status        string   `json:"status"`
+	ReservationID int64    `json:"reservation_id,omitempty"`
+	CreatedAt     string   `json:"created_at"`
+	Warnings      []string `json:"warnings,omitempty"`
+}
+
+func newWaitlistResponse(entry storage.WaitlistEntry) waitlistResponse {
+	return waitlistResponse{
+		ID:            entry.ID,
+		Person:        entry.Person,
+		Member:        entry.Member,
+		Start:         entry.Start.Format(time.RFC3339),
+		End:           entry.End.Format(time.RFC3339),
+		Comment:       entry.Comment,
+		Adults:        entry.Adults,
+		Children:      entry.Children,
+		Rooms:         append([]string{}, entry.Rooms...),
+		Status:        entry.Status,
+		ReservationID: entry.ReservationID,
+		CreatedAt:     entry.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+func (s *Server) handleWaitlistCollection(w http.ResponseWriter, r *http.Request) {
+	if !s.isAuthenticated(r) {
+		s.writeUnauthorized(w)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		entries, err := s.store.ListWaitlist(r.Context())
+		if err != nil {
+			http.Error(w, "failed to list waitlist", http.StatusInternalServerError)
+			return
+		}
+		out := make([]waitlistResponse, 0, len(entries))
+		for _, entry := range entries {
+			out = append(out, newWaitlistResponse(entry))
+		}
+		writeJSON(w, http.StatusOK, out)
+	case http.MethodPost:
+		s.joinWaitlist(w, r)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// joinWaitlist records a request for dates that cannot be booked right now.
+// Dates that are free must be booked directly. Booking rules and quotas apply
+// as for a booking, the override reason being kept for when the offer is
+// accepted.
+func (s *Server) joinWaitlist(w http.ResponseWriter, r *http.Request) {
+	member, _ := s.currentMember(r)
+	req, ok := s.decodeReservation(w, r, member)
+	if !ok {
+		return
+	}
+	res := req.Reservation
+	if !res.End.After(res.Start) {
+		http.Error(w, "end must be after start", http.StatusBadRequest)
+		return
+	}
+
+	blocked, err := s.isBlocked(r.Context(), res)
+	if err != nil {
+		http.Error(w, "failed to check availability", http.StatusInternalServerError)
+		return
+	}
+	if !blocked {
+		http.Error(w, "dates are available", http.StatusConflict)
+		return
+	}
+
+	ruleOverrides, warnings, ok := s.checkRules(w, member, res.Start, res.End, req.OverrideReason)
+	if !ok {
+		return
+	}
+	excess, err := s.exceededQuotas(r.Context(), res)
+	if err != nil {
+		http.Error(w, "failed to check quotas", http.StatusInternalServerError)
+		return
+	}
+	_, quotaWarnings, ok := s.overrideQuotas(w, member, excess, req.OverrideReason)
+	if !ok {
+		return
+	}
+	warnings = append(warnings, quotaWarnings...)
+
+	entry := storage.WaitlistEntry{
+		Person:    res.Person,
+		Member:    res.Member,
+		Start:     res.Start,
+		End:       res.End,
+		Comment:   res.Comment,
+		Adults:    res.Adults,
+		Children:  res.Children,
+		Rooms:     res.Rooms,
+		CreatedAt: time.Now(),
+	}
+	if len(ruleOverrides) > 0 || len(excess) > 0 {
+		entry.OverrideReason = req.OverrideReason
+	}
+	id, err := s.store.CreateWaitlistEntry(r.Context(), entry)
+	if err != nil {
+		http.Error(w, "failed to create", http.StatusInternalServerError)
+		return
+	}
+
+	entry.ID = id
+	entry.Status = storage.WaitlistWaiting
+	response := newWaitlistResponse(entry)
+	response.Warnings = warnings
+	writeJSON(w, http.StatusCreated, response)
+}
+
+// handleWaitlistEntry serves /api/waitlist/{id} (DELETE) and
+// /api/waitlist/{id}/accept|decline (POST), the answer to an offer.
+func (s *Server) handleWaitlistEntry(w http.ResponseWriter, r *http.Request) {
+	if !s.isAuthenticated(r) {
+		s.writeUnauthorized(w)
+		return
+	}
+
+	idStr, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/waitlist/"), "/")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	entry, err := s.store.GetWaitlistEntry(r.Context(), id)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, "failed to load waitlist entry", http.StatusInternalServerError)
+		return
+	}
+
+	member, _ := s.currentMember(r)
+	if member != "" && s.households[member] != entry.Person && !s.isAdmin(member) {
+		http.Error(w, "member does not belong to this household", http.StatusForbidden)
+		return
+	}
+
+	switch action {
+	case "":
+		if r.Method != http.MethodDelete {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if entry.Status == storage.WaitlistOffered {
+			if err := s.store.SettleWaitlistOffer(r.Context(), id, false, "", nil); err != nil {
+				http.Error(w, "failed to delete", http.StatusInternalServerError)
+				return
+			}
+		}
+		if err := s.store.DeleteWaitlistEntry(r.Context(), id); err != nil {
+			http.Error(w, "failed to delete", http.StatusInternalServerError)
+			return
+		}
+		if entry.Status == storage.WaitlistOffered {
+			s.processWaitlist(r.Context())
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case "accept", "decline":
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		s.answerOffer(w, r, entry, action == "accept")
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// answerOffer settles an offer. An accepted offer becomes a regular booking,
+// still subject to approval when it falls in an approval period, to the
+// booking rules and to the quotas of the household, which may have changed
+// since it joined; a declined one frees the slot for the next entry.
+func (s *Server) answerOffer(w http.ResponseWriter, r *http.Request, entry storage.WaitlistEntry, accept bool) {
+	if entry.Status != storage.WaitlistOffered {
+		http.Error(w, "no pending offer", http.StatusConflict)
+		return
+	}
+
+	member, _ := s.currentMember(r)
+	if member == "" {
+		member = entry.Member
+	}
+	res := entry.Reservation()
+	res.ID = entry.ReservationID
+	status := s.initialStatus(member, res)
+
+	var (
+		overrides []storage.Override
+		warnings  []string
+	)
+	if accept {
+		reason, ok := decodeOverrideReason(w, r)
+		if !ok {
+			return
+		}
+		// Without a new reason, the one given on joining stands, on behalf
+		// of the member who gave it.
+		decider := member
+		if reason == "" {
+			reason, decider = entry.OverrideReason, entry.Member
+		}
+
+		if overrides, warnings, ok = s.checkRules(w, decider, res.Start, res.End, reason); !ok {
+			return
+		}
+		excess, err := s.exceededQuotas(r.Context(), res)
+		if err != nil {
+			http.Error(w, "failed to check quotas", http.StatusInternalServerError)
+			return
+		}
+		quotaOverrides, quotaWarnings, ok := s.overrideQuotas(w, decider, excess, reason)
+		if !ok {
+			return
+		}
+		overrides = append(overrides, quotaOverrides...)
+		warnings = append(warnings, quotaWarnings...)
+	}
+
+	err := s.store.SettleWaitlistOffer(r.Context(), entry.ID, accept, status, overrides)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, "offer no longer available", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "failed to update", http.StatusInternalServerError)
+		return
+	}
+
+	if !accept {
+		s.processWaitlist(r.Context())
+	}
+
+	entry, err = s.store.GetWaitlistEntry(r.Context(), entry.ID)
+	if err != nil {
+		http.Error(w, "failed to load waitlist entry", http.StatusInternalServerError)
+		return
+	}
+	response := newWaitlistResponse(entry)
+	response.Warnings = warnings
+	writeJSON(w, http.StatusOK, response)
+}
+
+// isBlocked reports whether the reservation cannot be made as is: the
+// period is blacked out, an active reservation claims one of its rooms (a
+// stay without rooms takes the whole apartment) or leaves no turnover buffer,
+// or the capacity would be exceeded.
+func (s *Server) isBlocked(ctx context.Context, res storage.Reservation) (bool, error) {
+	blackout, err := s.blackoutFor(ctx, res.Start, res.End)
+	if err != nil || blackout != nil {
+		return blackout != nil, err
+	}
+
+	buffer := s.store.Turnover()
+	existing, err := s.store.ListReservationsBetween(ctx, res.Start.Add(-buffer), res.End.Add(buffer))
+	if err != nil {
+		return false, err
+	}
+	for _, other := range existing {
+		if storage.InTurnover(res, other, buffer) {
+			return true, nil
+		}
+		if other.Active() && storage.Overlaps(other.Start, other.End, res.Start, res.End) && storage.RoomsIntersect(other.Rooms, res.Rooms) {
+			return true, nil
+		}
+	}
+
+	over, err := s.exceededSlots(ctx, res)
+	if err != nil {
+		return false, err
+	}
+	return len(over) > 0, nil
+}
+
+// processWaitlist offers the freed slots to the waiting entries, oldest
+// first. Each offer holds the slot with a tentative reservation and the
+// household is notified. It runs after every change that may free dates.
+func (s *Server) processWaitlist(ctx context.Context) {
+	ctx = context.WithoutCancel(ctx)
+
+	entries, err := s.store.ListWaitlist(ctx)
+	if err != nil {
+		log.Printf("warning: unable to list waitlist: %v", err)
+		return
+	}
+
+	now := time.Now()
+	for _, entry := range entries {
+		if entry.Status != storage.WaitlistWaiting || !entry.End.After(now) {
+			continue
+		}
+
+		blocked, err := s.isBlocked(ctx, entry.Reservation())
+		if err != nil {
+			log.Printf("warning: unable to check waitlist entry %d: %v", entry.ID, err)
+			return
+		}
+		if blocked {
+			continue
+		}
+
+		if _, err := s.store.OfferWaitlistEntry(ctx, entry.ID); err != nil {
+			var conflict *storage.RoomConflictError
+			var turnover *storage.TurnoverConflictError
+			if !errors.As(err, &conflict) && !errors.As(err, &turnover) {
+				log.Printf("warning: unable to offer waitlist entry %d: %v", entry.ID, err)
+			}
+			continue
+		}
+
+		s.notifyHousehold(entry.Person, notify.Message{
+			Subject: "Des dates se sont liberees",
+			Body: fmt.Sprintf(
+				"Les dates demandees (%s) sont de nouveau disponibles.\nUne reservation provisoire vous est reservee : confirmez-la ou renoncez-y depuis le planning.",
+				s.describeStay(entry.Start, entry.End),
+			),
+		})
+	}
+}
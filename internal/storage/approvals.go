@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Vote is the opinion of a household on a tentative reservation.
+type Vote struct {
+	Household string    `json:"household"`
+	Member    string    `json:"member,omitempty"`
+	Approve   bool      `json:"approve"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SetReservationStatus changes the status of the reservation.
+func (s *Store) SetReservationStatus(ctx context.Context, id int64, status string) error {
+	res, err := s.db.ExecContext(ctx, `UPDATE reservations SET status = ? WHERE id = ?`, status, id)
+	if err != nil {
+		return err
+	}
+	return expectAffected(res)
+}
+
+// RecordVote stores the vote of a household on the reservation, replacing
+// its previous vote.
+func (s *Store) RecordVote(ctx context.Context, id int64, vote Vote) error {
+	_, err := s.db.ExecContext(
+		ctx,
+		`INSERT INTO reservation_votes (reservation_id, household, member, approve, created_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(reservation_id, household) DO UPDATE SET member = excluded.member, approve = excluded.approve, created_at = excluded.created_at`,
+		id,
+		vote.Household,
+		vote.Member,
+		vote.Approve,
+		vote.CreatedAt.UTC().Format(time.RFC3339),
+	)
+	return err
+}
+
+// ListVotes returns the votes cast on the reservation.
+func (s *Store) ListVotes(ctx context.Context, id int64) ([]Vote, error) {
+	return s.queryVotes(ctx, `SELECT household, member, approve, created_at FROM reservation_votes WHERE reservation_id = ? ORDER BY created_at`, id)
+}
+
+// SetSeriesStatus changes the status of the series, and so of all its
+// occurrences.
+func (s *Store) SetSeriesStatus(ctx context.Context, id int64, status string) error {
+	res, err := s.db.ExecContext(ctx, `UPDATE reservation_series SET status = ? WHERE id = ?`, status, id)
+	if err != nil {
+		return err
+	}
+	return expectAffected(res)
+}
+
+// RecordSeriesVote stores the vote of a household on the series, replacing
+// its previous vote.
+func (s *Store) RecordSeriesVote(ctx context.Context, id int64, vote Vote) error {
+	_, err := s.db.ExecContext(
+		ctx,
+		`INSERT INTO series_votes (series_id, household, member, approve, created_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(series_id, household) DO UPDATE SET member = excluded.member, approve = excluded.approve, created_at = excluded.created_at`,
+		id,
+		vote.Household,
+		vote.Member,
+		vote.Approve,
+		vote.CreatedAt.UTC().Format(time.RFC3339),
+	)
+	return err
+}
+
+// ListSeriesVotes returns the votes cast on the series.
+func (s *Store) ListSeriesVotes(ctx context.Context, id int64) ([]Vote, error) {
+	return s.queryVotes(ctx, `SELECT household, member, approve, created_at FROM series_votes WHERE series_id = ? ORDER BY created_at`, id)
+}
+
+func (s *Store) queryVotes(ctx context.Context, query string, id int64) ([]Vote, error) {
+	rows, err := s.db.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Vote
+	for rows.Next() {
+		var (
+			vote      Vote
+			member    sql.NullString
+			createdAt string
+		)
+		if err := rows.Scan(&vote.Household, &member, &vote.Approve, &createdAt); err != nil {
+			return nil, err
+		}
+		vote.Member = member.String
+		if vote.CreatedAt, err = time.Parse(time.RFC3339, createdAt); err != nil {
+			return nil, err
+		}
+		out = append(out, vote)
+	}
+	return out, rows.Err()
+}
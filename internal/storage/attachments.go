@@ -0,0 +1,121 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Attachment is a file joined to a reservation, such as a photo of the meters
+// or of a broken item. The file and its thumbnail live on disk under the
+// attachments directory of the property; only their names are stored.
+type Attachment struct {
+	ID            int64     `json:"id"`
+	ReservationID int64     `json:"reservation_id"`
+	Name          string    `json:"name"`
+	ContentType   string    `json:"content_type"`
+	Size          int64     `json:"size"`
+	File          string    `json:"-"`
+	Thumbnail     string    `json:"-"`
+	UploadedBy    string    `json:"uploaded_by,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+const attachmentColumns = `id, reservation_id, name, content_type, size, file, thumbnail, uploaded_by, created_at`
+
+// ListAttachments returns the attachments of the reservation, oldest first.
+func (s *Store) ListAttachments(ctx context.Context, reservationID int64) ([]Attachment, error) {
+	return scanAttachments(s.db.QueryContext(ctx, `SELECT `+attachmentColumns+` FROM attachments WHERE reservation_id = ? ORDER BY created_at, id`, reservationID))
+}
+
+// AttachmentsByReservation returns every attachment, grouped by reservation.
+func (s *Store) AttachmentsByReservation(ctx context.Context) (map[int64][]Attachment, error) {
+	attachments, err := scanAttachments(s.db.QueryContext(ctx, `SELECT `+attachmentColumns+` FROM attachments ORDER BY created_at, id`))
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[int64][]Attachment)
+	for _, a := range attachments {
+		out[a.ReservationID] = append(out[a.ReservationID], a)
+	}
+	return out, nil
+}
+
+// GetAttachment returns the attachment matching the provided ID, or
+// ErrNotFound.
+func (s *Store) GetAttachment(ctx context.Context, id int64) (Attachment, error) {
+	attachments, err := scanAttachments(s.db.QueryContext(ctx, `SELECT `+attachmentColumns+` FROM attachments WHERE id = ?`, id))
+	if err != nil {
+		return Attachment{}, err
+	}
+	if len(attachments) == 0 {
+		return Attachment{}, ErrNotFound
+	}
+	return attachments[0], nil
+}
+
+// CreateAttachment records an attachment whose files are already written and
+// returns its identifier.
+func (s *Store) CreateAttachment(ctx context.Context, a Attachment) (int64, error) {
+	if a.File == "" {
+		return 0, errors.New("file is required")
+	}
+	if a.CreatedAt.IsZero() {
+		a.CreatedAt = time.Now()
+	}
+
+	res, err := s.db.ExecContext(
+		ctx,
+		`INSERT INTO attachments (reservation_id, name, content_type, size, file, thumbnail, uploaded_by, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		a.ReservationID,
+		a.Name,
+		a.ContentType,
+		a.Size,
+		a.File,
+		a.Thumbnail,
+		a.UploadedBy,
+		a.CreatedAt.UTC().Format(time.RFC3339),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// DeleteAttachment removes the record of the attachment; its files are left
+// to the caller.
+func (s *Store) DeleteAttachment(ctx context.Context, id int64) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM attachments WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	return expectAffected(res)
+}
+
+func scanAttachments(rows *sql.Rows, err error) ([]Attachment, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Attachment{}
+	for rows.Next() {
+		var (
+			a          Attachment
+			thumbnail  sql.NullString
+			uploadedBy sql.NullString
+			createdAt  string
+		)
+		if err := rows.Scan(&a.ID, &a.ReservationID, &a.Name, &a.ContentType, &a.Size, &a.File, &thumbnail, &uploadedBy, &createdAt); err != nil {
+			return nil, err
+		}
+		if a.CreatedAt, err = time.Parse(time.RFC3339, createdAt); err != nil {
+			return nil, err
+		}
+		a.Thumbnail = thumbnail.String
+		a.UploadedBy = uploadedBy.String
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,103 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Blackout is a period during which nobody may stay, such as works in the
+// building.
+type Blackout struct {
+	ID        int64     `json:"id"`
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Reason    string    `json:"reason"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+const blackoutColumns = `id, start, end, reason, created_by, created_at`
+
+// ListBlackouts returns every blackout ordered by start date.
+func (s *Store) ListBlackouts(ctx context.Context) ([]Blackout, error) {
+	return s.queryBlackouts(ctx, `SELECT `+blackoutColumns+` FROM blackouts ORDER BY start`)
+}
+
+// ListBlackoutsBetween returns the blackouts overlapping [from, to).
+func (s *Store) ListBlackoutsBetween(ctx context.Context, from, to time.Time) ([]Blackout, error) {
+	return s.queryBlackouts(
+		ctx,
+		`SELECT `+blackoutColumns+` FROM blackouts WHERE start < ? AND end > ? ORDER BY start`,
+		to.UTC().Format(time.RFC3339),
+		from.UTC().Format(time.RFC3339),
+	)
+}
+
+// CreateBlackout persists a blackout and returns its identifier.
+func (s *Store) CreateBlackout(ctx context.Context, b Blackout) (int64, error) {
+	if !b.End.After(b.Start) {
+		return 0, errors.New("end must be after start")
+	}
+	if b.CreatedAt.IsZero() {
+		b.CreatedAt = time.Now()
+	}
+
+	res, err := s.db.ExecContext(
+		ctx,
+		`INSERT INTO blackouts (start, end, reason, created_by, created_at) VALUES (?, ?, ?, ?, ?)`,
+		b.Start.UTC().Format(time.RFC3339),
+		b.End.UTC().Format(time.RFC3339),
+		b.Reason,
+		b.CreatedBy,
+		b.CreatedAt.UTC().Format(time.RFC3339),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// DeleteBlackout removes the blackout matching the provided ID.
+func (s *Store) DeleteBlackout(ctx context.Context, id int64) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM blackouts WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	return expectAffected(res)
+}
+
+func (s *Store) queryBlackouts(ctx context.Context, query string, args ...any) ([]Blackout, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Blackout
+	for rows.Next() {
+		var (
+			b         Blackout
+			start     string
+			end       string
+			createdBy sql.NullString
+			createdAt string
+		)
+		if err := rows.Scan(&b.ID, &start, &end, &b.Reason, &createdBy, &createdAt); err != nil {
+			return nil, err
+		}
+		if b.Start, err = time.Parse(time.RFC3339, start); err != nil {
+			return nil, err
+		}
+		if b.End, err = time.Parse(time.RFC3339, end); err != nil {
+			return nil, err
+		}
+		if b.CreatedAt, err = time.Parse(time.RFC3339, createdAt); err != nil {
+			return nil, err
+		}
+		b.CreatedBy = createdBy.String
+		out = append(out, b)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,125 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Comment is a message in the discussion thread of a reservation, next to
+// its pinned description (Reservation.Comment).
+type Comment struct {
+	ID            int64     `json:"id"`
+	ReservationID int64     `json:"reservation_id"`
+	Author        string    `json:"author"`
+	Person        string    `json:"person"`
+	Body          string    `json:"body"`
+	CreatedAt     time.Time `json:"created_at"`
+	EditedAt      time.Time `json:"edited_at,omitzero"`
+}
+
+const commentColumns = `id, reservation_id, author, person, body, created_at, edited_at`
+
+// ListComments returns the thread of the reservation, oldest first.
+func (s *Store) ListComments(ctx context.Context, reservationID int64) ([]Comment, error) {
+	return scanComments(s.db.QueryContext(ctx, `SELECT `+commentColumns+` FROM reservation_comments WHERE reservation_id = ? ORDER BY created_at, id`, reservationID))
+}
+
+// GetComment returns the comment matching the provided ID, or ErrNotFound.
+func (s *Store) GetComment(ctx context.Context, id int64) (Comment, error) {
+	comments, err := scanComments(s.db.QueryContext(ctx, `SELECT `+commentColumns+` FROM reservation_comments WHERE id = ?`, id))
+	if err != nil {
+		return Comment{}, err
+	}
+	if len(comments) == 0 {
+		return Comment{}, ErrNotFound
+	}
+	return comments[0], nil
+}
+
+// CreateComment adds a comment to the thread of its reservation and returns
+// its identifier.
+func (s *Store) CreateComment(ctx context.Context, c Comment) (int64, error) {
+	if c.Author == "" {
+		return 0, errors.New("author is required")
+	}
+	if c.Body == "" {
+		return 0, errors.New("body is required")
+	}
+	if c.CreatedAt.IsZero() {
+		c.CreatedAt = time.Now()
+	}
+
+	res, err := s.db.ExecContext(
+		ctx,
+		`INSERT INTO reservation_comments (reservation_id, author, person, body, created_at) VALUES (?, ?, ?, ?, ?)`,
+		c.ReservationID,
+		c.Author,
+		c.Person,
+		c.Body,
+		c.CreatedAt.UTC().Format(time.RFC3339),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// UpdateComment replaces the body of the comment and marks it as edited.
+func (s *Store) UpdateComment(ctx context.Context, id int64, body string) error {
+	if body == "" {
+		return errors.New("body is required")
+	}
+	res, err := s.db.ExecContext(
+		ctx,
+		`UPDATE reservation_comments SET body = ?, edited_at = ? WHERE id = ?`,
+		body,
+		time.Now().UTC().Format(time.RFC3339),
+		id,
+	)
+	if err != nil {
+		return err
+	}
+	return expectAffected(res)
+}
+
+// DeleteComment removes the comment matching the provided ID.
+func (s *Store) DeleteComment(ctx context.Context, id int64) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM reservation_comments WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	return expectAffected(res)
+}
+
+func scanComments(rows *sql.Rows, err error) ([]Comment, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Comment{}
+	for rows.Next() {
+		var (
+			c         Comment
+			person    sql.NullString
+			createdAt string
+			editedAt  sql.NullString
+		)
+		if err := rows.Scan(&c.ID, &c.ReservationID, &c.Author, &person, &c.Body, &createdAt, &editedAt); err != nil {
+			return nil, err
+		}
+		if c.CreatedAt, err = time.Parse(time.RFC3339, createdAt); err != nil {
+			return nil, err
+		}
+		if editedAt.Valid {
+			if c.EditedAt, err = time.Parse(time.RFC3339, editedAt.String); err != nil {
+				return nil, err
+			}
+		}
+		c.Person = person.String
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
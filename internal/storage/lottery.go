@@ -0,0 +1,224 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+// ErrAlreadyDrawn is returned when saving the draw of a round drawn before.
+var ErrAlreadyDrawn = errors.New("round already drawn")
+
+// LotteryWish is a period a household asks for in an allocation round; rank 1
+// is its first choice.
+type LotteryWish struct {
+	Round     string    `json:"round"`
+	Person    string    `json:"person"`
+	Member    string    `json:"member,omitempty"`
+	Rank      int       `json:"rank"`
+	Period    string    `json:"period"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// LotteryDraw is the outcome of an allocation round, kept along with the
+// seed and priority order it was computed from.
+type LotteryDraw struct {
+	Round    string          `json:"round"`
+	Method   string          `json:"method"`
+	Seed     int64           `json:"seed"`
+	Priority []string        `json:"priority"`
+	DrawnBy  string          `json:"drawn_by,omitempty"`
+	DrawnAt  time.Time       `json:"drawn_at"`
+	Results  []LotteryResult `json:"results"`
+}
+
+// LotteryResult is a period given to a household by a draw.
+type LotteryResult struct {
+	Period        string `json:"period"`
+	Person        string `json:"person"`
+	Rank          int    `json:"rank"`
+	ReservationID int64  `json:"reservation_id,omitempty"`
+}
+
+// ListLotteryWishes returns the wishes of the round by household and rank.
+func (s *Store) ListLotteryWishes(ctx context.Context, round string) ([]LotteryWish, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT round, person, member, rank, period, created_at FROM lottery_wishes WHERE round = ? ORDER BY person, rank`, round)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []LotteryWish
+	for rows.Next() {
+		var (
+			wish      LotteryWish
+			member    sql.NullString
+			createdAt string
+		)
+		if err := rows.Scan(&wish.Round, &wish.Person, &member, &wish.Rank, &wish.Period, &createdAt); err != nil {
+			return nil, err
+		}
+		wish.Member = member.String
+		if wish.CreatedAt, err = time.Parse(time.RFC3339, createdAt); err != nil {
+			return nil, err
+		}
+		out = append(out, wish)
+	}
+	return out, rows.Err()
+}
+
+// SetLotteryWishes replaces the wishes of the household for the round with
+// periods, given by order of preference.
+func (s *Store) SetLotteryWishes(ctx context.Context, round, person, member string, periods []string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM lottery_wishes WHERE round = ? AND person = ?`, round, person); err != nil {
+		return err
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	for i, period := range periods {
+		if _, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO lottery_wishes (round, person, member, rank, period, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+			round, person, member, i+1, period, now,
+		); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetLotteryDraw returns the draw of the round, or ErrNotFound when it has
+// not been drawn yet.
+func (s *Store) GetLotteryDraw(ctx context.Context, round string) (LotteryDraw, error) {
+	var (
+		draw     LotteryDraw
+		priority string
+		drawnBy  sql.NullString
+		drawnAt  string
+	)
+	err := s.db.QueryRowContext(ctx, `SELECT round, method, seed, priority, drawn_by, drawn_at FROM lottery_draws WHERE round = ?`, round).
+		Scan(&draw.Round, &draw.Method, &draw.Seed, &priority, &drawnBy, &drawnAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return LotteryDraw{}, ErrNotFound
+	}
+	if err != nil {
+		return LotteryDraw{}, err
+	}
+	draw.DrawnBy = drawnBy.String
+	draw.Priority = []string{}
+	if priority != "" {
+		draw.Priority = strings.Split(priority, "\n")
+	}
+	if draw.DrawnAt, err = time.Parse(time.RFC3339, drawnAt); err != nil {
+		return LotteryDraw{}, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, `SELECT period, person, rank, reservation_id FROM lottery_results WHERE round = ? ORDER BY id`, round)
+	if err != nil {
+		return LotteryDraw{}, err
+	}
+	defer rows.Close()
+
+	draw.Results = []LotteryResult{}
+	for rows.Next() {
+		var (
+			result        LotteryResult
+			reservationID sql.NullInt64
+		)
+		if err := rows.Scan(&result.Period, &result.Person, &result.Rank, &reservationID); err != nil {
+			return LotteryDraw{}, err
+		}
+		result.ReservationID = reservationID.Int64
+		draw.Results = append(draw.Results, result)
+	}
+	return draw, rows.Err()
+}
+
+// LotteryWins counts the periods each household won over all the rounds
+// drawn so far.
+func (s *Store) LotteryWins(ctx context.Context) (map[string]int, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT person, COUNT(*) FROM lottery_results GROUP BY person`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	wins := make(map[string]int)
+	for rows.Next() {
+		var (
+			person string
+			count  int
+		)
+		if err := rows.Scan(&person, &count); err != nil {
+			return nil, err
+		}
+		wins[person] = count
+	}
+	return wins, rows.Err()
+}
+
+// SaveLotteryDraw records the draw and books the reservation of each result,
+// reservations[i] matching draw.Results[i], in one transaction. It returns
+// the draw with the IDs of the reservations.
+func (s *Store) SaveLotteryDraw(ctx context.Context, draw LotteryDraw, reservations []Reservation) (LotteryDraw, error) {
+	if len(reservations) != len(draw.Results) {
+		return LotteryDraw{}, errors.New("one reservation per result is required")
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return LotteryDraw{}, err
+	}
+	defer tx.Rollback()
+
+	var existing int
+	if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM lottery_draws WHERE round = ?`, draw.Round).Scan(&existing); err != nil {
+		return LotteryDraw{}, err
+	}
+	if existing > 0 {
+		return LotteryDraw{}, ErrAlreadyDrawn
+	}
+
+	if _, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO lottery_draws (round, method, seed, priority, drawn_by, drawn_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		draw.Round,
+		draw.Method,
+		draw.Seed,
+		strings.Join(draw.Priority, "\n"),
+		draw.DrawnBy,
+		draw.DrawnAt.UTC().Format(time.RFC3339),
+	); err != nil {
+		return LotteryDraw{}, err
+	}
+
+	results := make([]LotteryResult, len(draw.Results))
+	for i, result := range draw.Results {
+		id, err := s.insertReservation(ctx, tx, reservations[i])
+		if err != nil {
+			return LotteryDraw{}, err
+		}
+		result.ReservationID = id
+		if _, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO lottery_results (round, period, person, rank, reservation_id) VALUES (?, ?, ?, ?, ?)`,
+			draw.Round, result.Period, result.Person, result.Rank, id,
+		); err != nil {
+			return LotteryDraw{}, err
+		}
+		results[i] = result
+	}
+
+	if err := tx.Commit(); err != nil {
+		return LotteryDraw{}, err
+	}
+	draw.Results = results
+	return draw, nil
+}
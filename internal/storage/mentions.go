@@ -0,0 +1,172 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// Mention links a message to a member or household named in it with an "@".
+// CommentID is zero when the mention is in the description of the
+// reservation rather than in its thread.
+type Mention struct {
+	ID            int64     `json:"id"`
+	ReservationID int64     `json:"reservation_id"`
+	CommentID     int64     `json:"comment_id,omitempty"`
+	Name          string    `json:"name"`
+	Author        string    `json:"author"`
+	Text          string    `json:"text"`
+	CreatedAt     time.Time `json:"created_at"`
+	ReadAt        time.Time `json:"read_at,omitzero"`
+}
+
+// SetMentions replaces the names mentioned by a message (commentID zero for
+// the description of the reservation) and returns those that were not
+// mentioned before, so that only they get notified after an edit.
+func (s *Store) SetMentions(ctx context.Context, reservationID, commentID int64, author string, names []string) ([]string, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var comment any
+	where := `reservation_id = ? AND comment_id IS NULL`
+	args := []any{reservationID}
+	if commentID != 0 {
+		comment = commentID
+		where = `comment_id = ?`
+		args = []any{commentID}
+	}
+
+	rows, err := tx.QueryContext(ctx, `SELECT name FROM mentions WHERE `+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	previous := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		previous[name] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	current := make(map[string]bool, len(names))
+	var added []string
+	now := time.Now().UTC().Format(time.RFC3339)
+	for _, name := range names {
+		current[name] = true
+		if previous[name] {
+			continue
+		}
+		added = append(added, name)
+		if _, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO mentions (reservation_id, comment_id, name, author, created_at) VALUES (?, ?, ?, ?, ?)`,
+			reservationID, comment, name, author, now,
+		); err != nil {
+			return nil, err
+		}
+	}
+	for name := range previous {
+		if current[name] {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM mentions WHERE `+where+` AND name = ?`, append(args, name)...); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return added, nil
+}
+
+// ListMentions returns the mentions of any of the names, most recent first,
+// along with the text of the message. unreadOnly leaves out those already
+// read.
+func (s *Store) ListMentions(ctx context.Context, names []string, unreadOnly bool) ([]Mention, error) {
+	if len(names) == 0 {
+		return []Mention{}, nil
+	}
+
+	placeholders := make([]string, 0, len(names))
+	args := make([]any, 0, len(names))
+	for _, name := range names {
+		placeholders = append(placeholders, "?")
+		args = append(args, name)
+	}
+	query := `SELECT m.id, m.reservation_id, m.comment_id, m.name, m.author, COALESCE(c.body, r.comment, ''), m.created_at, m.read_at
+		FROM mentions m
+		JOIN reservations r ON r.id = m.reservation_id
+		LEFT JOIN reservation_comments c ON c.id = m.comment_id
+		WHERE m.name IN (` + strings.Join(placeholders, ",") + `)`
+	if unreadOnly {
+		query += ` AND m.read_at IS NULL`
+	}
+	query += ` ORDER BY m.created_at DESC, m.id DESC`
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Mention{}
+	for rows.Next() {
+		var (
+			m         Mention
+			commentID sql.NullInt64
+			createdAt string
+			readAt    sql.NullString
+		)
+		if err := rows.Scan(&m.ID, &m.ReservationID, &commentID, &m.Name, &m.Author, &m.Text, &createdAt, &readAt); err != nil {
+			return nil, err
+		}
+		if m.CreatedAt, err = time.Parse(time.RFC3339, createdAt); err != nil {
+			return nil, err
+		}
+		if readAt.Valid {
+			if m.ReadAt, err = time.Parse(time.RFC3339, readAt.String); err != nil {
+				return nil, err
+			}
+		}
+		m.CommentID = commentID.Int64
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
+
+// MarkMentionsRead marks as read the unread mentions of any of the names,
+// restricted to ids when provided.
+func (s *Store) MarkMentionsRead(ctx context.Context, names []string, ids []int64) error {
+	if len(names) == 0 {
+		return nil
+	}
+
+	placeholders := make([]string, 0, len(names))
+	args := []any{time.Now().UTC().Format(time.RFC3339)}
+	for _, name := range names {
+		placeholders = append(placeholders, "?")
+		args = append(args, name)
+	}
+	query := `UPDATE mentions SET read_at = ? WHERE read_at IS NULL AND name IN (` + strings.Join(placeholders, ",") + `)`
+	if len(ids) > 0 {
+		idPlaceholders := make([]string, 0, len(ids))
+		for _, id := range ids {
+			idPlaceholders = append(idPlaceholders, "?")
+			args = append(args, id)
+		}
+		query += ` AND id IN (` + strings.Join(idPlaceholders, ",") + `)`
+	}
+	_, err := s.db.ExecContext(ctx, query, args...)
+	return err
+}
@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Meters read at arrival and departure.
+const (
+	MeterElectricity = "electricity"
+	MeterWater       = "water"
+	MeterGas         = "gas"
+)
+
+// Meters lists the meters in display order.
+var Meters = []string{MeterElectricity, MeterWater, MeterGas}
+
+// MeterReading is the index of a meter noted by the occupants of a
+// reservation at arrival or departure (Phase).
+type MeterReading struct {
+	ID            int64     `json:"id"`
+	ReservationID int64     `json:"reservation_id"`
+	Meter         string    `json:"meter"`
+	Phase         string    `json:"phase"`
+	Value         float64   `json:"value"`
+	RecordedBy    string    `json:"recorded_by,omitempty"`
+	RecordedAt    time.Time `json:"recorded_at"`
+}
+
+const meterReadingColumns = `id, reservation_id, meter, phase, value, recorded_by, recorded_at`
+
+// ListMeterReadings returns the readings of the reservation.
+func (s *Store) ListMeterReadings(ctx context.Context, reservationID int64) ([]MeterReading, error) {
+	return scanMeterReadings(s.db.QueryContext(ctx, `SELECT `+meterReadingColumns+` FROM meter_readings WHERE reservation_id = ? ORDER BY meter, phase`, reservationID))
+}
+
+// MeterReadingsByReservation returns every reading, keyed by reservation.
+func (s *Store) MeterReadingsByReservation(ctx context.Context) (map[int64][]MeterReading, error) {
+	readings, err := scanMeterReadings(s.db.QueryContext(ctx, `SELECT `+meterReadingColumns+` FROM meter_readings ORDER BY meter, phase`))
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[int64][]MeterReading)
+	for _, reading := range readings {
+		out[reading.ReservationID] = append(out[reading.ReservationID], reading)
+	}
+	return out, nil
+}
+
+// SetMeterReading records the reading, replacing the one of the same meter
+// and phase, and returns its identifier.
+func (s *Store) SetMeterReading(ctx context.Context, m MeterReading) (int64, error) {
+	switch m.Meter {
+	case MeterElectricity, MeterWater, MeterGas:
+	default:
+		return 0, errors.New("invalid meter")
+	}
+	if m.Phase != PhaseArrival && m.Phase != PhaseDeparture {
+		return 0, errors.New("invalid phase")
+	}
+	if m.Value < 0 {
+		return 0, errors.New("value must not be negative")
+	}
+	if m.RecordedAt.IsZero() {
+		m.RecordedAt = time.Now()
+	}
+
+	var id int64
+	err := s.db.QueryRowContext(
+		ctx,
+		`INSERT INTO meter_readings (reservation_id, meter, phase, value, recorded_by, recorded_at) VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(reservation_id, meter, phase) DO UPDATE SET value = excluded.value, recorded_by = excluded.recorded_by, recorded_at = excluded.recorded_at
+		RETURNING id`,
+		m.ReservationID,
+		m.Meter,
+		m.Phase,
+		m.Value,
+		m.RecordedBy,
+		m.RecordedAt.UTC().Format(time.RFC3339),
+	).Scan(&id)
+	return id, err
+}
+
+// DeleteMeterReading removes the reading matching the provided ID.
+func (s *Store) DeleteMeterReading(ctx context.Context, id int64) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM meter_readings WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	return expectAffected(res)
+}
+
+func scanMeterReadings(rows *sql.Rows, err error) ([]MeterReading, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []MeterReading{}
+	for rows.Next() {
+		var (
+			m          MeterReading
+			recordedBy sql.NullString
+			recordedAt string
+		)
+		if err := rows.Scan(&m.ID, &m.ReservationID, &m.Meter, &m.Phase, &m.Value, &recordedBy, &recordedAt); err != nil {
+			return nil, err
+		}
+		m.RecordedBy = recordedBy.String
+		if m.RecordedAt, err = time.Parse(time.RFC3339, recordedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
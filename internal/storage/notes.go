@@ -0,0 +1,159 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Visibilities of a day note.
+const (
+	NoteVisibilityAll       = "all"
+	NoteVisibilityHousehold = "household"
+	NoteVisibilityPrivate   = "private"
+)
+
+// DayNote is an annotation on the calendar that does not take the apartment,
+// such as a visit of the plumber or a water cut. It covers whole days or
+// half-days.
+type DayNote struct {
+	ID         int64     `json:"id"`
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end"`
+	Text       string    `json:"text"`
+	Author     string    `json:"author"`
+	Person     string    `json:"person"`
+	Visibility string    `json:"visibility"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+const noteColumns = `id, start, end, text, author, person, visibility, created_at`
+
+// ListNotes returns every day note ordered by start date.
+func (s *Store) ListNotes(ctx context.Context) ([]DayNote, error) {
+	return scanNotes(s.db.QueryContext(ctx, `SELECT `+noteColumns+` FROM day_notes ORDER BY start, id`))
+}
+
+// ListNotesBetween returns the day notes overlapping [from, to).
+func (s *Store) ListNotesBetween(ctx context.Context, from, to time.Time) ([]DayNote, error) {
+	return scanNotes(s.db.QueryContext(
+		ctx,
+		`SELECT `+noteColumns+` FROM day_notes WHERE start < ? AND end > ? ORDER BY start, id`,
+		to.UTC().Format(time.RFC3339),
+		from.UTC().Format(time.RFC3339),
+	))
+}
+
+// GetNote returns the day note matching the provided ID, or ErrNotFound.
+func (s *Store) GetNote(ctx context.Context, id int64) (DayNote, error) {
+	notes, err := scanNotes(s.db.QueryContext(ctx, `SELECT `+noteColumns+` FROM day_notes WHERE id = ?`, id))
+	if err != nil {
+		return DayNote{}, err
+	}
+	if len(notes) == 0 {
+		return DayNote{}, ErrNotFound
+	}
+	return notes[0], nil
+}
+
+// CreateNote persists a day note and returns its identifier.
+func (s *Store) CreateNote(ctx context.Context, n DayNote) (int64, error) {
+	if err := validateNote(n); err != nil {
+		return 0, err
+	}
+	if n.CreatedAt.IsZero() {
+		n.CreatedAt = time.Now()
+	}
+
+	res, err := s.db.ExecContext(
+		ctx,
+		`INSERT INTO day_notes (start, end, text, author, person, visibility, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		n.Start.UTC().Format(time.RFC3339),
+		n.End.UTC().Format(time.RFC3339),
+		n.Text,
+		n.Author,
+		n.Person,
+		n.Visibility,
+		n.CreatedAt.UTC().Format(time.RFC3339),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// UpdateNote replaces the period, text and visibility of the day note.
+func (s *Store) UpdateNote(ctx context.Context, n DayNote) error {
+	if err := validateNote(n); err != nil {
+		return err
+	}
+	res, err := s.db.ExecContext(
+		ctx,
+		`UPDATE day_notes SET start = ?, end = ?, text = ?, visibility = ? WHERE id = ?`,
+		n.Start.UTC().Format(time.RFC3339),
+		n.End.UTC().Format(time.RFC3339),
+		n.Text,
+		n.Visibility,
+		n.ID,
+	)
+	if err != nil {
+		return err
+	}
+	return expectAffected(res)
+}
+
+// DeleteNote removes the day note matching the provided ID.
+func (s *Store) DeleteNote(ctx context.Context, id int64) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM day_notes WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	return expectAffected(res)
+}
+
+func validateNote(n DayNote) error {
+	if !n.End.After(n.Start) {
+		return errors.New("end must be after start")
+	}
+	if n.Text == "" {
+		return errors.New("text is required")
+	}
+	switch n.Visibility {
+	case NoteVisibilityAll, NoteVisibilityHousehold, NoteVisibilityPrivate:
+		return nil
+	default:
+		return errors.New("invalid visibility")
+	}
+}
+
+func scanNotes(rows *sql.Rows, err error) ([]DayNote, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []DayNote
+	for rows.Next() {
+		var (
+			n         DayNote
+			start     string
+			end       string
+			createdAt string
+		)
+		if err := rows.Scan(&n.ID, &start, &end, &n.Text, &n.Author, &n.Person, &n.Visibility, &createdAt); err != nil {
+			return nil, err
+		}
+		if n.Start, err = time.Parse(time.RFC3339, start); err != nil {
+			return nil, err
+		}
+		if n.End, err = time.Parse(time.RFC3339, end); err != nil {
+			return nil, err
+		}
+		if n.CreatedAt, err = time.Parse(time.RFC3339, createdAt); err != nil {
+			return nil, err
+		}
+		out = append(out, n)
+	}
+	return out, rows.Err()
+}
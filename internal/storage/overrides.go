@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Override records why a reservation was accepted although it broke a rule
+// such as a quota.
+type Override struct {
+	Rule      string    `json:"rule"`
+	Reason    string    `json:"reason"`
+	Member    string    `json:"member,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func insertOverrides(ctx context.Context, tx *sql.Tx, id int64, overrides []Override) error {
+	for _, override := range overrides {
+		createdAt := override.CreatedAt
+		if createdAt.IsZero() {
+			createdAt = time.Now()
+		}
+		if _, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO reservation_overrides (reservation_id, rule, reason, member, created_at) VALUES (?, ?, ?, ?, ?)`,
+			id,
+			override.Rule,
+			override.Reason,
+			override.Member,
+			createdAt.UTC().Format(time.RFC3339),
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// insertSeriesOverrides records the overrides a series, or one of its
+// occurrences when occurrence is not zero, needed.
+func insertSeriesOverrides(ctx context.Context, tx *sql.Tx, seriesID int64, occurrence time.Time, overrides []Override) error {
+	var at any
+	if !occurrence.IsZero() {
+		at = occurrence.UTC().Format(time.RFC3339)
+	}
+	for _, override := range overrides {
+		createdAt := override.CreatedAt
+		if createdAt.IsZero() {
+			createdAt = time.Now()
+		}
+		if _, err := tx.ExecContext(
+			ctx,
+			`INSERT INTO series_overrides (series_id, occurrence, rule, reason, member, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+			seriesID,
+			at,
+			override.Rule,
+			override.Reason,
+			override.Member,
+			createdAt.UTC().Format(time.RFC3339),
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListOverrides returns the overrides recorded for the reservation.
+func (s *Store) ListOverrides(ctx context.Context, id int64) ([]Override, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT rule, reason, member, created_at FROM reservation_overrides WHERE reservation_id = ? ORDER BY id`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Override
+	for rows.Next() {
+		var (
+			override  Override
+			member    sql.NullString
+			createdAt string
+		)
+		if err := rows.Scan(&override.Rule, &override.Reason, &member, &createdAt); err != nil {
+			return nil, err
+		}
+		override.Member = member.String
+		if override.CreatedAt, err = time.Parse(time.RFC3339, createdAt); err != nil {
+			return nil, err
+		}
+		out = append(out, override)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// RSVP answers.
+const (
+	RSVPYes   = "yes"
+	RSVPNo    = "no"
+	RSVPMaybe = "maybe"
+)
+
+// RSVP is the answer of a household to a family event, with the number of
+// people it brings.
+type RSVP struct {
+	Household string    `json:"household"`
+	Member    string    `json:"member"`
+	Answer    string    `json:"answer"`
+	Headcount int       `json:"headcount"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SetRSVP stores the answer of a household to the event, replacing its
+// previous answer, and records it in the history of the event.
+func (s *Store) SetRSVP(ctx context.Context, id int64, rsvp RSVP) error {
+	switch rsvp.Answer {
+	case RSVPYes, RSVPNo, RSVPMaybe:
+	default:
+		return errors.New("invalid answer")
+	}
+	if rsvp.Headcount < 0 {
+		return errors.New("headcount must not be negative")
+	}
+	if rsvp.UpdatedAt.IsZero() {
+		rsvp.UpdatedAt = time.Now()
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(
+		ctx,
+		`INSERT INTO event_rsvps (reservation_id, household, member, answer, headcount, updated_at) VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(reservation_id, household) DO UPDATE SET member = excluded.member, answer = excluded.answer, headcount = excluded.headcount, updated_at = excluded.updated_at`,
+		id,
+		rsvp.Household,
+		rsvp.Member,
+		rsvp.Answer,
+		rsvp.Headcount,
+		rsvp.UpdatedAt.UTC().Format(time.RFC3339),
+	)
+	if err != nil {
+		return err
+	}
+
+	details := fmt.Sprintf("rsvp %s, headcount %d", rsvp.Answer, rsvp.Headcount)
+	if err := insertHistory(ctx, tx, id, HistoryRSVP, rsvp.Household, rsvp.Member, details); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ListRSVPs returns the answers to the event, latest first.
+func (s *Store) ListRSVPs(ctx context.Context, id int64) ([]RSVP, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT household, member, answer, headcount, updated_at FROM event_rsvps WHERE reservation_id = ? ORDER BY updated_at DESC, household`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []RSVP{}
+	for rows.Next() {
+		var (
+			rsvp      RSVP
+			updatedAt string
+		)
+		if err := rows.Scan(&rsvp.Household, &rsvp.Member, &rsvp.Answer, &rsvp.Headcount, &updatedAt); err != nil {
+			return nil, err
+		}
+		if rsvp.UpdatedAt, err = time.Parse(time.RFC3339, updatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, rsvp)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,360 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+	"unicode"
+
+	"AppartmentBooker/internal/fold"
+)
+
+// Kinds of searchable texts.
+const (
+	SearchReservation = "reservation"
+	SearchComment     = "comment"
+	SearchNote        = "note"
+)
+
+// searchSource is a table whose text column is searchable, along with the
+// names of the people the row is about. Reservation names the column linking
+// a row to its reservation; it is empty for texts that stand on their own.
+type searchSource struct {
+	kind        string
+	table       string
+	text        string
+	names       []string
+	reservation string
+}
+
+var searchSources = []searchSource{
+	{kind: SearchReservation, table: "reservations", text: "comment", names: []string{"person", "member"}, reservation: "id"},
+	{kind: SearchComment, table: "reservation_comments", text: "body", names: []string{"author", "person"}, reservation: "reservation_id"},
+	{kind: SearchNote, table: "day_notes", text: "text", names: []string{"author", "person"}},
+}
+
+// namesExpr is the SQL expression joining the names of a row, each once, its
+// columns prefixed with prefix ("NEW." in triggers). A household of one
+// books under its own name: its member is not repeated.
+func (source searchSource) namesExpr(prefix string) string {
+	var seen []string
+	var parts []string
+	for _, column := range source.names {
+		value := fmt.Sprintf("COALESCE(%s%s, '')", prefix, column)
+		if len(seen) == 0 {
+			parts = append(parts, value)
+		} else {
+			parts = append(parts, fmt.Sprintf("CASE WHEN %s IN ('', %s) THEN '' ELSE ' ' || %s END", value, strings.Join(seen, ", "), value))
+		}
+		seen = append(seen, value)
+	}
+	return strings.Join(parts, " || ")
+}
+
+// Markers delimiting the matched terms in raw snippets, turned into <mark>
+// elements once the snippet is escaped.
+const (
+	markOpen  = "\x02"
+	markClose = "\x03"
+)
+
+// SearchHit is a text matching a search. Snippet is an HTML excerpt of it,
+// escaped, with the matched terms wrapped in <mark> elements. Higher scores
+// rank first.
+type SearchHit struct {
+	Kind          string  `json:"kind"`
+	ID            int64   `json:"id"`
+	ReservationID int64   `json:"reservation_id,omitempty"`
+	Snippet       string  `json:"snippet"`
+	Score         float64 `json:"score"`
+}
+
+// initialiseSearch sets up the full-text index when SQLite was built with
+// FTS5 (the sqlite_fts5 build tag) and reports whether it is available. The
+// index is rebuilt from the source tables on every start, then kept in sync
+// by triggers.
+func initialiseSearch(db *sql.DB) (bool, error) {
+	// The index and its triggers may come from another build or an older
+	// layout: drop them, as the index is rebuilt anyway.
+	for _, source := range searchSources {
+		for _, event := range []string{"insert", "update", "delete"} {
+			if _, err := db.Exec(fmt.Sprintf(`DROP TRIGGER IF EXISTS search_%s_%s`, source.kind, event)); err != nil {
+				return false, err
+			}
+		}
+	}
+	_, err := db.Exec(`DROP TABLE IF EXISTS search_index`)
+	if err == nil {
+		_, err = db.Exec(`CREATE VIRTUAL TABLE search_index USING fts5(
+			kind UNINDEXED,
+			ref UNINDEXED,
+			reservation_id UNINDEXED,
+			body,
+			names,
+			tokenize = 'unicode61 remove_diacritics 2'
+		)`)
+	}
+	if err != nil {
+		if !strings.Contains(err.Error(), "no such module") {
+			return false, err
+		}
+		return false, nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	for _, source := range searchSources {
+		reservation, newReservation := "0", "0"
+		if source.reservation != "" {
+			reservation, newReservation = source.reservation, "NEW."+source.reservation
+		}
+		columns := strings.Join(append([]string{source.text}, source.names...), ", ")
+		statements := []string{
+			fmt.Sprintf(
+				`INSERT INTO search_index (kind, ref, reservation_id, body, names) SELECT '%s', id, %s, COALESCE(%s, ''), %s FROM %s`,
+				source.kind, reservation, source.text, source.namesExpr(""), source.table,
+			),
+			fmt.Sprintf(
+				`CREATE TRIGGER search_%[1]s_insert AFTER INSERT ON %[2]s BEGIN
+					INSERT INTO search_index (kind, ref, reservation_id, body, names) VALUES ('%[1]s', NEW.id, %[4]s, COALESCE(NEW.%[3]s, ''), %[5]s);
+				END`,
+				source.kind, source.table, source.text, newReservation, source.namesExpr("NEW."),
+			),
+			fmt.Sprintf(
+				`CREATE TRIGGER search_%[1]s_update AFTER UPDATE OF %[3]s ON %[2]s BEGIN
+					DELETE FROM search_index WHERE kind = '%[1]s' AND ref = OLD.id;
+					INSERT INTO search_index (kind, ref, reservation_id, body, names) VALUES ('%[1]s', NEW.id, %[4]s, COALESCE(NEW.%[5]s, ''), %[6]s);
+				END`,
+				source.kind, source.table, columns, newReservation, source.text, source.namesExpr("NEW."),
+			),
+			fmt.Sprintf(
+				`CREATE TRIGGER search_%[1]s_delete AFTER DELETE ON %[2]s BEGIN
+					DELETE FROM search_index WHERE kind = '%[1]s' AND ref = OLD.id;
+				END`,
+				source.kind, source.table,
+			),
+		}
+		for _, statement := range statements {
+			if _, err := tx.Exec(statement); err != nil {
+				return false, err
+			}
+		}
+	}
+	return true, tx.Commit()
+}
+
+// Search returns the texts matching every word of query, best first, at most
+// limit of them. A word matches in the text or in the names of the people
+// the text is about: the household, the member who booked, the author.
+// Matching ignores case and accents; each word also matches the words it
+// starts ("plomb" finds "plombier"). Without FTS5 the texts are
+// scanned instead, which gives the same matches with a simpler ranking.
+func (s *Store) Search(ctx context.Context, query string, limit int) ([]SearchHit, error) {
+	var terms []string
+	for _, word := range strings.Fields(query) {
+		word = strings.Trim(strings.ReplaceAll(word, `"`, ""), "*")
+		if word != "" {
+			terms = append(terms, word)
+		}
+	}
+	if len(terms) == 0 {
+		return []SearchHit{}, nil
+	}
+	if s.fts {
+		return s.searchIndex(ctx, terms, limit)
+	}
+	return s.searchScan(ctx, terms, limit)
+}
+
+func (s *Store) searchIndex(ctx context.Context, terms []string, limit int) ([]SearchHit, error) {
+	quoted := make([]string, 0, len(terms))
+	for _, term := range terms {
+		quoted = append(quoted, `"`+term+`"*`)
+	}
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT kind, ref, reservation_id, snippet(search_index, -1, ?, ?, '…', 16), bm25(search_index)
+		FROM search_index WHERE search_index MATCH ? ORDER BY rank LIMIT ?`,
+		markOpen, markClose, strings.Join(quoted, " "), limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []SearchHit{}
+	for rows.Next() {
+		var (
+			hit     SearchHit
+			snippet string
+			rank    float64
+		)
+		if err := rows.Scan(&hit.Kind, &hit.ID, &hit.ReservationID, &snippet, &rank); err != nil {
+			return nil, err
+		}
+		// bm25 is lower for better matches.
+		hit.Score = -rank
+		hit.Snippet = highlight(snippet)
+		out = append(out, hit)
+	}
+	return out, rows.Err()
+}
+
+// searchScan matches the folded texts in Go, ranking them by the number of
+// occurrences of the terms.
+func (s *Store) searchScan(ctx context.Context, terms []string, limit int) ([]SearchHit, error) {
+	folded := make([][]rune, 0, len(terms))
+	for _, term := range terms {
+		folded = append(folded, []rune(fold.String(term)))
+	}
+
+	out := []SearchHit{}
+	for _, source := range searchSources {
+		reservation := "0"
+		if source.reservation != "" {
+			reservation = source.reservation
+		}
+		rows, err := s.db.QueryContext(ctx, fmt.Sprintf(
+			`SELECT id, %s, COALESCE(%s, ''), %s FROM %s`,
+			reservation, source.text, source.namesExpr(""), source.table,
+		))
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			hit := SearchHit{Kind: source.kind}
+			var text, names string
+			if err := rows.Scan(&hit.ID, &hit.ReservationID, &text, &names); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			snippet, count := scanText(text, names, folded)
+			if count == 0 {
+				continue
+			}
+			hit.Score = float64(count)
+			hit.Snippet = highlight(snippet)
+			out = append(out, hit)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Score != out[j].Score {
+			return out[i].Score > out[j].Score
+		}
+		return out[i].ID > out[j].ID
+	})
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+// scanText looks for every term in text and names, at the start of words.
+// It returns a raw snippet around the first match, in the text unless only
+// the names match, and the number of matches, zero unless all terms are
+// found.
+func scanText(text, names string, terms [][]rune) (string, int) {
+	body, people := newScannedText(text), newScannedText(names)
+
+	count := 0
+	for _, term := range terms {
+		found := body.match(term) + people.match(term)
+		if found == 0 {
+			return "", 0
+		}
+		count += found
+	}
+	if body.first == -1 {
+		return people.snippet(), count
+	}
+	return body.snippet(), count
+}
+
+// scannedText is a text being matched, its folded runes aligned with the
+// original ones.
+type scannedText struct {
+	original []rune
+	runes    []rune
+	matched  []bool
+	first    int
+}
+
+func newScannedText(text string) *scannedText {
+	runes := []rune(fold.String(text))
+	return &scannedText{
+		original: []rune(text),
+		runes:    runes,
+		matched:  make([]bool, len(runes)),
+		first:    -1,
+	}
+}
+
+// match marks the words of the text starting with term and returns their
+// number.
+func (t *scannedText) match(term []rune) int {
+	count := 0
+	for i := 0; i+len(term) <= len(t.runes); i++ {
+		if (i > 0 && isWordRune(t.runes[i-1])) || string(t.runes[i:i+len(term)]) != string(term) {
+			continue
+		}
+		count++
+		// Highlight the whole word, as the index does.
+		for j := i; j < len(t.runes) && (j < i+len(term) || isWordRune(t.runes[j])); j++ {
+			t.matched[j] = true
+		}
+		if t.first == -1 || i < t.first {
+			t.first = i
+		}
+	}
+	return count
+}
+
+// snippet returns the text around the first match, with markers around the
+// matched words.
+func (t *scannedText) snippet() string {
+	const context = 60
+	from := max(0, t.first-context)
+	to := min(len(t.original), t.first+context)
+	var b strings.Builder
+	if from > 0 {
+		b.WriteString("…")
+	}
+	for i := from; i < to; i++ {
+		if t.matched[i] && (i == from || !t.matched[i-1]) {
+			b.WriteString(markOpen)
+		}
+		b.WriteRune(t.original[i])
+		if t.matched[i] && (i+1 == to || !t.matched[i+1]) {
+			b.WriteString(markClose)
+		}
+	}
+	if to < len(t.original) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// highlight escapes a raw snippet and turns its markers into <mark>
+// elements.
+func highlight(snippet string) string {
+	escaped := html.EscapeString(snippet)
+	escaped = strings.ReplaceAll(escaped, markOpen, "<mark>")
+	return strings.ReplaceAll(escaped, markClose, "</mark>")
+}
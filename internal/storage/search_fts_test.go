@@ -0,0 +1,48 @@
+//go:build sqlite_fts5
+
+package storage
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// TestSearchIndex covers the FTS5 index and the triggers keeping it in sync.
+func TestSearchIndex(t *testing.T) {
+	store := newTestStore(t)
+	if !store.fts {
+		t.Fatal("FTS5 unavailable despite the sqlite_fts5 build tag")
+	}
+	testSearch(t, store)
+}
+
+func TestSearchIndexRebuiltOnStart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	store, err := New(path, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := mustCreate(t, store, Reservation{Person: "Manon", Start: day(1, 12), End: day(3, 12), Comment: "Plombier", Adults: 1})
+	// An index of another layout is replaced.
+	if _, err := store.db.Exec(`DROP TABLE search_index`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.db.Exec(`CREATE VIRTUAL TABLE search_index USING fts5(kind UNINDEXED, ref UNINDEXED, reservation_id UNINDEXED, body)`); err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	store, err = New(path, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	for _, query := range []string{"plombier", "manon"} {
+		if got, want := searchRefs(t, store, query), []string{fmt.Sprintf("%s:%d", SearchReservation, id)}; !slices.Equal(got, want) {
+			t.Errorf("Search(%q) after restart = %v, want %v", query, got, want)
+		}
+	}
+}
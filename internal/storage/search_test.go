@@ -0,0 +1,168 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// searchRefs runs a search and returns "kind:id" for each hit, best first.
+func searchRefs(t *testing.T, store *Store, query string) []string {
+	t.Helper()
+	hits, err := store.Search(context.Background(), query, 50)
+	if err != nil {
+		t.Fatalf("Search(%q): %v", query, err)
+	}
+	refs := []string{}
+	for _, hit := range hits {
+		refs = append(refs, fmt.Sprintf("%s:%d", hit.Kind, hit.ID))
+	}
+	return refs
+}
+
+// testSearch checks the matches of Search, whichever path the store takes.
+func testSearch(t *testing.T, store *Store) {
+	ctx := context.Background()
+	plumber := mustCreate(t, store, Reservation{
+		Person: "Florence et Valentin", Member: "Valentin", Start: day(1, 12), End: day(3, 12),
+		Comment: "Passage du plombier pour la chaudière", Adults: 2,
+	})
+	other := mustCreate(t, store, Reservation{
+		Person: "Joëlle et Yves", Member: "Joëlle", Start: day(5, 12), End: day(7, 12),
+		Comment: "Réunion de la copropriété", Adults: 2,
+	})
+	comment, err := store.CreateComment(ctx, Comment{
+		ReservationID: other, Author: "Yves", Person: "Joëlle et Yves", Body: "Le plombier revient jeudi", CreatedAt: day(5, 12),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	note, err := store.CreateNote(ctx, DayNote{
+		Start: day(1, 0), End: day(2, 0), Text: "Clés chez la voisine", Author: "Grégoire", Person: "Grégoire",
+		Visibility: NoteVisibilityHousehold, CreatedAt: day(1, 0),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref := func(kind string, id int64) string { return fmt.Sprintf("%s:%d", kind, id) }
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"chaudière", []string{ref(SearchReservation, plumber)}},
+		{"plombier", []string{ref(SearchReservation, plumber), ref(SearchComment, comment)}},
+		{"plombier jeudi", []string{ref(SearchComment, comment)}},
+		{"plombier samedi", []string{}},
+		// Accents are folded both ways, and a word matches the words it
+		// starts.
+		{"CHAUDIERE", []string{ref(SearchReservation, plumber)}},
+		{"copropriete", []string{ref(SearchReservation, other)}},
+		{"réunion", []string{ref(SearchReservation, other)}},
+		{"plomb", []string{ref(SearchReservation, plumber), ref(SearchComment, comment)}},
+		{"lombier", []string{}},
+		// Names match: the household, the member who booked, the author.
+		{"valentin", []string{ref(SearchReservation, plumber)}},
+		{"joelle", []string{ref(SearchReservation, other), ref(SearchComment, comment)}},
+		{"yves plombier", []string{ref(SearchComment, comment)}},
+		{"gregoire", []string{ref(SearchNote, note)}},
+		// A word of one household's texts does not pull in the other's.
+		{"florence copropriete", []string{}},
+		{"joelle chaudiere", []string{}},
+	}
+	for _, tt := range tests {
+		got := searchRefs(t, store, tt.query)
+		slices.Sort(got)
+		slices.Sort(tt.want)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+
+	hits, err := store.Search(ctx, "chaudiere", 50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 1 || !strings.Contains(hits[0].Snippet, "<mark>chaudière</mark>") || hits[0].ReservationID != plumber {
+		t.Errorf("Search(chaudiere) = %+v, want the highlighted description of %d", hits, plumber)
+	}
+
+	// The index follows edits and deletions.
+	if err := store.UpdateReservationComment(ctx, plumber, "Ramonage de la cheminée"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.UpdateComment(ctx, comment, "Le chauffagiste revient jeudi"); err != nil {
+		t.Fatal(err)
+	}
+	if got := searchRefs(t, store, "plombier"); len(got) != 0 {
+		t.Errorf("Search(plombier) after edits = %v, want none", got)
+	}
+	if got := searchRefs(t, store, "cheminee"); !slices.Equal(got, []string{ref(SearchReservation, plumber)}) {
+		t.Errorf("Search(cheminee) after edit = %v", got)
+	}
+	if got := searchRefs(t, store, "chauffagiste"); !slices.Equal(got, []string{ref(SearchComment, comment)}) {
+		t.Errorf("Search(chauffagiste) after edit = %v", got)
+	}
+	if err := store.DeleteComment(ctx, comment); err != nil {
+		t.Fatal(err)
+	}
+	if got := searchRefs(t, store, "chauffagiste"); len(got) != 0 {
+		t.Errorf("Search(chauffagiste) after deletion = %v, want none", got)
+	}
+	if err := store.DeleteReservation(ctx, plumber); err != nil {
+		t.Fatal(err)
+	}
+	if got := searchRefs(t, store, "valentin"); len(got) != 0 {
+		t.Errorf("Search(valentin) after deletion = %v, want none", got)
+	}
+}
+
+// TestSearchScan covers the scan used when SQLite lacks FTS5.
+func TestSearchScan(t *testing.T) {
+	store := newTestStore(t)
+	store.fts = false
+	testSearch(t, store)
+}
+
+func TestScanTextSnippet(t *testing.T) {
+	terms := func(words ...string) [][]rune {
+		var out [][]rune
+		for _, word := range words {
+			out = append(out, []rune(word))
+		}
+		return out
+	}
+	tests := []struct {
+		text, names string
+		terms       [][]rune
+		want        string
+		count       int
+	}{
+		{"Le plombier & la chaudière", "Manon", terms("plomb"), "Le \x02plombier\x03 & la chaudière", 1},
+		{"Le plombier", "Manon", terms("manon"), "\x02Manon\x03", 1},
+		{"", "Manon Yves", terms("yves"), "Manon \x02Yves\x03", 1},
+		{"Le plombier", "Manon", terms("manon", "plombier"), "Le \x02plombier\x03", 2},
+		{"Le plombier", "Manon", terms("chaudiere"), "", 0},
+	}
+	for _, tt := range tests {
+		got, count := scanText(tt.text, tt.names, tt.terms)
+		if got != tt.want || count != tt.count {
+			t.Errorf("scanText(%q, %q) = %q, %d, want %q, %d", tt.text, tt.names, got, count, tt.want, tt.count)
+		}
+	}
+}
+
+func TestSearchNamesOnce(t *testing.T) {
+	store := newTestStore(t)
+	store.fts = false
+	id := mustCreate(t, store, Reservation{Person: "Manon", Member: "Manon", Start: day(1, 12), End: day(3, 12), Adults: 1})
+	hits, err := store.Search(context.Background(), "manon", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 1 || hits[0].ID != id || hits[0].Snippet != "<mark>Manon</mark>" {
+		t.Errorf("Search(manon) = %+v, want one match on the name of %d", hits, id)
+	}
+}
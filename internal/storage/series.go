@@ -0,0 +1,482 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+	"time"
+
+	"AppartmentBooker/internal/recurrence"
+)
+
+// SeriesHorizon bounds how far ahead of now ListReservations expands
+// recurring series.
+const SeriesHorizon = 2 * 366 * 24 * time.Hour
+
+// maxOccurrences bounds the occurrences of a single series returned by one
+// expansion.
+const maxOccurrences = 1000
+
+// Series is a recurring reservation. Start and End delimit the first
+// occurrence and RRule, an RFC 5545 recurrence rule, describes the repetition.
+type Series struct {
+	ID       int64     `json:"id"`
+	Person   string    `json:"person"`
+	Member   string    `json:"member,omitempty"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	RRule    string    `json:"rrule"`
+	Comment  string    `json:"comment"`
+	Adults   int       `json:"adults"`
+	Children int       `json:"children"`
+	// Status applies to every occurrence: a series waiting for approval is
+	// approved or declined as a whole.
+	Status     string            `json:"status"`
+	Exceptions []SeriesException `json:"exceptions,omitempty"`
+	// Overrides are recorded along with a series whose occurrences break
+	// a rule or a quota.
+	Overrides []Override `json:"overrides,omitempty"`
+}
+
+// SeriesException cancels (EXDATE) or overrides a single occurrence of a
+// series, identified by its original start.
+type SeriesException struct {
+	Occurrence time.Time `json:"occurrence"`
+	Cancelled  bool      `json:"cancelled"`
+	Start      time.Time `json:"start,omitempty"`
+	End        time.Time `json:"end,omitempty"`
+	Comment    string    `json:"comment,omitempty"`
+}
+
+// Occurrence returns the reservation corresponding to the occurrence of the
+// series starting at start, before exceptions are applied. The end keeps the
+// local time of day and the number of days of the first occurrence, and the
+// status is the series'.
+func (series Series) Occurrence(start time.Time) Reservation {
+	loc := start.Location()
+	first := series.Start.In(loc)
+	last := series.End.In(loc)
+	days := int(time.Date(last.Year(), last.Month(), last.Day(), 0, 0, 0, 0, time.UTC).
+		Sub(time.Date(first.Year(), first.Month(), first.Day(), 0, 0, 0, 0, time.UTC)) / (24 * time.Hour))
+	hour, minute, second := last.Clock()
+	end := time.Date(start.Year(), start.Month(), start.Day()+days, hour, minute, second, 0, loc)
+	status := series.Status
+	if status == "" {
+		status = StatusConfirmed
+	}
+
+	return Reservation{
+		Person:     series.Person,
+		Member:     series.Member,
+		Start:      start,
+		End:        end,
+		Comment:    series.Comment,
+		Adults:     series.Adults,
+		Children:   series.Children,
+		Status:     status,
+		SeriesID:   series.ID,
+		Occurrence: start,
+	}
+}
+
+// Expand returns the occurrences of the series overlapping [from, to) with
+// exceptions applied, at most maxOccurrences of them counted from the start
+// of the window. A zero from means no lower bound.
+func (series Series) Expand(loc *time.Location, from, to time.Time) ([]Reservation, error) {
+	rule, err := recurrence.Parse(series.RRule, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	exceptions := make(map[int64]SeriesException, len(series.Exceptions))
+	for _, exc := range series.Exceptions {
+		exceptions[exc.Occurrence.Unix()] = exc
+	}
+
+	var out []Reservation
+	for _, start := range rule.Occurrences(series.Start.In(loc), to) {
+		occurrence := series.Occurrence(start)
+		if exc, ok := exceptions[start.Unix()]; ok {
+			if exc.Cancelled {
+				continue
+			}
+			occurrence.Start = exc.Start
+			occurrence.End = exc.End
+			occurrence.Comment = exc.Comment
+		}
+		if !from.IsZero() && !Overlaps(occurrence.Start, occurrence.End, from, to) {
+			continue
+		}
+		if from.IsZero() && !occurrence.Start.Before(to) {
+			continue
+		}
+		out = append(out, occurrence)
+		if len(out) == maxOccurrences {
+			break
+		}
+	}
+	return out, nil
+}
+
+// HasOccurrence reports whether the series has an occurrence starting at
+// start, ignoring exceptions.
+func (series Series) HasOccurrence(loc *time.Location, start time.Time) bool {
+	rule, err := recurrence.Parse(series.RRule, loc)
+	if err != nil {
+		return false
+	}
+	for _, candidate := range rule.Occurrences(series.Start.In(loc), start.Add(time.Second)) {
+		if candidate.Equal(start) {
+			return true
+		}
+	}
+	return false
+}
+
+// withOccurrences appends the expanded series occurrences overlapping
+// [from, to) to the reservations and sorts the result by start date.
+func (s *Store) withOccurrences(ctx context.Context, reservations []Reservation, from, to time.Time) ([]Reservation, error) {
+	series, err := s.ListSeries(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(series) == 0 {
+		return reservations, nil
+	}
+
+	for _, item := range series {
+		occurrences, err := item.Expand(s.loc, from, to)
+		if err != nil {
+			// A rule that no longer parses must not hide the other bookings.
+			continue
+		}
+		reservations = append(reservations, occurrences...)
+	}
+	sort.SliceStable(reservations, func(i, j int) bool {
+		return reservations[i].Start.Before(reservations[j].Start)
+	})
+	return reservations, nil
+}
+
+// Location returns the time zone series are expanded in.
+func (s *Store) Location() *time.Location {
+	return s.loc
+}
+
+// queryer runs queries on the database or within a transaction.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// ListSeries returns every recurring series with its exceptions.
+func (s *Store) ListSeries(ctx context.Context) ([]Series, error) {
+	return listSeries(ctx, s.db)
+}
+
+func listSeries(ctx context.Context, q queryer) ([]Series, error) {
+	rows, err := q.QueryContext(ctx, `SELECT id, person, member, start, end, rrule, comment, adults, children, status FROM reservation_series ORDER BY start`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Series
+	index := make(map[int64]int)
+	for rows.Next() {
+		item, err := scanSeries(rows)
+		if err != nil {
+			return nil, err
+		}
+		index[item.ID] = len(out)
+		out = append(out, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, nil
+	}
+
+	exceptions, err := q.QueryContext(ctx, `SELECT series_id, occurrence, cancelled, start, end, comment FROM series_exceptions ORDER BY occurrence`)
+	if err != nil {
+		return nil, err
+	}
+	defer exceptions.Close()
+
+	for exceptions.Next() {
+		seriesID, exc, err := scanSeriesException(exceptions)
+		if err != nil {
+			return nil, err
+		}
+		if i, ok := index[seriesID]; ok {
+			out[i].Exceptions = append(out[i].Exceptions, exc)
+		}
+	}
+	return out, exceptions.Err()
+}
+
+// GetSeries returns the series matching the provided ID, or ErrNotFound.
+func (s *Store) GetSeries(ctx context.Context, id int64) (Series, error) {
+	series, err := s.ListSeries(ctx)
+	if err != nil {
+		return Series{}, err
+	}
+	for _, item := range series {
+		if item.ID == id {
+			return item, nil
+		}
+	}
+	return Series{}, ErrNotFound
+}
+
+// CreateSeries persists a recurring series along with its overrides and
+// returns its identifier. A *TurnoverConflictError is returned if an upcoming
+// occurrence falls within the turnover buffer of another stay.
+func (s *Store) CreateSeries(ctx context.Context, series Series) (int64, error) {
+	if err := s.validateSeries(series); err != nil {
+		return 0, err
+	}
+	if series.Status == "" {
+		series.Status = StatusConfirmed
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if err := s.checkSeriesTurnover(ctx, tx, series); err != nil {
+		return 0, err
+	}
+	res, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO reservation_series (person, member, start, end, rrule, comment, adults, children, status) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		series.Person,
+		series.Member,
+		series.Start.UTC().Format(time.RFC3339),
+		series.End.UTC().Format(time.RFC3339),
+		series.RRule,
+		series.Comment,
+		series.Adults,
+		series.Children,
+		series.Status,
+	)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	if err := insertSeriesOverrides(ctx, tx, id, time.Time{}, series.Overrides); err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+// UpdateSeries replaces the definition of a series and records its new
+// overrides. Existing exceptions are kept; those no longer matching an
+// occurrence are simply ignored. A *TurnoverConflictError is returned if an
+// upcoming occurrence falls within the turnover buffer of another stay.
+func (s *Store) UpdateSeries(ctx context.Context, series Series) error {
+	if err := s.validateSeries(series); err != nil {
+		return err
+	}
+	if series.Status == "" {
+		series.Status = StatusConfirmed
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := s.checkSeriesTurnover(ctx, tx, series); err != nil {
+		return err
+	}
+	res, err := tx.ExecContext(
+		ctx,
+		`UPDATE reservation_series SET start = ?, end = ?, rrule = ?, comment = ?, adults = ?, children = ?, status = ? WHERE id = ?`,
+		series.Start.UTC().Format(time.RFC3339),
+		series.End.UTC().Format(time.RFC3339),
+		series.RRule,
+		series.Comment,
+		series.Adults,
+		series.Children,
+		series.Status,
+		series.ID,
+	)
+	if err != nil {
+		return err
+	}
+	if err := expectAffected(res); err != nil {
+		return err
+	}
+	if err := insertSeriesOverrides(ctx, tx, series.ID, time.Time{}, series.Overrides); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteSeries removes the series and all its occurrences.
+func (s *Store) DeleteSeries(ctx context.Context, id int64) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM reservation_series WHERE id = ?`, id)
+	return err
+}
+
+// SetSeriesException cancels or overrides one occurrence of the series,
+// replacing any previous exception for it, and records the overrides the
+// change needed. A *TurnoverConflictError is returned if the moved occurrence
+// falls within the turnover buffer of another stay.
+func (s *Store) SetSeriesException(ctx context.Context, seriesID int64, exc SeriesException, overrides []Override) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var start, end any
+	if !exc.Cancelled {
+		if !exc.End.After(exc.Start) {
+			return errors.New("end must be after start")
+		}
+		series, err := listSeries(ctx, tx)
+		if err != nil {
+			return err
+		}
+		for _, item := range series {
+			if item.ID != seriesID {
+				continue
+			}
+			occurrence := item.Occurrence(exc.Occurrence.In(s.loc))
+			occurrence.Start, occurrence.End = exc.Start, exc.End
+			if err := s.checkTurnover(ctx, tx, occurrence, 0); err != nil {
+				return err
+			}
+		}
+		start = exc.Start.UTC().Format(time.RFC3339)
+		end = exc.End.UTC().Format(time.RFC3339)
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`INSERT INTO series_exceptions (series_id, occurrence, cancelled, start, end, comment) VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(series_id, occurrence) DO UPDATE SET cancelled = excluded.cancelled, start = excluded.start, end = excluded.end, comment = excluded.comment`,
+		seriesID,
+		exc.Occurrence.UTC().Format(time.RFC3339),
+		exc.Cancelled,
+		start,
+		end,
+		exc.Comment,
+	)
+	if err != nil {
+		return err
+	}
+	if err := insertSeriesOverrides(ctx, tx, seriesID, exc.Occurrence, overrides); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// checkSeriesTurnover checks the upcoming occurrences of the series against
+// the turnover buffers of the other stays.
+func (s *Store) checkSeriesTurnover(ctx context.Context, tx *sql.Tx, series Series) error {
+	if s.turnover <= 0 {
+		return nil
+	}
+	now := time.Now()
+	occurrences, err := series.Expand(s.loc, now, now.Add(SeriesHorizon))
+	if err != nil {
+		return err
+	}
+	for _, occurrence := range occurrences {
+		if err := s.checkTurnover(ctx, tx, occurrence, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Store) validateSeries(series Series) error {
+	if series.Person == "" {
+		return errors.New("person is required")
+	}
+	if !series.End.After(series.Start) {
+		return errors.New("end must be after start")
+	}
+	if series.Adults < 0 || series.Children < 0 {
+		return errors.New("guest counts must not be negative")
+	}
+	_, err := recurrence.Parse(series.RRule, s.loc)
+	return err
+}
+
+func scanSeries(rows *sql.Rows) (Series, error) {
+	var (
+		item    Series
+		member  sql.NullString
+		start   string
+		end     string
+		comment sql.NullString
+	)
+	if err := rows.Scan(&item.ID, &item.Person, &member, &start, &end, &item.RRule, &comment, &item.Adults, &item.Children, &item.Status); err != nil {
+		return Series{}, err
+	}
+
+	var err error
+	if item.Start, err = time.Parse(time.RFC3339, start); err != nil {
+		return Series{}, err
+	}
+	if item.End, err = time.Parse(time.RFC3339, end); err != nil {
+		return Series{}, err
+	}
+	item.Member = member.String
+	item.Comment = comment.String
+	return item, nil
+}
+
+func scanSeriesException(rows *sql.Rows) (int64, SeriesException, error) {
+	var (
+		seriesID   int64
+		exc        SeriesException
+		occurrence string
+		start      sql.NullString
+		end        sql.NullString
+		comment    sql.NullString
+	)
+	if err := rows.Scan(&seriesID, &occurrence, &exc.Cancelled, &start, &end, &comment); err != nil {
+		return 0, SeriesException{}, err
+	}
+
+	var err error
+	if exc.Occurrence, err = time.Parse(time.RFC3339, occurrence); err != nil {
+		return 0, SeriesException{}, err
+	}
+	if start.Valid {
+		if exc.Start, err = time.Parse(time.RFC3339, start.String); err != nil {
+			return 0, SeriesException{}, err
+		}
+	}
+	if end.Valid {
+		if exc.End, err = time.Parse(time.RFC3339, end.String); err != nil {
+			return 0, SeriesException{}, err
+		}
+	}
+	exc.Comment = comment.String
+	return seriesID, exc, nil
+}
+
+// expectAffected turns an update that matched no row into ErrNotFound.
+func expectAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSeriesExpand(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skipf("time zone Europe/Paris unavailable: %v", err)
+	}
+	local := func(month time.Month, day, hour int) time.Time {
+		return time.Date(2027, month, day, hour, 0, 0, 0, paris)
+	}
+
+	// Weekends from Friday 17h to Sunday 10h, across the switch to summer
+	// time on 28 March 2027.
+	base := Series{
+		ID:     7,
+		Person: "Manon",
+		Start:  local(time.March, 19, 17),
+		End:    local(time.March, 21, 10),
+		RRule:  "FREQ=WEEKLY;COUNT=3",
+	}
+
+	type stay struct{ start, end time.Time }
+	tests := []struct {
+		name       string
+		exceptions []SeriesException
+		from, to   time.Time
+		want       []stay
+	}{
+		{
+			name: "plain occurrences keep their local times",
+			to:   local(time.December, 31, 0),
+			want: []stay{
+				{local(time.March, 19, 17), local(time.March, 21, 10)},
+				{local(time.March, 26, 17), local(time.March, 28, 10)},
+				{local(time.April, 2, 17), local(time.April, 4, 10)},
+			},
+		},
+		{
+			name:       "cancelled occurrence",
+			exceptions: []SeriesException{{Occurrence: local(time.March, 26, 17), Cancelled: true}},
+			to:         local(time.December, 31, 0),
+			want: []stay{
+				{local(time.March, 19, 17), local(time.March, 21, 10)},
+				{local(time.April, 2, 17), local(time.April, 4, 10)},
+			},
+		},
+		{
+			name: "moved occurrence",
+			exceptions: []SeriesException{{
+				Occurrence: local(time.April, 2, 17).UTC(),
+				Start:      local(time.April, 3, 9),
+				End:        local(time.April, 5, 18),
+				Comment:    "Lundi de Paques",
+			}},
+			to: local(time.December, 31, 0),
+			want: []stay{
+				{local(time.March, 19, 17), local(time.March, 21, 10)},
+				{local(time.March, 26, 17), local(time.March, 28, 10)},
+				{local(time.April, 3, 9), local(time.April, 5, 18)},
+			},
+		},
+		{
+			name: "window keeps overlapping occurrences only",
+			from: local(time.March, 27, 0),
+			to:   local(time.April, 3, 0),
+			want: []stay{
+				{local(time.March, 26, 17), local(time.March, 28, 10)},
+				{local(time.April, 2, 17), local(time.April, 4, 10)},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			series := base
+			series.Exceptions = tt.exceptions
+			got, err := series.Expand(paris, tt.from, tt.to)
+			if err != nil {
+				t.Fatalf("Expand: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d occurrences, want %d: %+v", len(got), len(tt.want), got)
+			}
+			for i, want := range tt.want {
+				if !got[i].Start.Equal(want.start) || !got[i].End.Equal(want.end) {
+					t.Errorf("occurrence %d = [%s, %s), want [%s, %s)", i, got[i].Start, got[i].End, want.start, want.end)
+				}
+				if got[i].SeriesID != series.ID || got[i].Person != series.Person {
+					t.Errorf("occurrence %d not attached to the series: %+v", i, got[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSeriesHasOccurrence(t *testing.T) {
+	series := Series{
+		Start: time.Date(2027, time.January, 1, 17, 0, 0, 0, time.UTC),
+		End:   time.Date(2027, time.January, 3, 10, 0, 0, 0, time.UTC),
+		RRule: "FREQ=WEEKLY",
+	}
+	tests := []struct {
+		start time.Time
+		want  bool
+	}{
+		{time.Date(2027, time.January, 1, 17, 0, 0, 0, time.UTC), true},
+		{time.Date(2027, time.January, 15, 17, 0, 0, 0, time.UTC), true},
+		{time.Date(2027, time.January, 15, 18, 0, 0, 0, time.UTC), false},
+		{time.Date(2027, time.January, 16, 17, 0, 0, 0, time.UTC), false},
+		{time.Date(2026, time.December, 25, 17, 0, 0, 0, time.UTC), false},
+	}
+	for _, tt := range tests {
+		if got := series.HasOccurrence(time.UTC, tt.start); got != tt.want {
+			t.Errorf("HasOccurrence(%s) = %v, want %v", tt.start, got, tt.want)
+		}
+	}
+}
+
+func TestSeriesOccurrenceStatus(t *testing.T) {
+	start := time.Date(2027, time.January, 1, 17, 0, 0, 0, time.UTC)
+	tests := []struct {
+		status string
+		want   string
+	}{
+		{"", StatusConfirmed},
+		{StatusConfirmed, StatusConfirmed},
+		{StatusTentative, StatusTentative},
+		{StatusDeclined, StatusDeclined},
+	}
+	for _, tt := range tests {
+		series := Series{Start: start, End: start.Add(40 * time.Hour), RRule: "FREQ=WEEKLY", Status: tt.status}
+		if got := series.Occurrence(start.AddDate(0, 0, 7)).Status; got != tt.want {
+			t.Errorf("status %q: occurrence status = %q, want %q", tt.status, got, tt.want)
+		}
+	}
+}
+
+func TestSeriesExpandLongAfterStart(t *testing.T) {
+	// A daily series started years before the window: thousands of
+	// occurrences precede it.
+	series := Series{
+		Start: time.Date(2020, time.January, 1, 17, 0, 0, 0, time.UTC),
+		End:   time.Date(2020, time.January, 1, 20, 0, 0, 0, time.UTC),
+		RRule: "FREQ=DAILY",
+	}
+	from := time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC)
+	got, err := series.Expand(time.UTC, from, from.AddDate(0, 0, 7))
+	if err != nil {
+		t.Fatalf("Expand: %v", err)
+	}
+	if len(got) != 7 {
+		t.Fatalf("got %d occurrences, want 7", len(got))
+	}
+	for i, occurrence := range got {
+		if want := from.AddDate(0, 0, i).Add(17 * time.Hour); !occurrence.Start.Equal(want) {
+			t.Errorf("occurrence %d starts %s, want %s", i, occurrence.Start, want)
+		}
+	}
+
+	// The cap applies from the start of the window.
+	got, err = series.Expand(time.UTC, from, from.AddDate(4, 0, 0))
+	if err != nil {
+		t.Fatalf("Expand: %v", err)
+	}
+	if len(got) != maxOccurrences || !got[0].Start.Equal(from.Add(17*time.Hour)) {
+		t.Fatalf("got %d occurrences from %s, want %d from the window start", len(got), got[0].Start, maxOccurrences)
+	}
+}
@@ -0,0 +1,59 @@
+package storage
+
+import "time"
+
+// HalfDay is the booking granularity: every reservation covers a whole number
+// of morning or afternoon slots.
+const HalfDay = 12 * time.Hour
+
+// HalfDaySlots returns the start of every half-day slot between from
+// (inclusive) and to (exclusive), stepping from the from boundary.
+func HalfDaySlots(from, to time.Time) []time.Time {
+	if !to.After(from) {
+		return nil
+	}
+	slots := make([]time.Time, 0, int(to.Sub(from)/HalfDay)+1)
+	for current := from; current.Before(to); current = current.Add(HalfDay) {
+		slots = append(slots, current)
+	}
+	return slots
+}
+
+// HalfDaysIn returns the local half-days, as LocalHalfDays, of the part of
+// [start, end) that falls within [from, to).
+func HalfDaysIn(start, end, from, to time.Time, loc *time.Location) []time.Time {
+	if start.Before(from) {
+		start = from
+	}
+	if end.After(to) {
+		end = to
+	}
+	return LocalHalfDays(start, end, loc)
+}
+
+// LocalHalfDays returns the start of every morning and afternoon, taken in
+// loc, that [start, end) overlaps. Unlike HalfDaySlots, it follows the
+// calendar across daylight saving changes, when a half-day lasts 11 or 13
+// hours.
+func LocalHalfDays(start, end time.Time, loc *time.Location) []time.Time {
+	if !end.After(start) {
+		return nil
+	}
+	local := start.In(loc)
+	var out []time.Time
+	for day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc); day.Before(end); day = day.AddDate(0, 0, 1) {
+		noon := time.Date(day.Year(), day.Month(), day.Day(), 12, 0, 0, 0, loc)
+		if Overlaps(start, end, day, noon) {
+			out = append(out, day)
+		}
+		if Overlaps(start, end, noon, day.AddDate(0, 0, 1)) {
+			out = append(out, noon)
+		}
+	}
+	return out
+}
+
+// Overlaps reports whether [start, end) intersects [from, to).
+func Overlaps(start, end, from, to time.Time) bool {
+	return start.Before(to) && end.After(from)
+}
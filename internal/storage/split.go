@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// SplitReservation cuts the reservation in two at the given instant, which
+// must fall strictly within it. The reservation keeps [start, at) and a new
+// one, copying its household, guests, comment, status, kind, rooms, approval
+// votes and RSVPs, takes [at, end). Both parts are recorded in the history. The
+// identifier of the new reservation is returned.
+func (s *Store) SplitReservation(ctx context.Context, id int64, at time.Time, member string) (int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	r, err := s.getReservationTx(ctx, tx, id)
+	if err != nil {
+		return 0, err
+	}
+	if !at.After(r.Start) || !at.Before(r.End) {
+		return 0, errors.New("split must fall within the reservation")
+	}
+
+	res, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO reservations (person, member, start, end, comment, adults, children, status, kind)
+		SELECT person, member, ?, end, comment, adults, children, status, kind FROM reservations WHERE id = ?`,
+		at.UTC().Format(time.RFC3339),
+		id,
+	)
+	if err != nil {
+		return 0, err
+	}
+	newID, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `INSERT INTO reservation_rooms (reservation_id, room) SELECT ?, room FROM reservation_rooms WHERE reservation_id = ?`, newID, id); err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO reservation_votes (reservation_id, household, member, approve, created_at) SELECT ?, household, member, approve, created_at FROM reservation_votes WHERE reservation_id = ?`, newID, id); err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO event_rsvps (reservation_id, household, member, answer, headcount, updated_at) SELECT ?, household, member, answer, headcount, updated_at FROM event_rsvps WHERE reservation_id = ?`, newID, id); err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE reservations SET end = ? WHERE id = ?`, at.UTC().Format(time.RFC3339), id); err != nil {
+		return 0, err
+	}
+
+	details := fmt.Sprintf("split at %s into %d and %d", at.UTC().Format(time.RFC3339), id, newID)
+	for _, reservation := range []int64{id, newID} {
+		if err := insertHistory(ctx, tx, reservation, HistorySplit, r.Person, member, details); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return newID, nil
+}
+
+// TrimReservation shortens the reservation to [start, end), which must lie
+// within its current dates and leave it non-empty. Shrinking frees slots
+// without claiming any, so no conflict can arise.
+func (s *Store) TrimReservation(ctx context.Context, id int64, start, end time.Time, member string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	r, err := s.getReservationTx(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	if !end.After(start) {
+		return errors.New("end must be after start")
+	}
+	if start.Before(r.Start) || end.After(r.End) {
+		return errors.New("trim must not extend the reservation")
+	}
+
+	if _, err := tx.ExecContext(
+		ctx,
+		`UPDATE reservations SET start = ?, end = ? WHERE id = ?`,
+		start.UTC().Format(time.RFC3339),
+		end.UTC().Format(time.RFC3339),
+		id,
+	); err != nil {
+		return err
+	}
+
+	details := fmt.Sprintf(
+		"trimmed from %s/%s to %s/%s",
+		r.Start.UTC().Format(time.RFC3339), r.End.UTC().Format(time.RFC3339),
+		start.UTC().Format(time.RFC3339), end.UTC().Format(time.RFC3339),
+	)
+	if err := insertHistory(ctx, tx, id, HistoryTrimmed, r.Person, member, details); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func insertHistory(ctx context.Context, tx *sql.Tx, reservationID int64, event, person, member, details string) error {
+	_, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO reservation_history (reservation_id, event, person, member, details, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		reservationID,
+		event,
+		person,
+		member,
+		details,
+		time.Now().UTC().Format(time.RFC3339),
+	)
+	return err
+}
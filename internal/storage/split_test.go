@@ -0,0 +1,141 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	store, err := New(filepath.Join(t.TempDir(), "test.db"), time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func day(d, hour int) time.Time {
+	return time.Date(2027, time.March, d, hour, 0, 0, 0, time.UTC)
+}
+
+func mustCreate(t *testing.T, store *Store, r Reservation) int64 {
+	t.Helper()
+	id, err := store.CreateReservation(context.Background(), r)
+	if err != nil {
+		t.Fatalf("CreateReservation: %v", err)
+	}
+	return id
+}
+
+func mustGet(t *testing.T, store *Store, id int64) Reservation {
+	t.Helper()
+	r, err := store.GetReservation(context.Background(), id)
+	if err != nil {
+		t.Fatalf("GetReservation(%d): %v", id, err)
+	}
+	return r
+}
+
+func historyEvents(t *testing.T, store *Store, id int64) []string {
+	t.Helper()
+	entries, err := store.ListHistory(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var events []string
+	for _, entry := range entries {
+		events = append(events, entry.Event)
+	}
+	return events
+}
+
+func TestSplitReservation(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	id := mustCreate(t, store, Reservation{
+		Person: "Manon", Start: day(1, 12), End: day(8, 12), Comment: "Vacances",
+		Adults: 2, Children: 1, Rooms: []string{"Bleue"}, Status: StatusTentative,
+	})
+	if err := store.RecordVote(ctx, id, Vote{Household: "Noel", Approve: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, at := range []time.Time{day(1, 12), day(8, 12), day(10, 0)} {
+		if _, err := store.SplitReservation(ctx, id, at, "Manon"); err == nil {
+			t.Errorf("split at %s succeeded, want an error", at)
+		}
+	}
+	if _, err := store.SplitReservation(ctx, 999, day(4, 12), "Manon"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("split of an unknown reservation: %v, want ErrNotFound", err)
+	}
+
+	newID, err := store.SplitReservation(ctx, id, day(4, 12), "Manon")
+	if err != nil {
+		t.Fatalf("SplitReservation: %v", err)
+	}
+	first, second := mustGet(t, store, id), mustGet(t, store, newID)
+	if !first.Start.Equal(day(1, 12)) || !first.End.Equal(day(4, 12)) {
+		t.Errorf("first part = [%s, %s), want [%s, %s)", first.Start, first.End, day(1, 12), day(4, 12))
+	}
+	if !second.Start.Equal(day(4, 12)) || !second.End.Equal(day(8, 12)) {
+		t.Errorf("second part = [%s, %s), want [%s, %s)", second.Start, second.End, day(4, 12), day(8, 12))
+	}
+	if second.Person != "Manon" || second.Comment != "Vacances" || second.Adults != 2 || second.Children != 1 ||
+		second.Status != StatusTentative || !slices.Equal(second.Rooms, []string{"Bleue"}) {
+		t.Errorf("second part = %+v, want a copy of the first", second)
+	}
+	votes, err := store.ListVotes(ctx, newID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(votes) != 1 || votes[0].Household != "Noel" || !votes[0].Approve {
+		t.Errorf("second part votes = %+v, want the approval of Noel", votes)
+	}
+	for _, part := range []int64{id, newID} {
+		if events := historyEvents(t, store, part); !slices.Equal(events, []string{HistorySplit}) {
+			t.Errorf("history of %d = %v, want a split", part, events)
+		}
+	}
+}
+
+func TestTrimReservation(t *testing.T) {
+	tests := []struct {
+		name       string
+		start, end time.Time
+		ok         bool
+	}{
+		{"shorter at both ends", day(2, 12), day(6, 0), true},
+		{"same dates", day(1, 12), day(8, 12), true},
+		{"earlier arrival", day(1, 0), day(6, 0), false},
+		{"later departure", day(2, 12), day(9, 12), false},
+		{"empty", day(3, 12), day(3, 12), false},
+		{"reversed", day(5, 12), day(3, 12), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			id := mustCreate(t, store, Reservation{Person: "Manon", Start: day(1, 12), End: day(8, 12)})
+
+			err := store.TrimReservation(context.Background(), id, tt.start, tt.end, "Manon")
+			if (err == nil) != tt.ok {
+				t.Fatalf("TrimReservation error = %v, want ok %v", err, tt.ok)
+			}
+			got := mustGet(t, store, id)
+			wantStart, wantEnd, wantHistory := day(1, 12), day(8, 12), []string(nil)
+			if tt.ok {
+				wantStart, wantEnd, wantHistory = tt.start, tt.end, []string{HistoryTrimmed}
+			}
+			if !got.Start.Equal(wantStart) || !got.End.Equal(wantEnd) {
+				t.Errorf("dates = [%s, %s), want [%s, %s)", got.Start, got.End, wantStart, wantEnd)
+			}
+			if events := historyEvents(t, store, id); !slices.Equal(events, wantHistory) {
+				t.Errorf("history = %v, want %v", events, wantHistory)
+			}
+		})
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -11,20 +13,88 @@ import (
 
 // Reservation represents a stored reservation record.
 type Reservation struct {
-	ID      int64     `json:"id"`
-	Person  string    `json:"person"`
-	Start   time.Time `json:"start"`
-	End     time.Time `json:"end"`
-	Comment string    `json:"comment"`
+	ID       int64     `json:"id"`
+	Person   string    `json:"person"`
+	Member   string    `json:"member,omitempty"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Comment  string    `json:"comment"`
+	Adults   int       `json:"adults"`
+	Children int       `json:"children"`
+	Rooms    []string  `json:"rooms,omitempty"`
+	Status   string    `json:"status"`
+	// Kind tells a stay from a family event other households answer to;
+	// Attendees is then the headcount of the households coming (RSVP yes),
+	// on top of the organiser's own guests.
+	Kind      string `json:"kind"`
+	Attendees int    `json:"attendees,omitempty"`
+	// Overrides are recorded along with a new reservation that breaks a
+	// rule on purpose; they are not loaded back when listing.
+	Overrides []Override `json:"overrides,omitempty"`
+	// SeriesID and Occurrence identify an occurrence expanded from a
+	// recurring series; such reservations have no ID of their own.
+	SeriesID   int64     `json:"series_id,omitempty"`
+	Occurrence time.Time `json:"occurrence,omitempty"`
+}
+
+// Reservation statuses. Tentative bookings await approval; declined ones are
+// kept for the record but no longer take the apartment.
+const (
+	StatusTentative = "tentative"
+	StatusConfirmed = "confirmed"
+	StatusDeclined  = "declined"
+)
+
+// Reservation kinds.
+const (
+	KindStay  = "stay"
+	KindEvent = "event"
+)
+
+// Active reports whether the reservation takes the apartment, i.e. it has not
+// been declined.
+func (r Reservation) Active() bool {
+	return r.Status != StatusDeclined
+}
+
+// Guests returns the number of people staying, children included. The
+// attendees of an event are not counted.
+func (r Reservation) Guests() int {
+	return r.Adults + r.Children
+}
+
+// Occupants returns the number of people present: the guests and, for an
+// event, its attendees.
+func (r Reservation) Occupants() int {
+	return r.Guests() + r.Attendees
+}
+
+// ErrNotFound is returned when the requested record does not exist.
+var ErrNotFound = errors.New("not found")
+
+// RoomConflictError reports that a room is already claimed by an overlapping
+// reservation.
+type RoomConflictError struct {
+	Room          string
+	ReservationID int64
+}
+
+func (e *RoomConflictError) Error() string {
+	return fmt.Sprintf("room %q is already booked by reservation %d", e.Room, e.ReservationID)
 }
 
 // Store provides persistence helpers backed by SQLite.
 type Store struct {
-	db *sql.DB
+	db       *sql.DB
+	loc      *time.Location
+	turnover time.Duration
+	// fts is set when SQLite provides FTS5 for the search index.
+	fts bool
 }
 
-// New initialises the SQLite database and returns a Store.
-func New(path string) (*Store, error) {
+// New initialises the SQLite database and returns a Store. Recurring series
+// are expanded in loc (time.Local when nil).
+func New(path string, loc *time.Location) (*Store, error) {
 	db, err := sql.Open("sqlite3", path+"?_foreign_keys=1&_busy_timeout=5000")
 	if err != nil {
 		return nil, err
@@ -36,8 +106,16 @@ func New(path string) (*Store, error) {
 		db.Close()
 		return nil, err
 	}
+	fts, err := initialiseSearch(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
 
-	return &Store{db: db}, nil
+	if loc == nil {
+		loc = time.Local
+	}
+	return &Store{db: db, loc: loc, fts: fts}, nil
 }
 
 // Close releases the underlying database handle.
@@ -48,9 +126,36 @@ func (s *Store) Close() error {
 	return s.db.Close()
 }
 
-// ListReservations returns every reservation ordered by start date.
+// ListReservations returns every reservation ordered by start date, including
+// the occurrences of recurring series up to SeriesHorizon from now.
 func (s *Store) ListReservations(ctx context.Context) ([]Reservation, error) {
-	rows, err := s.db.QueryContext(ctx, `SELECT id, person, start, end, comment FROM reservations ORDER BY start`)
+	res, err := s.queryReservations(ctx, `SELECT `+reservationColumns+` FROM reservations ORDER BY start`)
+	if err != nil {
+		return nil, err
+	}
+	return s.withOccurrences(ctx, res, time.Time{}, time.Now().Add(SeriesHorizon))
+}
+
+// ListReservationsBetween returns the reservations overlapping [from, to)
+// ordered by start date, including the occurrences of recurring series.
+func (s *Store) ListReservationsBetween(ctx context.Context, from, to time.Time) ([]Reservation, error) {
+	res, err := s.queryReservations(
+		ctx,
+		`SELECT `+reservationColumns+` FROM reservations WHERE start < ? AND end > ? ORDER BY start`,
+		to.UTC().Format(time.RFC3339),
+		from.UTC().Format(time.RFC3339),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return s.withOccurrences(ctx, res, from, to)
+}
+
+const reservationColumns = `id, person, member, start, end, comment, adults, children, status, kind,
+	(SELECT COALESCE(SUM(headcount), 0) FROM event_rsvps WHERE event_rsvps.reservation_id = reservations.id AND answer = '` + RSVPYes + `')`
+
+func (s *Store) queryReservations(ctx context.Context, query string, args ...any) ([]Reservation, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -59,13 +164,19 @@ func (s *Store) ListReservations(ctx context.Context) ([]Reservation, error) {
 	var res []Reservation
 	for rows.Next() {
 		var (
-			id      int64
-			person  string
-			start   string
-			end     string
-			comment sql.NullString
+			id        int64
+			person    string
+			member    sql.NullString
+			start     string
+			end       string
+			comment   sql.NullString
+			adults    int
+			children  int
+			status    string
+			kind      string
+			attendees int
 		)
-		if err := rows.Scan(&id, &person, &start, &end, &comment); err != nil {
+		if err := rows.Scan(&id, &person, &member, &start, &end, &comment, &adults, &children, &status, &kind, &attendees); err != nil {
 			return nil, err
 		}
 
@@ -79,11 +190,17 @@ func (s *Store) ListReservations(ctx context.Context) ([]Reservation, error) {
 		}
 
 		res = append(res, Reservation{
-			ID:      id,
-			Person:  person,
-			Start:   startTime,
-			End:     endTime,
-			Comment: comment.String,
+			ID:        id,
+			Person:    person,
+			Member:    member.String,
+			Start:     startTime,
+			End:       endTime,
+			Comment:   comment.String,
+			Adults:    adults,
+			Children:  children,
+			Status:    status,
+			Kind:      kind,
+			Attendees: attendees,
 		})
 	}
 
@@ -91,25 +208,113 @@ func (s *Store) ListReservations(ctx context.Context) ([]Reservation, error) {
 		return nil, err
 	}
 
+	if err := s.loadRooms(ctx, res); err != nil {
+		return nil, err
+	}
 	return res, nil
 }
 
-// CreateReservation persists a reservation and returns its identifier.
+// loadRooms fills the Rooms field of the provided reservations.
+func (s *Store) loadRooms(ctx context.Context, reservations []Reservation) error {
+	if len(reservations) == 0 {
+		return nil
+	}
+
+	index := make(map[int64]int, len(reservations))
+	placeholders := make([]string, 0, len(reservations))
+	args := make([]any, 0, len(reservations))
+	for i, res := range reservations {
+		index[res.ID] = i
+		placeholders = append(placeholders, "?")
+		args = append(args, res.ID)
+	}
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT reservation_id, room FROM reservation_rooms WHERE reservation_id IN (`+strings.Join(placeholders, ",")+`) ORDER BY room`,
+		args...,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			id   int64
+			room string
+		)
+		if err := rows.Scan(&id, &room); err != nil {
+			return err
+		}
+		if i, ok := index[id]; ok {
+			reservations[i].Rooms = append(reservations[i].Rooms, room)
+		}
+	}
+	return rows.Err()
+}
+
+// CreateReservation persists a reservation and returns its identifier. When
+// the reservation claims rooms, a *RoomConflictError is returned if one of
+// them is already claimed by an overlapping reservation. A
+// *TurnoverConflictError is returned if it falls within the turnover buffer
+// of another stay.
 func (s *Store) CreateReservation(ctx context.Context, r Reservation) (int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	id, err := s.insertReservation(ctx, tx, r)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// insertReservation validates r, checks its rooms are free and the turnover
+// buffers respected and inserts it within the transaction.
+func (s *Store) insertReservation(ctx context.Context, tx *sql.Tx, r Reservation) (int64, error) {
 	if r.Person == "" {
 		return 0, errors.New("person is required")
 	}
 	if !r.End.After(r.Start) {
 		return 0, errors.New("end must be after start")
 	}
+	if r.Adults < 0 || r.Children < 0 {
+		return 0, errors.New("guest counts must not be negative")
+	}
+	if r.Status == "" {
+		r.Status = StatusConfirmed
+	}
+	if r.Kind == "" {
+		r.Kind = KindStay
+	}
 
-	res, err := s.db.ExecContext(
+	if err := checkRoomConflicts(ctx, tx, r, 0); err != nil {
+		return 0, err
+	}
+	if err := s.checkTurnover(ctx, tx, r, 0); err != nil {
+		return 0, err
+	}
+
+	res, err := tx.ExecContext(
 		ctx,
-		`INSERT INTO reservations (person, start, end, comment) VALUES (?, ?, ?, ?)`,
+		`INSERT INTO reservations (person, member, start, end, comment, adults, children, status, kind) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		r.Person,
-		r.Start.Format(time.RFC3339),
-		r.End.Format(time.RFC3339),
+		r.Member,
+		r.Start.UTC().Format(time.RFC3339),
+		r.End.UTC().Format(time.RFC3339),
 		r.Comment,
+		r.Adults,
+		r.Children,
+		r.Status,
+		r.Kind,
 	)
 	if err != nil {
 		return 0, err
@@ -119,15 +324,131 @@ func (s *Store) CreateReservation(ctx context.Context, r Reservation) (int64, er
 	if err != nil {
 		return 0, err
 	}
+
+	if err := insertRooms(ctx, tx, id, r.Rooms); err != nil {
+		return 0, err
+	}
+	if err := insertOverrides(ctx, tx, id, r.Overrides); err != nil {
+		return 0, err
+	}
 	return id, nil
 }
 
+// checkRoomConflicts looks for reservations other than excludeID that overlap
+// r and claim one of its rooms.
+func checkRoomConflicts(ctx context.Context, tx *sql.Tx, r Reservation, excludeID int64) error {
+	if len(r.Rooms) == 0 {
+		return nil
+	}
+
+	placeholders := make([]string, 0, len(r.Rooms))
+	args := []any{r.End.UTC().Format(time.RFC3339), r.Start.UTC().Format(time.RFC3339), excludeID}
+	for _, room := range r.Rooms {
+		placeholders = append(placeholders, "?")
+		args = append(args, room)
+	}
+
+	var conflict RoomConflictError
+	err := tx.QueryRowContext(
+		ctx,
+		`SELECT rr.room, r.id FROM reservations r
+		JOIN reservation_rooms rr ON rr.reservation_id = r.id
+		WHERE r.start < ? AND r.end > ? AND r.id != ? AND r.status != '`+StatusDeclined+`' AND rr.room IN (`+strings.Join(placeholders, ",")+`)
+		ORDER BY r.start LIMIT 1`,
+		args...,
+	).Scan(&conflict.Room, &conflict.ReservationID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return &conflict
+}
+
+func insertRooms(ctx context.Context, tx *sql.Tx, id int64, rooms []string) error {
+	for _, room := range rooms {
+		if _, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO reservation_rooms (reservation_id, room) VALUES (?, ?)`, id, room); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetReservation returns the reservation matching the provided ID, or
+// ErrNotFound.
+func (s *Store) GetReservation(ctx context.Context, id int64) (Reservation, error) {
+	res, err := s.queryReservations(ctx, `SELECT `+reservationColumns+` FROM reservations WHERE id = ?`, id)
+	if err != nil {
+		return Reservation{}, err
+	}
+	if len(res) == 0 {
+		return Reservation{}, ErrNotFound
+	}
+	return res[0], nil
+}
+
 // DeleteReservation removes the reservation matching the provided ID.
 func (s *Store) DeleteReservation(ctx context.Context, id int64) error {
 	_, err := s.db.ExecContext(ctx, `DELETE FROM reservations WHERE id = ?`, id)
 	return err
 }
 
+// UpdateReservationDates moves the reservation to [start, end), sets its
+// status and records the overrides the change needed, in one transaction.
+// Votes cast on a reservation put back to tentative are discarded, as they
+// were about the previous dates. A *RoomConflictError is returned if one of
+// its rooms is claimed by another reservation over the new dates, a
+// *TurnoverConflictError if the new dates fall within the turnover buffer of
+// another stay.
+func (s *Store) UpdateReservationDates(ctx context.Context, id int64, start, end time.Time, status string, overrides []Override) error {
+	if !end.After(start) {
+		return errors.New("end must be after start")
+	}
+
+	r, err := s.GetReservation(ctx, id)
+	if err != nil {
+		return err
+	}
+	r.Start, r.End, r.Status = start, end, status
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := checkRoomConflicts(ctx, tx, r, id); err != nil {
+		return err
+	}
+	if err := s.checkTurnover(ctx, tx, r, id); err != nil {
+		return err
+	}
+	res, err := tx.ExecContext(
+		ctx,
+		`UPDATE reservations SET start = ?, end = ?, status = ? WHERE id = ?`,
+		start.UTC().Format(time.RFC3339),
+		end.UTC().Format(time.RFC3339),
+		status,
+		id,
+	)
+	if err != nil {
+		return err
+	}
+	if err := expectAffected(res); err != nil {
+		return err
+	}
+	if status == StatusTentative {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM reservation_votes WHERE reservation_id = ?`, id); err != nil {
+			return err
+		}
+	}
+	if err := insertOverrides(ctx, tx, id, overrides); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
 // UpdateReservationComment updates the comment attached to the reservation.
 func (s *Store) UpdateReservationComment(ctx context.Context, id int64, comment string) error {
 	_, err := s.db.ExecContext(ctx, `UPDATE reservations SET comment = ? WHERE id = ?`, comment, id)
@@ -141,18 +462,249 @@ func initialise(db *sql.DB) error {
 		person TEXT NOT NULL,
 		start TEXT NOT NULL,
 		end TEXT NOT NULL,
-		comment TEXT
+		comment TEXT,
+		member TEXT,
+		adults INTEGER NOT NULL DEFAULT 1,
+		children INTEGER NOT NULL DEFAULT 0,
+		status TEXT NOT NULL DEFAULT 'confirmed',
+		kind TEXT NOT NULL DEFAULT 'stay'
 	);
 	CREATE INDEX IF NOT EXISTS idx_reservations_range ON reservations(start, end);
+	CREATE TABLE IF NOT EXISTS reservation_rooms (
+		reservation_id INTEGER NOT NULL REFERENCES reservations(id) ON DELETE CASCADE,
+		room TEXT NOT NULL,
+		PRIMARY KEY (reservation_id, room)
+	);
+	CREATE INDEX IF NOT EXISTS idx_reservation_rooms_room ON reservation_rooms(room);
+	CREATE TABLE IF NOT EXISTS reservation_votes (
+		reservation_id INTEGER NOT NULL REFERENCES reservations(id) ON DELETE CASCADE,
+		household TEXT NOT NULL,
+		member TEXT,
+		approve INTEGER NOT NULL,
+		created_at TEXT NOT NULL,
+		PRIMARY KEY (reservation_id, household)
+	);
+	CREATE TABLE IF NOT EXISTS reservation_overrides (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		reservation_id INTEGER NOT NULL REFERENCES reservations(id) ON DELETE CASCADE,
+		rule TEXT NOT NULL,
+		reason TEXT NOT NULL,
+		member TEXT,
+		created_at TEXT NOT NULL
+	);
+	CREATE TABLE IF NOT EXISTS blackouts (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		start TEXT NOT NULL,
+		end TEXT NOT NULL,
+		reason TEXT NOT NULL,
+		created_by TEXT,
+		created_at TEXT NOT NULL
+	);
+	CREATE TABLE IF NOT EXISTS waitlist (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		person TEXT NOT NULL,
+		member TEXT,
+		start TEXT NOT NULL,
+		end TEXT NOT NULL,
+		comment TEXT,
+		adults INTEGER NOT NULL DEFAULT 1,
+		children INTEGER NOT NULL DEFAULT 0,
+		rooms TEXT NOT NULL DEFAULT '',
+		status TEXT NOT NULL DEFAULT 'waiting',
+		reservation_id INTEGER REFERENCES reservations(id) ON DELETE SET NULL,
+		override_reason TEXT NOT NULL DEFAULT '',
+		created_at TEXT NOT NULL
+	);
+	CREATE TABLE IF NOT EXISTS lottery_wishes (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		round TEXT NOT NULL,
+		person TEXT NOT NULL,
+		member TEXT,
+		rank INTEGER NOT NULL,
+		period TEXT NOT NULL,
+		created_at TEXT NOT NULL,
+		UNIQUE (round, person, rank)
+	);
+	CREATE TABLE IF NOT EXISTS lottery_draws (
+		round TEXT PRIMARY KEY,
+		method TEXT NOT NULL,
+		seed INTEGER NOT NULL,
+		priority TEXT NOT NULL,
+		drawn_by TEXT,
+		drawn_at TEXT NOT NULL
+	);
+	CREATE TABLE IF NOT EXISTS lottery_results (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		round TEXT NOT NULL REFERENCES lottery_draws(round) ON DELETE CASCADE,
+		period TEXT NOT NULL,
+		person TEXT NOT NULL,
+		rank INTEGER NOT NULL,
+		reservation_id INTEGER REFERENCES reservations(id) ON DELETE SET NULL
+	);
+	CREATE TABLE IF NOT EXISTS reservation_transfers (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		from_person TEXT NOT NULL,
+		to_person TEXT NOT NULL,
+		proposed_by TEXT,
+		offered_id INTEGER NOT NULL,
+		requested_id INTEGER,
+		message TEXT,
+		status TEXT NOT NULL DEFAULT 'pending',
+		created_at TEXT NOT NULL,
+		decided_by TEXT,
+		decided_at TEXT
+	);
+	CREATE TABLE IF NOT EXISTS reservation_history (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		reservation_id INTEGER NOT NULL REFERENCES reservations(id) ON DELETE CASCADE,
+		event TEXT NOT NULL,
+		person TEXT NOT NULL,
+		member TEXT,
+		details TEXT NOT NULL,
+		created_at TEXT NOT NULL
+	);
+	CREATE TABLE IF NOT EXISTS reservation_comments (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		reservation_id INTEGER NOT NULL REFERENCES reservations(id) ON DELETE CASCADE,
+		author TEXT NOT NULL,
+		person TEXT,
+		body TEXT NOT NULL,
+		created_at TEXT NOT NULL,
+		edited_at TEXT
+	);
+	CREATE INDEX IF NOT EXISTS idx_reservation_comments_reservation ON reservation_comments(reservation_id);
+	CREATE TABLE IF NOT EXISTS mentions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		reservation_id INTEGER NOT NULL REFERENCES reservations(id) ON DELETE CASCADE,
+		comment_id INTEGER REFERENCES reservation_comments(id) ON DELETE CASCADE,
+		name TEXT NOT NULL,
+		author TEXT NOT NULL,
+		created_at TEXT NOT NULL,
+		read_at TEXT
+	);
+	CREATE INDEX IF NOT EXISTS idx_mentions_name ON mentions(name);
+	CREATE TABLE IF NOT EXISTS attachments (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		reservation_id INTEGER NOT NULL REFERENCES reservations(id) ON DELETE CASCADE,
+		name TEXT NOT NULL,
+		content_type TEXT NOT NULL,
+		size INTEGER NOT NULL,
+		file TEXT NOT NULL,
+		thumbnail TEXT,
+		uploaded_by TEXT,
+		created_at TEXT NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_attachments_reservation ON attachments(reservation_id);
+	CREATE TABLE IF NOT EXISTS event_rsvps (
+		reservation_id INTEGER NOT NULL REFERENCES reservations(id) ON DELETE CASCADE,
+		household TEXT NOT NULL,
+		member TEXT NOT NULL,
+		answer TEXT NOT NULL,
+		headcount INTEGER NOT NULL DEFAULT 0,
+		updated_at TEXT NOT NULL,
+		PRIMARY KEY (reservation_id, household)
+	);
+	CREATE TABLE IF NOT EXISTS reservation_tasks (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		reservation_id INTEGER NOT NULL REFERENCES reservations(id) ON DELETE CASCADE,
+		phase TEXT NOT NULL,
+		title TEXT NOT NULL,
+		custom INTEGER NOT NULL DEFAULT 0,
+		done_by TEXT,
+		done_at TEXT,
+		created_at TEXT NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_reservation_tasks_reservation ON reservation_tasks(reservation_id);
+	CREATE TABLE IF NOT EXISTS meter_readings (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		reservation_id INTEGER NOT NULL REFERENCES reservations(id) ON DELETE CASCADE,
+		meter TEXT NOT NULL,
+		phase TEXT NOT NULL,
+		value REAL NOT NULL,
+		recorded_by TEXT,
+		recorded_at TEXT NOT NULL,
+		UNIQUE (reservation_id, meter, phase)
+	);
+	CREATE TABLE IF NOT EXISTS day_notes (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		start TEXT NOT NULL,
+		end TEXT NOT NULL,
+		text TEXT NOT NULL,
+		author TEXT NOT NULL,
+		person TEXT NOT NULL,
+		visibility TEXT NOT NULL DEFAULT 'all',
+		created_at TEXT NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_day_notes_start ON day_notes(start);
+	CREATE TABLE IF NOT EXISTS reservation_series (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		person TEXT NOT NULL,
+		member TEXT,
+		start TEXT NOT NULL,
+		end TEXT NOT NULL,
+		rrule TEXT NOT NULL,
+		comment TEXT,
+		adults INTEGER NOT NULL DEFAULT 1,
+		children INTEGER NOT NULL DEFAULT 0,
+		status TEXT NOT NULL DEFAULT 'confirmed'
+	);
+	CREATE TABLE IF NOT EXISTS series_exceptions (
+		series_id INTEGER NOT NULL REFERENCES reservation_series(id) ON DELETE CASCADE,
+		occurrence TEXT NOT NULL,
+		cancelled INTEGER NOT NULL DEFAULT 0,
+		start TEXT,
+		end TEXT,
+		comment TEXT,
+		PRIMARY KEY (series_id, occurrence)
+	);
+	CREATE TABLE IF NOT EXISTS series_votes (
+		series_id INTEGER NOT NULL REFERENCES reservation_series(id) ON DELETE CASCADE,
+		household TEXT NOT NULL,
+		member TEXT,
+		approve INTEGER NOT NULL,
+		created_at TEXT NOT NULL,
+		PRIMARY KEY (series_id, household)
+	);
+	CREATE TABLE IF NOT EXISTS series_overrides (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		series_id INTEGER NOT NULL REFERENCES reservation_series(id) ON DELETE CASCADE,
+		occurrence TEXT,
+		rule TEXT NOT NULL,
+		reason TEXT NOT NULL,
+		member TEXT,
+		created_at TEXT NOT NULL
+	);
 	`
 	if _, err := db.Exec(schema); err != nil {
 		return err
 	}
-	return ensureCommentColumn(db)
+
+	columns := []struct {
+		name       string
+		definition string
+	}{
+		{"comment", "TEXT"},
+		{"member", "TEXT"},
+		{"adults", "INTEGER NOT NULL DEFAULT 1"},
+		{"children", "INTEGER NOT NULL DEFAULT 0"},
+		{"status", "TEXT NOT NULL DEFAULT 'confirmed'"},
+		{"kind", "TEXT NOT NULL DEFAULT 'stay'"},
+	}
+	for _, column := range columns {
+		if err := ensureColumn(db, "reservations", column.name, column.definition); err != nil {
+			return err
+		}
+	}
+	if err := ensureColumn(db, "reservation_series", "status", "TEXT NOT NULL DEFAULT 'confirmed'"); err != nil {
+		return err
+	}
+	return ensureColumn(db, "waitlist", "override_reason", "TEXT NOT NULL DEFAULT ''")
 }
 
-func ensureCommentColumn(db *sql.DB) error {
-	rows, err := db.Query(`PRAGMA table_info(reservations)`)
+// ensureColumn adds the column to the table when a database created by an
+// older version lacks it.
+func ensureColumn(db *sql.DB, table, column, definition string) error {
+	rows, err := db.Query(`PRAGMA table_info(` + table + `)`)
 	if err != nil {
 		return err
 	}
@@ -170,7 +722,7 @@ func ensureCommentColumn(db *sql.DB) error {
 		if err := rows.Scan(&cid, &name, &typ, &notnull, &dfltValue, &primaryKey); err != nil {
 			return err
 		}
-		if name == "comment" {
+		if name == column {
 			return nil
 		}
 	}
@@ -178,6 +730,6 @@ func ensureCommentColumn(db *sql.DB) error {
 		return err
 	}
 
-	_, err = db.Exec(`ALTER TABLE reservations ADD COLUMN comment TEXT`)
+	_, err = db.Exec(`ALTER TABLE ` + table + ` ADD COLUMN ` + column + ` ` + definition)
 	return err
 }
@@ -0,0 +1,183 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Task phases.
+const (
+	PhaseArrival   = "arrival"
+	PhaseDeparture = "departure"
+)
+
+// TaskTemplate is an item of the checklist every stay goes through.
+type TaskTemplate struct {
+	Phase string `json:"phase"`
+	Title string `json:"title"`
+}
+
+// Task is a checklist item of a reservation, instantiated from a template or
+// added by the occupants (Custom), that they tick off.
+type Task struct {
+	ID            int64     `json:"id"`
+	ReservationID int64     `json:"reservation_id"`
+	Phase         string    `json:"phase"`
+	Title         string    `json:"title"`
+	Custom        bool      `json:"custom"`
+	Done          bool      `json:"done"`
+	DoneBy        string    `json:"done_by,omitempty"`
+	DoneAt        time.Time `json:"done_at,omitzero"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+const taskColumns = `id, reservation_id, phase, title, custom, done_by, done_at, created_at`
+
+// EnsureTasks instantiates the checklist templates for the reservation the
+// first time its tasks are needed, then returns its tasks, arrival first.
+func (s *Store) EnsureTasks(ctx context.Context, reservationID int64, templates []TaskTemplate) ([]Task, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var count int
+	if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM reservation_tasks WHERE reservation_id = ? AND custom = 0`, reservationID).Scan(&count); err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		now := time.Now().UTC().Format(time.RFC3339)
+		for _, template := range templates {
+			if _, err := tx.ExecContext(
+				ctx,
+				`INSERT INTO reservation_tasks (reservation_id, phase, title, custom, created_at) VALUES (?, ?, ?, 0, ?)`,
+				reservationID, template.Phase, template.Title, now,
+			); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	tasks, err := scanTasks(tx.QueryContext(ctx, `SELECT `+taskColumns+` FROM reservation_tasks WHERE reservation_id = ? ORDER BY phase = '`+PhaseDeparture+`', id`, reservationID))
+	if err != nil {
+		return nil, err
+	}
+	return tasks, tx.Commit()
+}
+
+// GetTask returns the task matching the provided ID, or ErrNotFound.
+func (s *Store) GetTask(ctx context.Context, id int64) (Task, error) {
+	tasks, err := scanTasks(s.db.QueryContext(ctx, `SELECT `+taskColumns+` FROM reservation_tasks WHERE id = ?`, id))
+	if err != nil {
+		return Task{}, err
+	}
+	if len(tasks) == 0 {
+		return Task{}, ErrNotFound
+	}
+	return tasks[0], nil
+}
+
+// CreateTask adds a custom task to the reservation and returns its
+// identifier.
+func (s *Store) CreateTask(ctx context.Context, t Task) (int64, error) {
+	if t.Title == "" {
+		return 0, errors.New("title is required")
+	}
+	if t.Phase != PhaseArrival && t.Phase != PhaseDeparture {
+		return 0, errors.New("invalid phase")
+	}
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = time.Now()
+	}
+
+	res, err := s.db.ExecContext(
+		ctx,
+		`INSERT INTO reservation_tasks (reservation_id, phase, title, custom, created_at) VALUES (?, ?, ?, 1, ?)`,
+		t.ReservationID,
+		t.Phase,
+		t.Title,
+		t.CreatedAt.UTC().Format(time.RFC3339),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// SetTaskDone ticks the task off on behalf of member, or opens it again.
+func (s *Store) SetTaskDone(ctx context.Context, id int64, done bool, member string) error {
+	var (
+		doneBy any
+		doneAt any
+	)
+	if done {
+		doneBy, doneAt = member, time.Now().UTC().Format(time.RFC3339)
+	}
+	res, err := s.db.ExecContext(ctx, `UPDATE reservation_tasks SET done_by = ?, done_at = ? WHERE id = ?`, doneBy, doneAt, id)
+	if err != nil {
+		return err
+	}
+	return expectAffected(res)
+}
+
+// DeleteTask removes the task matching the provided ID.
+func (s *Store) DeleteTask(ctx context.Context, id int64) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM reservation_tasks WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	return expectAffected(res)
+}
+
+// PreviousReservation returns the last stay that ended by start, other than
+// excludeID and declined ones, or ErrNotFound.
+func (s *Store) PreviousReservation(ctx context.Context, start time.Time, excludeID int64) (Reservation, error) {
+	res, err := s.queryReservations(
+		ctx,
+		`SELECT `+reservationColumns+` FROM reservations WHERE end <= ? AND id != ? AND status != '`+StatusDeclined+`' ORDER BY end DESC, id DESC LIMIT 1`,
+		start.UTC().Format(time.RFC3339),
+		excludeID,
+	)
+	if err != nil {
+		return Reservation{}, err
+	}
+	if len(res) == 0 {
+		return Reservation{}, ErrNotFound
+	}
+	return res[0], nil
+}
+
+func scanTasks(rows *sql.Rows, err error) ([]Task, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Task{}
+	for rows.Next() {
+		var (
+			t         Task
+			doneBy    sql.NullString
+			doneAt    sql.NullString
+			createdAt string
+		)
+		if err := rows.Scan(&t.ID, &t.ReservationID, &t.Phase, &t.Title, &t.Custom, &doneBy, &doneAt, &createdAt); err != nil {
+			return nil, err
+		}
+		if doneAt.Valid {
+			t.Done = true
+			t.DoneBy = doneBy.String
+			if t.DoneAt, err = time.Parse(time.RFC3339, doneAt.String); err != nil {
+				return nil, err
+			}
+		}
+		if t.CreatedAt, err = time.Parse(time.RFC3339, createdAt); err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,295 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Transfer statuses. A pending proposal is accepted or declined by the
+// receiving household, or cancelled by the proposing one; it is cancelled as
+// well when another transfer of the same reservations is accepted first.
+const (
+	TransferPending   = "pending"
+	TransferAccepted  = "accepted"
+	TransferDeclined  = "declined"
+	TransferCancelled = "cancelled"
+)
+
+// ErrTransferStale is returned when accepting a transfer whose reservations
+// changed hands or were removed since it was proposed.
+var ErrTransferStale = errors.New("reservations changed since the proposal")
+
+// Transfer is a proposal to give a reservation to another household, or to
+// exchange it for one of theirs when RequestedID is set.
+type Transfer struct {
+	ID          int64     `json:"id"`
+	FromPerson  string    `json:"from_person"`
+	ToPerson    string    `json:"to_person"`
+	ProposedBy  string    `json:"proposed_by,omitempty"`
+	OfferedID   int64     `json:"offered_id"`
+	RequestedID int64     `json:"requested_id,omitempty"`
+	Message     string    `json:"message"`
+	Status      string    `json:"status"`
+	CreatedAt   time.Time `json:"created_at"`
+	DecidedBy   string    `json:"decided_by,omitempty"`
+	DecidedAt   time.Time `json:"decided_at,omitempty"`
+}
+
+// HistoryEntry records a change in the life of a reservation.
+type HistoryEntry struct {
+	ReservationID int64     `json:"reservation_id"`
+	Event         string    `json:"event"`
+	Person        string    `json:"person"`
+	Member        string    `json:"member,omitempty"`
+	Details       string    `json:"details"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// History events.
+const (
+	HistoryTransferred = "transferred"
+	HistorySplit       = "split"
+	HistoryTrimmed     = "trimmed"
+	HistoryRSVP        = "rsvp"
+)
+
+const transferColumns = `id, from_person, to_person, proposed_by, offered_id, requested_id, message, status, created_at, decided_by, decided_at`
+
+// ListTransfers returns every transfer, most recent first.
+func (s *Store) ListTransfers(ctx context.Context) ([]Transfer, error) {
+	return scanTransfers(s.db.QueryContext(ctx, `SELECT `+transferColumns+` FROM reservation_transfers ORDER BY created_at DESC, id DESC`))
+}
+
+// GetTransfer returns the transfer matching the provided ID, or ErrNotFound.
+func (s *Store) GetTransfer(ctx context.Context, id int64) (Transfer, error) {
+	transfers, err := scanTransfers(s.db.QueryContext(ctx, `SELECT `+transferColumns+` FROM reservation_transfers WHERE id = ?`, id))
+	if err != nil {
+		return Transfer{}, err
+	}
+	if len(transfers) == 0 {
+		return Transfer{}, ErrNotFound
+	}
+	return transfers[0], nil
+}
+
+// CreateTransfer records a pending proposal.
+func (s *Store) CreateTransfer(ctx context.Context, t Transfer) (int64, error) {
+	if t.FromPerson == "" || t.ToPerson == "" || t.FromPerson == t.ToPerson {
+		return 0, errors.New("two different households are required")
+	}
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = time.Now()
+	}
+
+	var requested any
+	if t.RequestedID != 0 {
+		requested = t.RequestedID
+	}
+	res, err := s.db.ExecContext(
+		ctx,
+		`INSERT INTO reservation_transfers (from_person, to_person, proposed_by, offered_id, requested_id, message, status, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		t.FromPerson,
+		t.ToPerson,
+		t.ProposedBy,
+		t.OfferedID,
+		requested,
+		t.Message,
+		TransferPending,
+		t.CreatedAt.UTC().Format(time.RFC3339),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// SettleTransfer closes a pending transfer with the given status, decided by
+// member. Accepting it hands the offered reservation to the receiving
+// household and, for an exchange, the requested one to the proposing
+// household; ownership changes are recorded in the history and the other
+// pending transfers of these reservations are cancelled, all in one
+// transaction. The overrides the change needed are recorded per reservation.
+// ErrNotFound is returned when the transfer is not pending.
+func (s *Store) SettleTransfer(ctx context.Context, id int64, status, member string, overrides map[int64][]Override) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	transfers, err := scanTransfers(tx.QueryContext(ctx, `SELECT `+transferColumns+` FROM reservation_transfers WHERE id = ? AND status = ?`, id, TransferPending))
+	if err != nil {
+		return err
+	}
+	if len(transfers) == 0 {
+		return ErrNotFound
+	}
+	t := transfers[0]
+	now := time.Now().UTC().Format(time.RFC3339)
+
+	if status == TransferAccepted {
+		type ownership struct {
+			reservation int64
+			from, to    string
+			member      string
+		}
+		moves := []ownership{{t.OfferedID, t.FromPerson, t.ToPerson, member}}
+		if t.RequestedID != 0 {
+			moves = append(moves, ownership{t.RequestedID, t.ToPerson, t.FromPerson, t.ProposedBy})
+		}
+
+		for _, move := range moves {
+			res, err := tx.ExecContext(
+				ctx,
+				`UPDATE reservations SET person = ?, member = ? WHERE id = ? AND person = ? AND status != ?`,
+				move.to, move.member, move.reservation, move.from, StatusDeclined,
+			)
+			if err != nil {
+				return err
+			}
+			if err := expectAffected(res); errors.Is(err, ErrNotFound) {
+				return ErrTransferStale
+			} else if err != nil {
+				return err
+			}
+			details := fmt.Sprintf("transfer %d: from %s to %s", t.ID, move.from, move.to)
+			if err := insertHistory(ctx, tx, move.reservation, HistoryTransferred, move.to, member, details); err != nil {
+				return err
+			}
+			if err := insertOverrides(ctx, tx, move.reservation, overrides[move.reservation]); err != nil {
+				return err
+			}
+		}
+
+		for _, move := range moves {
+			moved, err := s.getReservationTx(ctx, tx, move.reservation)
+			if err != nil {
+				return err
+			}
+			if err := s.checkTurnover(ctx, tx, moved, move.reservation); err != nil {
+				return err
+			}
+		}
+
+		if _, err := tx.ExecContext(
+			ctx,
+			`UPDATE reservation_transfers SET status = ?, decided_at = ? WHERE id != ? AND status = ? AND (offered_id IN (?, ?) OR requested_id IN (?, ?))`,
+			TransferCancelled, now, t.ID, TransferPending, t.OfferedID, t.RequestedID, t.OfferedID, t.RequestedID,
+		); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.ExecContext(
+		ctx,
+		`UPDATE reservation_transfers SET status = ?, decided_by = ?, decided_at = ? WHERE id = ?`,
+		status, member, now, t.ID,
+	); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// getReservationTx loads the reservation and its rooms within the
+// transaction.
+func (s *Store) getReservationTx(ctx context.Context, tx *sql.Tx, id int64) (Reservation, error) {
+	var (
+		r     Reservation
+		start string
+		end   string
+	)
+	err := tx.QueryRowContext(ctx, `SELECT id, person, start, end, status FROM reservations WHERE id = ?`, id).Scan(&r.ID, &r.Person, &start, &end, &r.Status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Reservation{}, ErrNotFound
+	}
+	if err != nil {
+		return Reservation{}, err
+	}
+	if r.Start, err = time.Parse(time.RFC3339, start); err != nil {
+		return Reservation{}, err
+	}
+	if r.End, err = time.Parse(time.RFC3339, end); err != nil {
+		return Reservation{}, err
+	}
+
+	rows, err := tx.QueryContext(ctx, `SELECT room FROM reservation_rooms WHERE reservation_id = ? ORDER BY room`, id)
+	if err != nil {
+		return Reservation{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var room string
+		if err := rows.Scan(&room); err != nil {
+			return Reservation{}, err
+		}
+		r.Rooms = append(r.Rooms, room)
+	}
+	return r, rows.Err()
+}
+
+// ListHistory returns the history of the reservation, oldest first.
+func (s *Store) ListHistory(ctx context.Context, reservationID int64) ([]HistoryEntry, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT reservation_id, event, person, member, details, created_at FROM reservation_history WHERE reservation_id = ? ORDER BY id`, reservationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []HistoryEntry{}
+	for rows.Next() {
+		var (
+			entry     HistoryEntry
+			member    sql.NullString
+			createdAt string
+		)
+		if err := rows.Scan(&entry.ReservationID, &entry.Event, &entry.Person, &member, &entry.Details, &createdAt); err != nil {
+			return nil, err
+		}
+		entry.Member = member.String
+		if entry.CreatedAt, err = time.Parse(time.RFC3339, createdAt); err != nil {
+			return nil, err
+		}
+		out = append(out, entry)
+	}
+	return out, rows.Err()
+}
+
+func scanTransfers(rows *sql.Rows, err error) ([]Transfer, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Transfer
+	for rows.Next() {
+		var (
+			t           Transfer
+			proposedBy  sql.NullString
+			requestedID sql.NullInt64
+			message     sql.NullString
+			createdAt   string
+			decidedBy   sql.NullString
+			decidedAt   sql.NullString
+		)
+		if err := rows.Scan(&t.ID, &t.FromPerson, &t.ToPerson, &proposedBy, &t.OfferedID, &requestedID, &message, &t.Status, &createdAt, &decidedBy, &decidedAt); err != nil {
+			return nil, err
+		}
+		if t.CreatedAt, err = time.Parse(time.RFC3339, createdAt); err != nil {
+			return nil, err
+		}
+		if decidedAt.Valid {
+			if t.DecidedAt, err = time.Parse(time.RFC3339, decidedAt.String); err != nil {
+				return nil, err
+			}
+		}
+		t.ProposedBy = proposedBy.String
+		t.RequestedID = requestedID.Int64
+		t.Message = message.String
+		t.DecidedBy = decidedBy.String
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,146 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func mustPropose(t *testing.T, store *Store, tr Transfer) int64 {
+	t.Helper()
+	id, err := store.CreateTransfer(context.Background(), tr)
+	if err != nil {
+		t.Fatalf("CreateTransfer: %v", err)
+	}
+	return id
+}
+
+func transferStatus(t *testing.T, store *Store, id int64) string {
+	t.Helper()
+	tr, err := store.GetTransfer(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tr.Status
+}
+
+func TestSettleTransferGift(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	offered := mustCreate(t, store, Reservation{Person: "Manon", Start: day(1, 12), End: day(5, 12)})
+	id := mustPropose(t, store, Transfer{FromPerson: "Manon", ToPerson: "Noel", ProposedBy: "Manon", OfferedID: offered})
+	other := mustPropose(t, store, Transfer{FromPerson: "Manon", ToPerson: "Lucie", ProposedBy: "Manon", OfferedID: offered})
+
+	overrides := map[int64][]Override{offered: {{Rule: "quota:Noel:2027:nights", Reason: "accord familial", Member: "Noel"}}}
+	if err := store.SettleTransfer(ctx, id, TransferAccepted, "Noel", overrides); err != nil {
+		t.Fatalf("SettleTransfer: %v", err)
+	}
+
+	if got := mustGet(t, store, offered).Person; got != "Noel" {
+		t.Errorf("offered reservation belongs to %s, want Noel", got)
+	}
+	if got := transferStatus(t, store, id); got != TransferAccepted {
+		t.Errorf("transfer status = %s, want accepted", got)
+	}
+	if got := transferStatus(t, store, other); got != TransferCancelled {
+		t.Errorf("competing transfer status = %s, want cancelled", got)
+	}
+	if events := historyEvents(t, store, offered); !slices.Equal(events, []string{HistoryTransferred}) {
+		t.Errorf("history = %v, want a transfer", events)
+	}
+	recorded, err := store.ListOverrides(ctx, offered)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recorded) != 1 || recorded[0].Rule != "quota:Noel:2027:nights" {
+		t.Errorf("overrides = %+v, want the quota override", recorded)
+	}
+
+	if err := store.SettleTransfer(ctx, id, TransferDeclined, "Noel", nil); !errors.Is(err, ErrNotFound) {
+		t.Errorf("settling a closed transfer: %v, want ErrNotFound", err)
+	}
+}
+
+func TestSettleTransferExchange(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	offered := mustCreate(t, store, Reservation{Person: "Manon", Start: day(1, 12), End: day(5, 12)})
+	requested := mustCreate(t, store, Reservation{Person: "Noel", Start: day(10, 12), End: day(15, 12)})
+	id := mustPropose(t, store, Transfer{FromPerson: "Manon", ToPerson: "Noel", ProposedBy: "Manon", OfferedID: offered, RequestedID: requested})
+
+	if err := store.SettleTransfer(ctx, id, TransferAccepted, "Noel", nil); err != nil {
+		t.Fatalf("SettleTransfer: %v", err)
+	}
+	if got := mustGet(t, store, offered).Person; got != "Noel" {
+		t.Errorf("offered reservation belongs to %s, want Noel", got)
+	}
+	if got := mustGet(t, store, requested).Person; got != "Manon" {
+		t.Errorf("requested reservation belongs to %s, want Manon", got)
+	}
+}
+
+// A failing acceptance must leave everything as it was.
+func TestSettleTransferRollback(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(t *testing.T, store *Store) (transfer int64, reservations []int64)
+		err   func(error) bool
+	}{
+		{
+			name: "requested reservation declined since",
+			setup: func(t *testing.T, store *Store) (int64, []int64) {
+				offered := mustCreate(t, store, Reservation{Person: "Manon", Start: day(1, 12), End: day(5, 12)})
+				requested := mustCreate(t, store, Reservation{Person: "Noel", Start: day(10, 12), End: day(15, 12)})
+				id := mustPropose(t, store, Transfer{FromPerson: "Manon", ToPerson: "Noel", OfferedID: offered, RequestedID: requested})
+				if err := store.SetReservationStatus(context.Background(), requested, StatusDeclined); err != nil {
+					t.Fatal(err)
+				}
+				return id, []int64{offered, requested}
+			},
+			err: func(err error) bool { return errors.Is(err, ErrTransferStale) },
+		},
+		{
+			name: "turnover buffer broken",
+			setup: func(t *testing.T, store *Store) (int64, []int64) {
+				store.SetTurnover(24 * time.Hour)
+				// Manon may chain her own stays, Noel may not follow her at once.
+				first := mustCreate(t, store, Reservation{Person: "Manon", Start: day(1, 12), End: day(5, 12)})
+				second := mustCreate(t, store, Reservation{Person: "Manon", Start: day(5, 12), End: day(7, 12)})
+				id := mustPropose(t, store, Transfer{FromPerson: "Manon", ToPerson: "Noel", OfferedID: second})
+				return id, []int64{first, second}
+			},
+			err: func(err error) bool {
+				var conflict *TurnoverConflictError
+				return errors.As(err, &conflict)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newTestStore(t)
+			id, reservations := tt.setup(t, store)
+			var owners []string
+			for _, res := range reservations {
+				owners = append(owners, mustGet(t, store, res).Person)
+			}
+
+			err := store.SettleTransfer(context.Background(), id, TransferAccepted, "Noel", nil)
+			if !tt.err(err) {
+				t.Fatalf("SettleTransfer error = %v", err)
+			}
+			for i, res := range reservations {
+				if got := mustGet(t, store, res).Person; got != owners[i] {
+					t.Errorf("reservation %d belongs to %s, want %s", res, got, owners[i])
+				}
+				if events := historyEvents(t, store, res); len(events) != 0 {
+					t.Errorf("reservation %d history = %v, want none", res, events)
+				}
+			}
+			if got := transferStatus(t, store, id); got != TransferPending {
+				t.Errorf("transfer status = %s, want pending", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TurnoverConflictError reports that a reservation starts or ends within the
+// turnover buffer kept after the departure of another household. SeriesID is
+// set instead of ReservationID when the other stay is an occurrence.
+type TurnoverConflictError struct {
+	ReservationID int64
+	SeriesID      int64
+}
+
+func (e *TurnoverConflictError) Error() string {
+	if e.SeriesID != 0 {
+		return fmt.Sprintf("within the turnover buffer of series %d", e.SeriesID)
+	}
+	return fmt.Sprintf("within the turnover buffer of reservation %d", e.ReservationID)
+}
+
+// SetTurnover sets the buffer kept free after each departure for cleaning;
+// zero disables it.
+func (s *Store) SetTurnover(buffer time.Duration) {
+	s.turnover = buffer
+}
+
+// Turnover returns the buffer kept free after each departure.
+func (s *Store) Turnover() time.Duration {
+	return s.turnover
+}
+
+// RoomsIntersect reports whether two reservations claim a common room. A
+// reservation without rooms takes the whole apartment.
+func RoomsIntersect(a, b []string) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return true
+	}
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// InTurnover reports whether a and b are consecutive active stays of
+// different households sharing a room, one starting less than buffer after
+// the other ends.
+func InTurnover(a, b Reservation, buffer time.Duration) bool {
+	if buffer <= 0 || a.Person == b.Person || !a.Active() || !b.Active() || !RoomsIntersect(a.Rooms, b.Rooms) {
+		return false
+	}
+	if !b.End.After(a.Start) {
+		return b.End.Add(buffer).After(a.Start)
+	}
+	if !a.End.After(b.Start) {
+		return a.End.Add(buffer).After(b.Start)
+	}
+	return false
+}
+
+// checkTurnover looks for a reservation other than excludeID, or an
+// occurrence of a series other than r's, whose turnover buffer r would not
+// respect.
+func (s *Store) checkTurnover(ctx context.Context, tx *sql.Tx, r Reservation, excludeID int64) error {
+	if s.turnover <= 0 || !r.Active() {
+		return nil
+	}
+
+	rows, err := tx.QueryContext(
+		ctx,
+		`SELECT r.id, r.person, r.start, r.end, COALESCE(GROUP_CONCAT(rr.room), '') FROM reservations r
+		LEFT JOIN reservation_rooms rr ON rr.reservation_id = r.id
+		WHERE r.start < ? AND r.end > ? AND r.id != ? AND r.status != ?
+		GROUP BY r.id ORDER BY r.start`,
+		r.End.Add(s.turnover).UTC().Format(time.RFC3339),
+		r.Start.Add(-s.turnover).UTC().Format(time.RFC3339),
+		excludeID,
+		StatusDeclined,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			other Reservation
+			start string
+			end   string
+			rooms string
+		)
+		if err := rows.Scan(&other.ID, &other.Person, &start, &end, &rooms); err != nil {
+			return err
+		}
+		if other.Start, err = time.Parse(time.RFC3339, start); err != nil {
+			return err
+		}
+		if other.End, err = time.Parse(time.RFC3339, end); err != nil {
+			return err
+		}
+		if rooms != "" {
+			other.Rooms = strings.Split(rooms, ",")
+		}
+		if InTurnover(r, other, s.turnover) {
+			return &TurnoverConflictError{ReservationID: other.ID}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	series, err := listSeries(ctx, tx)
+	if err != nil {
+		return err
+	}
+	for _, item := range series {
+		if r.SeriesID != 0 && item.ID == r.SeriesID {
+			continue
+		}
+		occurrences, err := item.Expand(s.loc, r.Start.Add(-s.turnover), r.End.Add(s.turnover))
+		if err != nil {
+			continue
+		}
+		for _, other := range occurrences {
+			if InTurnover(r, other, s.turnover) {
+				return &TurnoverConflictError{SeriesID: item.ID}
+			}
+		}
+	}
+	return nil
+}
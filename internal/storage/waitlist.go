@@ -0,0 +1,248 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Waitlist entry statuses. A waiting entry is offered the slot once it frees
+// up: a tentative reservation holds it until the household accepts or
+// declines the offer.
+const (
+	WaitlistWaiting  = "waiting"
+	WaitlistOffered  = "offered"
+	WaitlistAccepted = "accepted"
+	WaitlistDeclined = "declined"
+)
+
+// WaitlistEntry is a request for dates that were already booked.
+type WaitlistEntry struct {
+	ID            int64     `json:"id"`
+	Person        string    `json:"person"`
+	Member        string    `json:"member,omitempty"`
+	Start         time.Time `json:"start"`
+	End           time.Time `json:"end"`
+	Comment       string    `json:"comment"`
+	Adults        int       `json:"adults"`
+	Children      int       `json:"children"`
+	Rooms         []string  `json:"rooms"`
+	Status        string    `json:"status"`
+	ReservationID int64     `json:"reservation_id,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	// OverrideReason is the reason given on joining for breaking a booking
+	// rule or a quota; it is applied again when the offer is accepted.
+	OverrideReason string `json:"-"`
+}
+
+// Reservation returns the booking the entry asks for.
+func (e WaitlistEntry) Reservation() Reservation {
+	return Reservation{
+		Person:   e.Person,
+		Member:   e.Member,
+		Start:    e.Start,
+		End:      e.End,
+		Comment:  e.Comment,
+		Adults:   e.Adults,
+		Children: e.Children,
+		Rooms:    e.Rooms,
+	}
+}
+
+const waitlistColumns = `id, person, member, start, end, comment, adults, children, rooms, status, reservation_id, created_at, override_reason`
+
+// ListWaitlist returns the waitlist entries in the order they were made.
+func (s *Store) ListWaitlist(ctx context.Context) ([]WaitlistEntry, error) {
+	return s.queryWaitlist(ctx, `SELECT `+waitlistColumns+` FROM waitlist ORDER BY created_at, id`)
+}
+
+// GetWaitlistEntry returns the entry matching the provided ID, or ErrNotFound.
+func (s *Store) GetWaitlistEntry(ctx context.Context, id int64) (WaitlistEntry, error) {
+	entries, err := s.queryWaitlist(ctx, `SELECT `+waitlistColumns+` FROM waitlist WHERE id = ?`, id)
+	if err != nil {
+		return WaitlistEntry{}, err
+	}
+	if len(entries) == 0 {
+		return WaitlistEntry{}, ErrNotFound
+	}
+	return entries[0], nil
+}
+
+// WaitlistOffer returns the offered entry holding the reservation, or
+// ErrNotFound when the reservation is not a pending offer.
+func (s *Store) WaitlistOffer(ctx context.Context, reservationID int64) (WaitlistEntry, error) {
+	entries, err := s.queryWaitlist(ctx, `SELECT `+waitlistColumns+` FROM waitlist WHERE reservation_id = ? AND status = ?`, reservationID, WaitlistOffered)
+	if err != nil {
+		return WaitlistEntry{}, err
+	}
+	if len(entries) == 0 {
+		return WaitlistEntry{}, ErrNotFound
+	}
+	return entries[0], nil
+}
+
+// CreateWaitlistEntry adds an entry at the end of the waitlist.
+func (s *Store) CreateWaitlistEntry(ctx context.Context, e WaitlistEntry) (int64, error) {
+	if e.Person == "" {
+		return 0, errors.New("person is required")
+	}
+	if !e.End.After(e.Start) {
+		return 0, errors.New("end must be after start")
+	}
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now()
+	}
+
+	res, err := s.db.ExecContext(
+		ctx,
+		`INSERT INTO waitlist (person, member, start, end, comment, adults, children, rooms, status, created_at, override_reason) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		e.Person,
+		e.Member,
+		e.Start.UTC().Format(time.RFC3339),
+		e.End.UTC().Format(time.RFC3339),
+		e.Comment,
+		e.Adults,
+		e.Children,
+		strings.Join(e.Rooms, ","),
+		WaitlistWaiting,
+		e.CreatedAt.UTC().Format(time.RFC3339),
+		e.OverrideReason,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// DeleteWaitlistEntry removes the entry.
+func (s *Store) DeleteWaitlistEntry(ctx context.Context, id int64) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM waitlist WHERE id = ?`, id)
+	return err
+}
+
+// OfferWaitlistEntry turns a waiting entry into a tentative reservation
+// holding the slot and marks the entry as offered, in one transaction. It
+// returns the ID of the reservation.
+func (s *Store) OfferWaitlistEntry(ctx context.Context, id int64) (int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	entries, err := scanWaitlist(tx.QueryContext(ctx, `SELECT `+waitlistColumns+` FROM waitlist WHERE id = ? AND status = ?`, id, WaitlistWaiting))
+	if err != nil {
+		return 0, err
+	}
+	if len(entries) == 0 {
+		return 0, ErrNotFound
+	}
+
+	res := entries[0].Reservation()
+	res.Status = StatusTentative
+	reservationID, err := s.insertReservation(ctx, tx, res)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE waitlist SET status = ?, reservation_id = ? WHERE id = ?`, WaitlistOffered, reservationID, id); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return reservationID, nil
+}
+
+// SettleWaitlistOffer closes an offered entry. When accepted, the held
+// reservation takes the provided status and records the overrides it needed;
+// otherwise it is removed.
+func (s *Store) SettleWaitlistOffer(ctx context.Context, id int64, accept bool, status string, overrides []Override) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var reservationID sql.NullInt64
+	err = tx.QueryRowContext(ctx, `SELECT reservation_id FROM waitlist WHERE id = ? AND status = ?`, id, WaitlistOffered).Scan(&reservationID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	entryStatus := WaitlistDeclined
+	if accept {
+		if !reservationID.Valid {
+			// The held reservation was deleted meanwhile.
+			return ErrNotFound
+		}
+		entryStatus = WaitlistAccepted
+		if _, err := tx.ExecContext(ctx, `UPDATE reservations SET status = ? WHERE id = ?`, status, reservationID.Int64); err != nil {
+			return err
+		}
+		if err := insertOverrides(ctx, tx, reservationID.Int64, overrides); err != nil {
+			return err
+		}
+	} else if reservationID.Valid {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM reservations WHERE id = ?`, reservationID.Int64); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE waitlist SET status = ? WHERE id = ?`, entryStatus, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *Store) queryWaitlist(ctx context.Context, query string, args ...any) ([]WaitlistEntry, error) {
+	return scanWaitlist(s.db.QueryContext(ctx, query, args...))
+}
+
+func scanWaitlist(rows *sql.Rows, err error) ([]WaitlistEntry, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []WaitlistEntry
+	for rows.Next() {
+		var (
+			entry         WaitlistEntry
+			member        sql.NullString
+			start         string
+			end           string
+			comment       sql.NullString
+			rooms         string
+			reservationID sql.NullInt64
+			createdAt     string
+		)
+		if err := rows.Scan(&entry.ID, &entry.Person, &member, &start, &end, &comment, &entry.Adults, &entry.Children, &rooms, &entry.Status, &reservationID, &createdAt, &entry.OverrideReason); err != nil {
+			return nil, err
+		}
+
+		if entry.Start, err = time.Parse(time.RFC3339, start); err != nil {
+			return nil, err
+		}
+		if entry.End, err = time.Parse(time.RFC3339, end); err != nil {
+			return nil, err
+		}
+		if entry.CreatedAt, err = time.Parse(time.RFC3339, createdAt); err != nil {
+			return nil, err
+		}
+		entry.Member = member.String
+		entry.Comment = comment.String
+		entry.ReservationID = reservationID.Int64
+		entry.Rooms = []string{}
+		if rooms != "" {
+			entry.Rooms = strings.Split(rooms, ",")
+		}
+		out = append(out, entry)
+	}
+	return out, rows.Err()
+}
@@ -2,15 +2,14 @@ package main
 
 import (
 	"embed"
-	"encoding/json"
-	"errors"
 	"html/template"
 	"io/fs"
 	"log"
 	"net/http"
 	"os"
 	"path/filepath"
-	"strings"
+	"time"
+	_ "time/tzdata"
 
 	"AppartmentBooker/internal/server"
 	"AppartmentBooker/internal/storage"
@@ -24,18 +23,6 @@ var staticFS embed.FS
 
 func main() {
 	cfg := loadConfig("config.json")
-	people := assignColours(cfg.People)
-	authCfg := loadAuthConfig("auth.json")
-
-	if err := os.MkdirAll("data", 0o755); err != nil {
-		log.Fatalf("unable to ensure data directory: %v", err)
-	}
-
-	store, err := storage.New(filepath.Join("data", "reservations.db"))
-	if err != nil {
-		log.Fatalf("failed to initialise storage: %v", err)
-	}
-	defer store.Close()
 
 	tpl, err := template.ParseFS(templateFS, "templates/*.html")
 	if err != nil {
@@ -48,146 +35,100 @@ func main() {
 	}
 	staticHandler := http.StripPrefix("/static/", http.FileServer(http.FS(staticContent)))
 
-	srv := server.New(store, tpl, staticHandler, people, cfg.PageTitle, cfg.BannerTitle, cfg.BasePath, authCfg.Password, authCfg.Hint)
+	properties := make([]*server.Server, 0, len(cfg.Properties))
+	for _, prop := range cfg.Properties {
+		srv, store := openProperty(prop, tpl, staticHandler)
+		defer store.Close()
+		properties = append(properties, srv)
+	}
+
+	handler := properties[0].Routes()
+	if len(properties) > 1 {
+		handler = server.NewSite(tpl, cfg.PageTitle, properties).Routes()
+	}
 
 	addr := ":64512"
 	log.Printf("Service lance sur http://localhost%s", addr)
-	if err := http.ListenAndServe(addr, srv.Routes()); err != nil {
+	if err := http.ListenAndServe(addr, handler); err != nil {
 		log.Fatalf("server stopped: %v", err)
 	}
 }
 
-func assignColours(names []string) []server.Person {
-	palette := []string{
-		"#800000",
-		"#3cb44b",
-		"#ffe119",
-		"#0082c8",
-		"#f58231",
-		"#911eb4",
-		"#46f0f0",
-		"#f032e6",
-		"#d2f53c",
-		"#fabebe",
-		"#008080",
-		"#e6beff",
-		"#aa6e28",
-		"#fffac8",
-		"#aaffc3",
-		"#808000",
-		"#ffd8b1",
-		"#000080",
-		"#808080",
-	}
-
-	people := make([]server.Person, 0, len(names))
-	for _, raw := range names {
-		name := strings.TrimSpace(raw)
-		if name == "" {
-			continue
+// openProperty opens the database of the property and builds its server.
+func openProperty(prop propertyConfig, tpl *template.Template, staticHandler http.Handler) (*server.Server, *storage.Store) {
+	people := buildPeople(prop.People)
+	rooms := buildRooms(prop.Rooms)
+	capacity := prop.Capacity
+	if capacity == 0 {
+		for _, room := range rooms {
+			capacity += room.Beds
 		}
-		color := palette[len(people)%len(palette)]
-		people = append(people, server.Person{
-			Name:  name,
-			Color: color,
-		})
 	}
-	return people
-}
+	authCfg := loadAuthConfig(prop.Auth)
+	admins := buildAdmins(prop.Admins, people)
+	approvalMode, approvalPeriods := buildApproval(prop.Approval, admins)
+	quotas, quotaPolicy, highSeason := buildQuotas(prop.Quotas, people)
 
-type appConfig struct {
-	People      []string `json:"people"`
-	PageTitle   string   `json:"page_title"`
-	BannerTitle string   `json:"banner_title"`
-	BasePath    string   `json:"base_path"`
-}
-
-type authConfig struct {
-	Password string `json:"password"`
-	Hint     string `json:"hint"`
-}
-
-func loadConfig(path string) appConfig {
-	cfg := defaultConfig()
-
-	data, err := os.ReadFile(path)
+	location, err := time.LoadLocation(prop.Timezone)
 	if err != nil {
-		if !errors.Is(err, os.ErrNotExist) {
-			log.Printf("warning: unable to read config %q: %v (using defaults)", path, err)
-		}
-		return cfg
+		log.Printf("warning: property %q: unknown timezone %q (using local time)", prop.ID, prop.Timezone)
+		location = time.Local
 	}
+	lotteryRounds, lotteryMethod := buildLottery(prop.Lottery, location)
+	attachmentMaxBytes, attachmentTypes := buildAttachments(prop.Attachments)
 
-	if err := json.Unmarshal(data, &cfg); err != nil {
-		log.Printf("warning: unable to parse config %q: %v (using defaults)", path, err)
-		return defaultConfig()
+	if err := os.MkdirAll(filepath.Dir(prop.Database), 0o755); err != nil {
+		log.Fatalf("unable to ensure data directory: %v", err)
+	}
+	if err := os.MkdirAll(prop.AttachmentsDir, 0o750); err != nil {
+		log.Fatalf("unable to ensure attachments directory: %v", err)
 	}
 
-	applyConfigDefaults(&cfg)
-	cfg.BasePath = sanitiseBasePath(cfg.BasePath)
-	return cfg
-}
-
-func loadAuthConfig(path string) authConfig {
-	data, err := os.ReadFile(path)
+	store, err := storage.New(prop.Database, location)
 	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			log.Fatalf("auth configuration %q introuvable", path)
+		log.Fatalf("failed to initialise storage %q: %v", prop.Database, err)
+	}
+	cleaningBlocks := false
+	if prop.Turnover != nil {
+		if prop.Turnover.HalfDays < 0 {
+			log.Printf("warning: property %q: negative turnover buffer (ignored)", prop.ID)
+		} else {
+			store.SetTurnover(time.Duration(prop.Turnover.HalfDays) * storage.HalfDay)
+			cleaningBlocks = prop.Turnover.CleaningBlocks
 		}
-		log.Fatalf("lecture de la configuration auth %q impossible: %v", path, err)
-	}
-
-	var cfg authConfig
-	if err := json.Unmarshal(data, &cfg); err != nil {
-		log.Fatalf("analyse de la configuration auth %q impossible: %v", path, err)
 	}
 
-	cfg.Password = strings.TrimSpace(cfg.Password)
-	cfg.Hint = strings.TrimSpace(cfg.Hint)
-	if cfg.Password == "" {
-		log.Fatalf("la configuration auth %q doit contenir un mot de passe non vide", path)
-	}
-
-	return cfg
-}
-
-func defaultConfig() appConfig {
-	return appConfig{
-		People: []string{
-			"Annabelle",
-			"Florence",
-			"Gregoire",
-			"Manon",
-			"Valentin",
-			"Yves",
-		},
-		PageTitle:   "Reservations appartement",
-		BannerTitle: "Planning des 18 prochains mois",
-		BasePath:    "",
-	}
-}
-
-func applyConfigDefaults(cfg *appConfig) {
-	def := defaultConfig()
-	if len(cfg.People) == 0 {
-		cfg.People = def.People
-	}
-	if cfg.PageTitle == "" {
-		cfg.PageTitle = def.PageTitle
-	}
-	if cfg.BannerTitle == "" {
-		cfg.BannerTitle = def.BannerTitle
-	}
-}
-
-func sanitiseBasePath(value string) string {
-	trimmed := strings.TrimSpace(value)
-	if trimmed == "" || trimmed == "/" {
-		return ""
-	}
-	if !strings.HasPrefix(trimmed, "/") {
-		trimmed = "/" + trimmed
-	}
-	trimmed = strings.TrimRight(trimmed, "/")
-	return trimmed
+	srv := server.New(store, tpl, staticHandler, server.Config{
+		ID:                 prop.ID,
+		Name:               prop.Name,
+		People:             people,
+		PageTitle:          prop.PageTitle,
+		BannerTitle:        prop.BannerTitle,
+		BasePath:           prop.BasePath,
+		Password:           authCfg.Password,
+		PasswordHint:       authCfg.Hint,
+		MemberPasswords:    authCfg.Members,
+		Capacity:           capacity,
+		CapacityPolicy:     prop.CapacityPolicy,
+		Rooms:              rooms,
+		Location:           location,
+		Admins:             admins,
+		ApprovalMode:       approvalMode,
+		ApprovalPeriods:    approvalPeriods,
+		Notifier:           buildNotifier(prop.SMTP, authCfg.SMTPPassword),
+		Quotas:             quotas,
+		QuotaPolicy:        quotaPolicy,
+		HighSeason:         highSeason,
+		SchoolHolidays:     buildHolidays(prop.SchoolHolidays),
+		Rules:              buildRules(prop.Rules),
+		Lottery:            lotteryRounds,
+		LotteryMethod:      lotteryMethod,
+		CleaningBlocks:     cleaningBlocks,
+		AttachmentsDir:     prop.AttachmentsDir,
+		AttachmentMaxBytes: attachmentMaxBytes,
+		AttachmentTypes:    attachmentTypes,
+		Checklist:          buildChecklist(prop.Checklist),
+		MeterPrices:        buildMeterPrices(prop.MeterPrices),
+	})
+	return srv, store
 }
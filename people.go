@@ -0,0 +1,461 @@
+package main
+
+import (
+	"hash/fnv"
+	"log"
+	"strings"
+	"time"
+
+	"AppartmentBooker/internal/lottery"
+	"AppartmentBooker/internal/media"
+	"AppartmentBooker/internal/notify"
+	"AppartmentBooker/internal/report"
+	"AppartmentBooker/internal/rules"
+	"AppartmentBooker/internal/season"
+	"AppartmentBooker/internal/server"
+	"AppartmentBooker/internal/storage"
+)
+
+// notificationChannels lists the channels a member may subscribe to.
+var notificationChannels = map[string]bool{
+	notify.ChannelEmail:   true,
+	notify.ChannelWebhook: true,
+}
+
+// buildPeople turns the configured households into server people. A household
+// declared without members gets a single member bearing its own name, so that
+// everybody can log in individually.
+func buildPeople(entries []personConfig) []server.Person {
+	people := make([]server.Person, 0, len(entries))
+	seen := make(map[string]bool, len(entries))
+	memberSeen := make(map[string]string)
+	for _, entry := range entries {
+		name := strings.TrimSpace(entry.Name)
+		if name == "" {
+			continue
+		}
+		if seen[name] {
+			log.Printf("warning: person %q is declared more than once (ignored)", name)
+			continue
+		}
+		seen[name] = true
+
+		person := server.Person{
+			Name:  name,
+			Color: personColour(name, entry.Color),
+			Short: strings.TrimSpace(entry.Short),
+			Email: strings.TrimSpace(entry.Email),
+		}
+
+		memberEntries := entry.Members
+		if len(memberEntries) == 0 {
+			memberEntries = []memberConfig{{Name: name, Email: person.Email}}
+		}
+		for _, raw := range memberEntries {
+			member := buildMember(raw)
+			if member.Name == "" {
+				continue
+			}
+			if other, ok := memberSeen[member.Name]; ok {
+				log.Printf("warning: member %q of %q already belongs to %q (ignored)", member.Name, name, other)
+				continue
+			}
+			memberSeen[member.Name] = name
+			person.Members = append(person.Members, member)
+		}
+
+		people = append(people, person)
+	}
+	return people
+}
+
+func buildMember(entry memberConfig) server.Member {
+	member := server.Member{
+		Name:    strings.TrimSpace(entry.Name),
+		Email:   strings.TrimSpace(entry.Email),
+		Webhook: strings.TrimSpace(entry.Webhook),
+	}
+
+	channels := entry.Notify
+	if channels == nil && member.Email != "" {
+		channels = []string{"email"}
+	}
+	for _, channel := range channels {
+		channel = strings.ToLower(strings.TrimSpace(channel))
+		if !notificationChannels[channel] {
+			log.Printf("warning: member %q: unknown notification channel %q (ignored)", member.Name, channel)
+			continue
+		}
+		member.Notify = append(member.Notify, channel)
+	}
+	return member
+}
+
+func buildRooms(entries []roomConfig) []server.Room {
+	rooms := make([]server.Room, 0, len(entries))
+	seen := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		id := strings.TrimSpace(entry.ID)
+		if id == "" {
+			log.Printf("warning: room without id (ignored)")
+			continue
+		}
+		if seen[id] {
+			log.Printf("warning: room %q is declared more than once (ignored)", id)
+			continue
+		}
+		seen[id] = true
+
+		name := strings.TrimSpace(entry.Name)
+		if name == "" {
+			name = id
+		}
+		beds := entry.Beds
+		if beds < 0 {
+			log.Printf("warning: room %q: negative bed count (using 0)", id)
+			beds = 0
+		}
+		rooms = append(rooms, server.Room{ID: id, Name: name, Beds: beds})
+	}
+	return rooms
+}
+
+// buildAdmins keeps the configured admins that are known members.
+func buildAdmins(names []string, people []server.Person) []string {
+	known := make(map[string]bool)
+	for _, person := range people {
+		for _, member := range person.Members {
+			known[member.Name] = true
+		}
+	}
+
+	var admins []string
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if !known[name] {
+			log.Printf("warning: admin %q is not a known member (ignored)", name)
+			continue
+		}
+		admins = append(admins, name)
+	}
+	return admins
+}
+
+// buildApproval parses the approval settings. Without admins, the admin mode
+// falls back to the majority of the households so bookings can still be
+// approved.
+func buildApproval(cfg *approvalConfig, admins []string) (string, []season.Period) {
+	if cfg == nil {
+		return "", nil
+	}
+
+	mode := strings.ToLower(strings.TrimSpace(cfg.Mode))
+	switch mode {
+	case "", "admin", "majority":
+	default:
+		log.Printf("warning: unknown approval mode %q (using \"admin\")", cfg.Mode)
+		mode = ""
+	}
+	if mode == "" || mode == "admin" {
+		mode = "admin"
+		if len(admins) == 0 {
+			log.Printf("warning: approval mode \"admin\" without admins (using \"majority\")")
+			mode = "majority"
+		}
+	}
+
+	return mode, buildPeriods("approval", cfg.Periods)
+}
+
+// buildPeriods parses yearly periods, skipping invalid ones.
+func buildPeriods(setting string, entries []periodConfig) []season.Period {
+	periods := make([]season.Period, 0, len(entries))
+	for _, entry := range entries {
+		period, err := season.Parse(strings.TrimSpace(entry.Name), entry.From, entry.To)
+		if err != nil {
+			log.Printf("warning: %s period %q: %v (ignored)", setting, entry.Name, err)
+			continue
+		}
+		periods = append(periods, period)
+	}
+	return periods
+}
+
+// buildNotifier returns the notifier of the property. Webhooks work without
+// any setting; e-mails need the SMTP server.
+func buildNotifier(cfg *smtpConfig, password string) *notify.Notifier {
+	if cfg == nil {
+		return notify.New(notify.SMTPConfig{})
+	}
+	return notify.New(notify.SMTPConfig{
+		Host:     strings.TrimSpace(cfg.Host),
+		Port:     cfg.Port,
+		Username: strings.TrimSpace(cfg.Username),
+		Password: password,
+		From:     strings.TrimSpace(cfg.From),
+	})
+}
+
+// buildQuotas resolves the quota of every household from the defaults and
+// the per-household overrides.
+func buildQuotas(cfg *quotasConfig, people []server.Person) (map[string]server.Quota, string, []season.Period) {
+	if cfg == nil {
+		return nil, "warn", nil
+	}
+
+	policy := strings.ToLower(strings.TrimSpace(cfg.Policy))
+	switch policy {
+	case "":
+		policy = "warn"
+	case "warn", "reject":
+	default:
+		log.Printf("warning: unknown quota policy %q (using \"warn\")", cfg.Policy)
+		policy = "warn"
+	}
+
+	known := make(map[string]bool, len(people))
+	for _, person := range people {
+		known[person.Name] = true
+	}
+	for name := range cfg.Households {
+		if !known[name] {
+			log.Printf("warning: quotas for unknown person %q (ignored)", name)
+		}
+	}
+
+	quotas := make(map[string]server.Quota, len(people))
+	for _, person := range people {
+		limits := cfg.quotaConfig
+		if own, ok := cfg.Households[person.Name]; ok {
+			if own.NightsPerYear != nil {
+				limits.NightsPerYear = own.NightsPerYear
+			}
+			if own.HighSeasonWeeks != nil {
+				limits.HighSeasonWeeks = own.HighSeasonWeeks
+			}
+		}
+
+		var quota server.Quota
+		if limits.NightsPerYear != nil && *limits.NightsPerYear > 0 {
+			quota.Nights = *limits.NightsPerYear
+		}
+		if limits.HighSeasonWeeks != nil && *limits.HighSeasonWeeks > 0 {
+			quota.HighSeasonWeeks = *limits.HighSeasonWeeks
+		}
+		quotas[person.Name] = quota
+	}
+
+	highSeason := buildPeriods("high season", cfg.HighSeason)
+	if len(highSeason) == 0 {
+		for name, quota := range quotas {
+			if quota.HighSeasonWeeks > 0 {
+				log.Printf("warning: %q has a high season quota but no high_season period is configured", name)
+				break
+			}
+		}
+	}
+	return quotas, policy, highSeason
+}
+
+// buildHolidays parses the school holidays, skipping invalid ones.
+func buildHolidays(entries []periodConfig) []report.Holiday {
+	holidays := make([]report.Holiday, 0, len(entries))
+	for _, entry := range entries {
+		from, errFrom := time.Parse("2006-01-02", strings.TrimSpace(entry.From))
+		to, errTo := time.Parse("2006-01-02", strings.TrimSpace(entry.To))
+		if errFrom != nil || errTo != nil || to.Before(from) {
+			log.Printf("warning: school holiday %q: invalid dates (expected YYYY-MM-DD, ignored)", entry.Name)
+			continue
+		}
+		holidays = append(holidays, report.Holiday{Name: strings.TrimSpace(entry.Name), From: from, To: to})
+	}
+	return holidays
+}
+
+// buildRules parses the booking rules. Invalid periods discard their rule;
+// unknown weekdays are skipped.
+func buildRules(entries []ruleConfig) []rules.Rule {
+	out := make([]rules.Rule, 0, len(entries))
+	for _, entry := range entries {
+		rule := rules.Rule{
+			Name:            strings.TrimSpace(entry.Name),
+			MinNights:       entry.MinNights,
+			MaxAdvanceDays:  entry.MaxAdvanceDays,
+			NoSingleHalfDay: entry.NoSingleHalfDay,
+		}
+		if entry.From != "" || entry.To != "" {
+			period, err := season.Parse(rule.Name, entry.From, entry.To)
+			if err != nil {
+				log.Printf("warning: rule %q: %v (ignored)", entry.Name, err)
+				continue
+			}
+			rule.Period = &period
+		}
+		for _, value := range entry.ChangeoverDays {
+			day, err := rules.ParseWeekday(value)
+			if err != nil {
+				log.Printf("warning: rule %q: %v (ignored)", entry.Name, err)
+				continue
+			}
+			rule.Changeover = append(rule.Changeover, day)
+		}
+		out = append(out, rule)
+	}
+	return out
+}
+
+// buildLottery parses the allocation rounds, skipping invalid ones. Without
+// an explicit seed, a round is seeded from its ID so that its draw can be
+// reproduced.
+func buildLottery(cfg *lotteryConfig, loc *time.Location) ([]server.LotteryRound, string) {
+	if cfg == nil {
+		return nil, lottery.MethodLottery
+	}
+
+	method := strings.ToLower(strings.TrimSpace(cfg.Method))
+	switch method {
+	case lottery.MethodLottery, lottery.MethodRotation:
+	case "":
+		method = lottery.MethodLottery
+	default:
+		log.Printf("warning: unknown lottery method %q (using %q)", cfg.Method, lottery.MethodLottery)
+		method = lottery.MethodLottery
+	}
+
+	date := func(value string) (time.Time, error) {
+		return time.ParseInLocation("2006-01-02", strings.TrimSpace(value), loc)
+	}
+	noon := func(day time.Time) time.Time {
+		return time.Date(day.Year(), day.Month(), day.Day(), 12, 0, 0, 0, loc)
+	}
+
+	rounds := make([]server.LotteryRound, 0, len(cfg.Rounds))
+	seen := make(map[string]bool)
+	for _, entry := range cfg.Rounds {
+		id := strings.TrimSpace(entry.ID)
+		if id == "" || seen[id] {
+			log.Printf("warning: lottery round %q: missing or duplicate id (ignored)", entry.Name)
+			continue
+		}
+		opens, errOpens := date(entry.Opens)
+		closes, errCloses := date(entry.Closes)
+		if errOpens != nil || errCloses != nil || closes.Before(opens) {
+			log.Printf("warning: lottery round %q: invalid window (expected YYYY-MM-DD, ignored)", id)
+			continue
+		}
+
+		round := server.LotteryRound{
+			ID:              id,
+			Name:            strings.TrimSpace(entry.Name),
+			Opens:           opens,
+			Closes:          closes.AddDate(0, 0, 1),
+			MaxPerHousehold: entry.MaxPerHousehold,
+		}
+		if round.Name == "" {
+			round.Name = id
+		}
+		if entry.Seed != nil {
+			round.Seed = *entry.Seed
+		} else {
+			hash := fnv.New64a()
+			hash.Write([]byte(id))
+			round.Seed = int64(hash.Sum64())
+		}
+
+		names := make(map[string]bool)
+		for _, period := range entry.Periods {
+			from, errFrom := date(period.From)
+			to, errTo := date(period.To)
+			name := strings.TrimSpace(period.Name)
+			if errFrom != nil || errTo != nil || !to.After(from) || name == "" || names[name] {
+				log.Printf("warning: lottery round %q: invalid period %q (ignored)", id, period.Name)
+				continue
+			}
+			candidate := server.LotteryPeriod{Name: name, Start: noon(from), End: noon(to)}
+			if other, ok := round.Overlap(candidate); ok {
+				log.Printf("warning: lottery round %q: period %q overlaps %q (ignored)", id, name, other.Name)
+				continue
+			}
+			names[name] = true
+			round.Periods = append(round.Periods, candidate)
+		}
+
+		seen[id] = true
+		rounds = append(rounds, round)
+	}
+	return rounds, method
+}
+
+// defaultAttachmentMB is the default size limit of an attachment.
+const defaultAttachmentMB = 10
+
+// buildAttachments returns the size limit of attachments, in bytes, and the
+// content types accepted. Types that cannot be handled are skipped.
+func buildAttachments(cfg *attachmentsConfig) (int64, []string) {
+	maxMB := defaultAttachmentMB
+	types := media.DefaultTypes
+	if cfg == nil {
+		return int64(maxMB) << 20, types
+	}
+
+	if cfg.MaxMB > 0 {
+		maxMB = cfg.MaxMB
+	}
+	if cfg.Types != nil {
+		types = nil
+		for _, value := range cfg.Types {
+			value = strings.ToLower(strings.TrimSpace(value))
+			if !media.Supported(value) {
+				log.Printf("warning: unsupported attachment type %q (ignored)", value)
+				continue
+			}
+			types = append(types, value)
+		}
+	}
+	return int64(maxMB) << 20, types
+}
+
+// buildChecklist turns the checklist configuration into task templates,
+// arrival first. Blank items are skipped.
+func buildChecklist(cfg *checklistConfig) []storage.TaskTemplate {
+	if cfg == nil {
+		return nil
+	}
+
+	var templates []storage.TaskTemplate
+	for _, phase := range []struct {
+		name  string
+		items []string
+	}{
+		{storage.PhaseArrival, cfg.Arrival},
+		{storage.PhaseDeparture, cfg.Departure},
+	} {
+		for _, item := range phase.items {
+			if item = strings.TrimSpace(item); item != "" {
+				templates = append(templates, storage.TaskTemplate{Phase: phase.name, Title: item})
+			}
+		}
+	}
+	return templates
+}
+
+// buildMeterPrices keeps the unit prices of the known meters; a meter without
+// price costs nothing.
+func buildMeterPrices(cfg map[string]float64) map[string]float64 {
+	prices := make(map[string]float64, len(storage.Meters))
+	for meter, price := range cfg {
+		known := false
+		for _, name := range storage.Meters {
+			known = known || name == meter
+		}
+		switch {
+		case !known:
+			log.Printf("warning: unknown meter %q in meter_prices (ignored)", meter)
+		case price < 0:
+			log.Printf("warning: negative price for meter %q (ignored)", meter)
+		default:
+			prices[meter] = price
+		}
+	}
+	return prices
+}
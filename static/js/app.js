@@ -4,6 +4,11 @@
     const CONFIG = window.APP_CONFIG || {};
     const BASE_PATH = normaliseBasePath(CONFIG.basePath || '');
     const PEOPLE_CONFIG = Array.isArray(CONFIG.people) ? CONFIG.people : [];
+    const ROOMS_CONFIG = Array.isArray(CONFIG.rooms) ? CONFIG.rooms : [];
+    const CURRENT_HOUSEHOLD = typeof CONFIG.household === 'string' ? CONFIG.household : '';
+    const CURRENT_MEMBER = typeof CONFIG.member === 'string' ? CONFIG.member : '';
+    const IS_ADMIN = CONFIG.admin === true;
+    const APPROVAL_MODE = typeof CONFIG.approvalMode === 'string' ? CONFIG.approvalMode : '';
 
     const HALF_DAY_MS = 12 * 60 * 60 * 1000;
     const MONTH_COUNT = 18;
@@ -27,10 +32,15 @@
     const state = {
         people: [],
         peopleMap: new Map(),
+        roomsMap: new Map(),
         calendarStart: null,
         slotElements: new Map(),
         indexToSlotKey: [],
         reservations: [],
+        blackouts: [],
+        notes: [],
+        transfers: [],
+        editingCommentId: null,
         pendingRange: null,
         pendingDeleteId: null,
     };
@@ -59,12 +69,60 @@
         elements.createConfirm = document.getElementById('create-confirm');
         elements.createCancel = document.getElementById('create-cancel');
         elements.createComment = document.getElementById('create-comment');
+        elements.createAdults = document.getElementById('create-adults');
+        elements.createChildren = document.getElementById('create-children');
+        elements.createRoomsWrapper = document.getElementById('create-rooms-wrapper');
+        elements.createRooms = document.getElementById('create-rooms');
+        elements.createWaitlist = document.getElementById('create-waitlist');
+        elements.createBlackout = document.getElementById('create-blackout');
+        elements.createNote = document.getElementById('create-note');
+        elements.createNoteWrapper = document.getElementById('create-note-wrapper');
+        elements.createNoteVisibility = document.getElementById('create-note-visibility');
+        elements.createOverrideWrapper = document.getElementById('create-override-wrapper');
+        elements.createOverride = document.getElementById('create-override');
         elements.deleteModal = document.getElementById('delete-modal');
         elements.deleteDescription = document.getElementById('delete-description');
         elements.deleteConfirm = document.getElementById('delete-confirm');
         elements.deleteCancel = document.getElementById('delete-cancel');
         elements.deleteComment = document.getElementById('delete-comment');
         elements.deleteSave = document.getElementById('delete-save');
+        elements.deleteSeries = document.getElementById('delete-series');
+        elements.deleteApprove = document.getElementById('delete-approve');
+        elements.deleteDecline = document.getElementById('delete-decline');
+        elements.offerAccept = document.getElementById('offer-accept');
+        elements.offerDecline = document.getElementById('offer-decline');
+        elements.transferWrapper = document.getElementById('transfer-wrapper');
+        elements.transferSelect = document.getElementById('transfer-select');
+        elements.transferIncoming = document.getElementById('transfer-incoming');
+        elements.transferPropose = document.getElementById('transfer-propose');
+        elements.transferAccept = document.getElementById('transfer-accept');
+        elements.transferDecline = document.getElementById('transfer-decline');
+        elements.commentsWrapper = document.getElementById('comments-wrapper');
+        elements.commentsList = document.getElementById('comments-list');
+        elements.commentNew = document.getElementById('comment-new');
+        elements.commentPost = document.getElementById('comment-post');
+        elements.attachmentsWrapper = document.getElementById('attachments-wrapper');
+        elements.attachmentsList = document.getElementById('attachments-list');
+        elements.attachmentFile = document.getElementById('attachment-file');
+        elements.attachmentUpload = document.getElementById('attachment-upload');
+        elements.tasksWrapper = document.getElementById('tasks-wrapper');
+        elements.tasksPrevious = document.getElementById('tasks-previous');
+        elements.tasksList = document.getElementById('tasks-list');
+        elements.taskNew = document.getElementById('task-new');
+        elements.taskAdd = document.getElementById('task-add');
+        elements.metersWrapper = document.getElementById('meters-wrapper');
+        elements.metersBody = document.getElementById('meters-body');
+        elements.metersCost = document.getElementById('meters-cost');
+        elements.metersSave = document.getElementById('meters-save');
+        elements.createEvent = document.getElementById('create-event');
+        elements.createEventWrapper = document.getElementById('create-event-wrapper');
+        elements.rsvpWrapper = document.getElementById('rsvp-wrapper');
+        elements.rsvpSummary = document.getElementById('rsvp-summary');
+        elements.rsvpList = document.getElementById('rsvp-list');
+        elements.rsvpForm = document.getElementById('rsvp-form');
+        elements.rsvpAnswer = document.getElementById('rsvp-answer');
+        elements.rsvpHeadcount = document.getElementById('rsvp-headcount');
+        elements.rsvpSend = document.getElementById('rsvp-send');
         elements.confirmModal = document.getElementById('confirm-modal');
         elements.confirmMessage = document.getElementById('confirm-message');
         elements.confirmBack = document.getElementById('confirm-back');
@@ -74,8 +132,11 @@
         state.people = PEOPLE_CONFIG;
         state.peopleMap = new Map(state.people.map((person) => [person.name, person.color]));
 
+        state.roomsMap = new Map(ROOMS_CONFIG.map((room) => [room.id, room]));
+
         initLegend();
         initPersonSelect();
+        initRoomChoices();
         buildCalendar();
         attachGlobalListeners();
         refreshPersonSelectColor();
@@ -112,13 +173,46 @@
             elements.personSelect.appendChild(option);
         });
 
-        if (state.people.length > 0) {
+        if (CURRENT_HOUSEHOLD && state.peopleMap.has(CURRENT_HOUSEHOLD)) {
+            elements.personSelect.value = CURRENT_HOUSEHOLD;
+            elements.personSelect.disabled = true;
+        } else if (state.people.length > 0) {
             elements.personSelect.value = state.people[0].name;
         }
 
         elements.personSelect.addEventListener('change', refreshPersonSelectColor);
     }
 
+    function initRoomChoices() {
+        elements.createRooms.innerHTML = '';
+        if (ROOMS_CONFIG.length === 0) {
+            return;
+        }
+
+        ROOMS_CONFIG.forEach((room) => {
+            const label = document.createElement('label');
+            label.className = 'modal-room';
+
+            const checkbox = document.createElement('input');
+            checkbox.type = 'checkbox';
+            checkbox.value = room.id;
+
+            const text = document.createElement('span');
+            text.textContent = room.beds ? `${room.name} (${room.beds} lit${room.beds > 1 ? 's' : ''})` : room.name;
+
+            label.appendChild(checkbox);
+            label.appendChild(text);
+            elements.createRooms.appendChild(label);
+        });
+        elements.createRoomsWrapper.classList.remove('hidden');
+    }
+
+    function selectedRooms() {
+        return Array.from(elements.createRooms.querySelectorAll('input[type="checkbox"]'))
+            .filter((checkbox) => checkbox.checked)
+            .map((checkbox) => checkbox.value);
+    }
+
     function refreshPersonSelectColor() {
         const select = elements.personSelect;
         const color = state.peopleMap.get(select.value) || '#cccccc';
@@ -242,6 +336,26 @@
         });
         elements.deleteSave.addEventListener('click', saveReservationComment);
         elements.deleteConfirm.addEventListener('click', openConfirmModal);
+        elements.deleteSeries.addEventListener('click', deleteSeries);
+        elements.deleteApprove.addEventListener('click', () => decideReservation(true));
+        elements.deleteDecline.addEventListener('click', () => decideReservation(false));
+        elements.offerAccept.addEventListener('click', () => answerOffer(true));
+        elements.offerDecline.addEventListener('click', () => answerOffer(false));
+        elements.transferPropose.addEventListener('click', proposeTransfer);
+        elements.transferAccept.addEventListener('click', () => answerTransfer(true));
+        elements.transferDecline.addEventListener('click', () => answerTransfer(false));
+        elements.commentPost.addEventListener('click', postComment);
+        elements.attachmentUpload.addEventListener('click', uploadAttachment);
+        elements.rsvpSend.addEventListener('click', sendRSVP);
+        elements.taskAdd.addEventListener('click', addTask);
+        elements.metersSave.addEventListener('click', saveMeters);
+        elements.createEventWrapper.classList.toggle('hidden', !CURRENT_MEMBER);
+        elements.createWaitlist.addEventListener('click', joinWaitlist);
+        elements.createBlackout.addEventListener('click', createBlackout);
+        elements.createBlackout.classList.toggle('hidden', !IS_ADMIN);
+        elements.createNote.addEventListener('click', createNote);
+        elements.createNote.classList.toggle('hidden', !CURRENT_MEMBER);
+        elements.createNoteWrapper.classList.toggle('hidden', !CURRENT_MEMBER);
         elements.deleteCancel.addEventListener('click', () => {
             closeDeleteModal();
         });
@@ -483,17 +597,53 @@
 
     async function loadReservations() {
         try {
-            const response = await fetch(buildURL('/api/reservations'));
-            if (!response.ok) {
+            const [response, blackoutsResponse, transfersResponse, notesResponse] = await Promise.all([
+                fetch(buildURL('/api/reservations')),
+                fetch(buildURL('/api/blackouts')),
+                fetch(buildURL('/api/transfers')),
+                fetch(buildURL('/api/notes')),
+            ]);
+            if (!response.ok || !blackoutsResponse.ok || !transfersResponse.ok || !notesResponse.ok) {
                 throw new Error('fetch failed');
             }
             const data = await response.json();
+            const blackouts = await blackoutsResponse.json();
+            const transfers = await transfersResponse.json();
+            const notes = await notesResponse.json();
+            state.notes = (Array.isArray(notes) ? notes : []).map((item) => ({
+                id: item.id,
+                start: new Date(item.start),
+                end: new Date(item.end),
+                text: typeof item.text === 'string' ? item.text : '',
+                author: typeof item.author === 'string' ? item.author : '',
+                visibility: item.visibility || 'all',
+            }));
+            state.transfers = (Array.isArray(transfers) ? transfers : []).filter((item) => item.status === 'pending');
+            state.blackouts = (Array.isArray(blackouts) ? blackouts : []).map((item) => ({
+                id: item.id,
+                start: new Date(item.start),
+                end: new Date(item.end),
+                reason: typeof item.reason === 'string' ? item.reason : '',
+            }));
             state.reservations = (Array.isArray(data) ? data : []).map((item) => ({
+                key: reservationKey(item),
                 id: item.id,
+                seriesId: item.series_id || 0,
+                occurrence: item.occurrence || '',
                 person: item.person,
+                member: typeof item.member === 'string' ? item.member : '',
                 start: new Date(item.start),
                 end: new Date(item.end),
                 comment: typeof item.comment === 'string' ? item.comment : '',
+                adults: Number(item.adults) || 0,
+                children: Number(item.children) || 0,
+                rooms: Array.isArray(item.rooms) ? item.rooms : [],
+                status: item.status || 'confirmed',
+                waitlistId: item.waitlist_id || 0,
+                attachments: Array.isArray(item.attachments) ? item.attachments : [],
+                kind: item.kind || 'stay',
+                attendees: Number(item.attendees) || 0,
+                rsvps: Array.isArray(item.rsvps) ? item.rsvps : [],
             }));
             renderReservations();
         } catch (error) {
@@ -503,11 +653,45 @@
 
     function renderReservations() {
         state.slotElements.forEach((slot) => {
-            slot.classList.remove('has-reservation');
-            const dots = slot.querySelectorAll('.reservation-dot');
+            slot.classList.remove('has-reservation', 'blocked', 'has-note');
+            slot.removeAttribute('title');
+            const dots = slot.querySelectorAll('.reservation-dot, .note-marker');
             dots.forEach((dot) => dot.remove());
         });
 
+        state.blackouts.forEach((blackout) => {
+            listSlotsForReservation(blackout).forEach((slotIndex) => {
+                const slotElement = state.slotElements.get(state.indexToSlotKey[slotIndex]);
+                if (slotElement) {
+                    slotElement.classList.add('blocked');
+                    slotElement.title = `Indisponible : ${blackout.reason}`;
+                }
+            });
+        });
+
+        state.notes.forEach((note) => {
+            listSlotsForReservation(note).forEach((slotIndex) => {
+                const slotElement = state.slotElements.get(state.indexToSlotKey[slotIndex]);
+                if (!slotElement) {
+                    return;
+                }
+                const label = `Note de ${note.author} : ${note.text}`;
+                const marker = document.createElement('button');
+                marker.type = 'button';
+                marker.className = 'note-marker';
+                marker.title = label;
+                marker.setAttribute('aria-label', label);
+                marker.addEventListener('mousedown', (event) => event.stopPropagation());
+                marker.addEventListener('click', (event) => {
+                    event.stopPropagation();
+                    openNote(note);
+                });
+                slotElement.classList.add('has-note');
+                slotElement.title = slotElement.title ? `${slotElement.title}\n${label}` : label;
+                slotElement.appendChild(marker);
+            });
+        });
+
         const sorted = state.reservations.slice().sort((a, b) => a.start - b.start);
 
         sorted.forEach((reservation) => {
@@ -525,7 +709,10 @@
                 const dot = document.createElement('button');
                 dot.type = 'button';
                 dot.className = 'reservation-dot';
-                dot.dataset.reservationId = reservation.id;
+                if (reservation.status !== 'confirmed') {
+                    dot.classList.add(reservation.status);
+                }
+                dot.dataset.reservationId = reservation.key;
                 dot.style.backgroundColor = color;
                 dot.title = tooltip;
                 dot.setAttribute('aria-label', tooltip);
@@ -533,7 +720,7 @@
                 dot.addEventListener('mousedown', (event) => event.stopPropagation());
                 dot.addEventListener('click', (event) => {
                     event.stopPropagation();
-                    openDeleteModal(reservation.id);
+                    openDeleteModal(reservation.key);
                 });
 
                 slotElement.classList.add('has-reservation');
@@ -569,9 +756,18 @@
                 ? `1 demi-journee le ${startLabel}`
                 : `${count} demi-journees du ${startLabel} au ${endLabel}`;
         elements.createRange.textContent = summary;
+        elements.createWaitlist.classList.add('hidden');
+        elements.createOverrideWrapper.classList.add('hidden');
+        elements.createOverride.value = '';
         if (elements.createComment) {
             elements.createComment.value = '';
         }
+        elements.createAdults.value = '1';
+        elements.createEvent.checked = false;
+        elements.createChildren.value = '0';
+        elements.createRooms.querySelectorAll('input[type="checkbox"]').forEach((checkbox) => {
+            checkbox.checked = false;
+        });
         elements.createModal.classList.remove('hidden');
         refreshPersonSelectColor();
         elements.personSelect.focus();
@@ -587,26 +783,37 @@
         }
     }
 
-    async function submitReservation() {
+    function reservationPayload() {
         const range = state.pendingRange;
         if (!range) {
-            return;
+            return null;
         }
 
         const person = elements.personSelect.value;
         if (!person) {
             showToast("Merci de choisir une personne");
-            return;
+            return null;
         }
 
-        const comment = elements.createComment ? elements.createComment.value.trim() : '';
-
-        const payload = {
+        return {
             person,
             start: range.startDate.toISOString(),
             end: range.endDateExclusive.toISOString(),
-            comment,
+            comment: elements.createComment ? elements.createComment.value.trim() : '',
+            adults: Number(elements.createAdults.value) || 0,
+            children: Number(elements.createChildren.value) || 0,
+            rooms: selectedRooms(),
+            kind: elements.createEvent.checked ? 'event' : 'stay',
+            override_reason: elements.createOverride.value.trim(),
         };
+    }
+
+    async function submitReservation() {
+        const payload = reservationPayload();
+        if (!payload) {
+            return;
+        }
+        const comment = payload.comment;
 
         try {
             const response = await fetch(buildURL('/api/reservations'), {
@@ -617,33 +824,99 @@
                 body: JSON.stringify(payload),
             });
 
+            if (response.status === 409) {
+                const conflict = await response.json().catch(() => ({}));
+                if (conflict.error === 'period is blocked') {
+                    showToast(`Periode indisponible : ${conflict.blackout ? conflict.blackout.reason : ''}`);
+                    return;
+                }
+                if (conflict.error === 'booking rules violated') {
+                    const broken = Array.isArray(conflict.violations) ? conflict.violations : [];
+                    if (IS_ADMIN) {
+                        elements.createOverrideWrapper.classList.remove('hidden');
+                        elements.createOverride.focus();
+                    }
+                    showToast(`Regles de reservation non respectees : ${broken.map((item) => item.message).join(' ; ')}`);
+                    return;
+                }
+                if (conflict.error === 'quota exceeded') {
+                    elements.createOverrideWrapper.classList.remove('hidden');
+                    elements.createOverride.focus();
+                    showToast('Quota annuel depasse : indiquez un motif pour reserver malgre tout');
+                    return;
+                }
+                if (conflict.room) {
+                    showToast(`${roomLabel(conflict.room)} est deja reservee`);
+                } else if (conflict.error === 'turnover buffer') {
+                    showToast('Le menage entre deux sejours n\'est pas respecte');
+                } else {
+                    showToast("Capacite de l'appartement depassee");
+                }
+                elements.createWaitlist.classList.remove('hidden');
+                return;
+            }
+            if (response.status === 403 && payload.override_reason) {
+                showToast('Seul un administrateur peut passer outre');
+                return;
+            }
             if (!response.ok) {
                 throw new Error(await response.text());
             }
 
             const created = await response.json();
             state.reservations.push({
+                key: reservationKey(created),
                 id: created.id,
+                seriesId: 0,
+                occurrence: '',
                 person: created.person,
+                member: typeof created.member === 'string' ? created.member : '',
                 start: new Date(created.start),
                 end: new Date(created.end),
                 comment: typeof created.comment === 'string' ? created.comment : comment,
+                adults: Number(created.adults) || 0,
+                children: Number(created.children) || 0,
+                rooms: Array.isArray(created.rooms) ? created.rooms : [],
+                status: created.status || 'confirmed',
+                waitlistId: 0,
+                attachments: [],
             });
             closeCreateModal();
             renderReservations();
-            showToast("Reservation enregistree");
+            if (created.status === 'tentative') {
+                showToast("Reservation en attente de validation");
+            } else if (Array.isArray(created.overrides) && created.overrides.length > 0) {
+                showToast("Reservation enregistree (quota depasse)");
+            } else if (Array.isArray(created.warnings) && created.warnings.length > 0) {
+                showToast("Reservation enregistree (capacite depassee)");
+            } else {
+                showToast("Reservation enregistree");
+            }
         } catch (error) {
             showToast("Echec de l'enregistrement");
         }
     }
 
-    function openDeleteModal(reservationId) {
-        const reservation = state.reservations.find((item) => item.id === reservationId);
+    function openDeleteModal(reservationKey) {
+        const reservation = findReservation(reservationKey);
         if (!reservation) {
             return;
         }
 
-        state.pendingDeleteId = reservationId;
+        state.pendingDeleteId = reservationKey;
+        elements.deleteSeries.classList.toggle('hidden', !reservation.seriesId);
+        const isOffer = Boolean(reservation.waitlistId) && (CURRENT_HOUSEHOLD === '' || CURRENT_HOUSEHOLD === reservation.person);
+        elements.offerAccept.classList.toggle('hidden', !isOffer);
+        elements.offerDecline.classList.toggle('hidden', !isOffer);
+        const canDecide = !reservation.waitlistId && canDecideReservation(reservation);
+        elements.deleteApprove.classList.toggle('hidden', !canDecide);
+        elements.deleteDecline.classList.toggle('hidden', !canDecide);
+        refreshTransferControls(reservation);
+        loadComments(reservation);
+        loadTasks(reservation);
+        loadMeters(reservation);
+        renderAttachments(reservation);
+        renderRSVPs(reservation);
         elements.deleteDescription.textContent = formatReservationSummary(reservation);
         if (elements.deleteComment) {
             elements.deleteComment.value = reservation.comment || '';
@@ -668,16 +941,20 @@
             return;
         }
 
-        const id = state.pendingDeleteId;
+        const key = state.pendingDeleteId;
+        const reservation = findReservation(key);
+        if (!reservation) {
+            return;
+        }
         try {
-            const response = await fetch(buildURL(`/api/reservations/${id}`), {
+            const response = await fetch(buildURL(reservationPath(reservation)), {
                 method: 'DELETE',
             });
             if (!response.ok) {
                 throw new Error('delete failed');
             }
 
-            state.reservations = state.reservations.filter((reservation) => reservation.id !== id);
+            state.reservations = state.reservations.filter((item) => item.key !== key);
             closeConfirmModal();
             closeDeleteModal();
             renderReservations();
@@ -687,6 +964,843 @@
         }
     }
 
+    async function deleteSeries() {
+        const reservation = findReservation(state.pendingDeleteId);
+        if (!reservation || !reservation.seriesId) {
+            return;
+        }
+
+        try {
+            const response = await fetch(buildURL(`/api/series/${reservation.seriesId}`), {
+                method: 'DELETE',
+            });
+            if (!response.ok) {
+                throw new Error('delete failed');
+            }
+
+            state.reservations = state.reservations.filter((item) => item.seriesId !== reservation.seriesId);
+            closeDeleteModal();
+            renderReservations();
+            showToast('Serie supprimee');
+        } catch (error) {
+            showToast("Echec de la suppression");
+        }
+    }
+
+    async function loadComments(reservation) {
+        state.editingCommentId = null;
+        elements.commentNew.value = '';
+        elements.commentsList.innerHTML = '';
+        const available = !reservation.seriesId && Boolean(CURRENT_MEMBER);
+        elements.commentsWrapper.classList.toggle('hidden', !available);
+        if (!available) {
+            return;
+        }
+
+        try {
+            const response = await fetch(buildURL(`/api/reservations/${reservation.id}/comments`));
+            if (!response.ok) {
+                throw new Error('comments failed');
+            }
+            renderComments(await response.json());
+        } catch (error) {
+            showToast('Echec du chargement de la discussion');
+        }
+    }
+
+    function renderComments(comments) {
+        elements.commentsList.innerHTML = '';
+        comments.forEach((comment) => {
+            const item = document.createElement('li');
+            item.className = 'comment-item';
+
+            const meta = document.createElement('span');
+            meta.className = 'comment-meta';
+            const edited = comment.edited_at ? ' (modifie)' : '';
+            meta.textContent = `${comment.author}, ${formatDateTime(new Date(comment.created_at))}${edited}`;
+            if (comment.author === CURRENT_MEMBER || IS_ADMIN) {
+                const edit = document.createElement('button');
+                edit.type = 'button';
+                edit.textContent = 'Modifier';
+                edit.addEventListener('click', () => {
+                    state.editingCommentId = comment.id;
+                    elements.commentNew.value = comment.body;
+                    elements.commentNew.focus();
+                });
+                const remove = document.createElement('button');
+                remove.type = 'button';
+                remove.textContent = 'Supprimer';
+                remove.addEventListener('click', () => deleteComment(comment.id));
+                meta.appendChild(edit);
+                meta.appendChild(remove);
+            }
+
+            const body = document.createElement('span');
+            body.textContent = comment.body;
+
+            item.appendChild(meta);
+            item.appendChild(body);
+            elements.commentsList.appendChild(item);
+        });
+    }
+
+    async function postComment() {
+        const reservation = findReservation(state.pendingDeleteId);
+        const body = elements.commentNew.value.trim();
+        if (!reservation || !body) {
+            return;
+        }
+
+        const editing = state.editingCommentId;
+        const path = editing ? `/api/reservations/${reservation.id}/comments/${editing}` : `/api/reservations/${reservation.id}/comments`;
+        try {
+            const response = await fetch(buildURL(path), {
+                method: editing ? 'PATCH' : 'POST',
+                headers: {
+                    'Content-Type': 'application/json',
+                },
+                body: JSON.stringify({ body }),
+            });
+            if (!response.ok) {
+                throw new Error('comment failed');
+            }
+            await loadComments(reservation);
+        } catch (error) {
+            showToast("Echec de l'envoi du message");
+        }
+    }
+
+    async function deleteComment(id) {
+        const reservation = findReservation(state.pendingDeleteId);
+        if (!reservation) {
+            return;
+        }
+
+        try {
+            const response = await fetch(buildURL(`/api/reservations/${reservation.id}/comments/${id}`), {
+                method: 'DELETE',
+            });
+            if (!response.ok) {
+                throw new Error('delete failed');
+            }
+            await loadComments(reservation);
+        } catch (error) {
+            showToast('Echec de la suppression du message');
+        }
+    }
+
+    function renderAttachments(reservation) {
+        elements.attachmentFile.value = '';
+        elements.attachmentsList.innerHTML = '';
+        elements.attachmentsWrapper.classList.toggle('hidden', Boolean(reservation.seriesId));
+        if (reservation.seriesId) {
+            return;
+        }
+
+        (reservation.attachments || []).forEach((attachment) => {
+            const item = document.createElement('li');
+            item.className = 'attachment-item';
+
+            const link = document.createElement('a');
+            link.href = buildURL(`/api/attachments/${attachment.id}`);
+            link.target = '_blank';
+            link.rel = 'noopener';
+            if (attachment.has_thumbnail) {
+                const image = document.createElement('img');
+                image.src = buildURL(`/api/attachments/${attachment.id}/thumbnail`);
+                image.alt = attachment.name;
+                link.appendChild(image);
+            } else {
+                link.textContent = attachment.name;
+            }
+
+            const remove = document.createElement('button');
+            remove.type = 'button';
+            remove.textContent = 'Retirer';
+            remove.addEventListener('click', () => deleteAttachment(reservation, attachment.id));
+
+            item.appendChild(link);
+            item.appendChild(remove);
+            elements.attachmentsList.appendChild(item);
+        });
+    }
+
+    const TASK_PHASES = [
+        ['arrival', 'Arrivee'],
+        ['departure', 'Depart'],
+    ];
+
+    function canManageReservation(reservation) {
+        return !CURRENT_MEMBER || CURRENT_HOUSEHOLD === reservation.person || IS_ADMIN;
+    }
+
+    async function loadTasks(reservation) {
+        elements.taskNew.value = '';
+        elements.tasksList.innerHTML = '';
+        elements.tasksPrevious.classList.add('hidden');
+        elements.tasksWrapper.classList.toggle('hidden', Boolean(reservation.seriesId));
+        if (reservation.seriesId) {
+            return;
+        }
+
+        try {
+            const response = await fetch(buildURL(`/api/reservations/${reservation.id}/tasks`));
+            if (!response.ok) {
+                throw new Error('tasks failed');
+            }
+            renderTasks(reservation, await response.json());
+        } catch (error) {
+            showToast('Echec du chargement de la checklist');
+        }
+    }
+
+    function renderTasks(reservation, data) {
+        const editable = canManageReservation(reservation);
+        elements.taskNew.classList.toggle('hidden', !editable);
+        elements.taskAdd.classList.toggle('hidden', !editable);
+
+        const previous = data.previous;
+        elements.tasksPrevious.classList.toggle('hidden', !previous);
+        if (previous) {
+            const titles = previous.open.map((task) => task.title).join(', ');
+            elements.tasksPrevious.textContent = `Laisse en suspens par ${previous.person} : ${titles}`;
+        }
+
+        elements.tasksList.innerHTML = '';
+        const tasks = Array.isArray(data.tasks) ? data.tasks : [];
+        TASK_PHASES.forEach(([phase, label]) => {
+            tasks.filter((task) => task.phase === phase).forEach((task) => {
+                const item = document.createElement('li');
+                item.className = 'task-item';
+
+                const box = document.createElement('input');
+                box.type = 'checkbox';
+                box.checked = task.done;
+                box.disabled = !editable;
+                box.addEventListener('change', () => tickTask(reservation, task, box.checked));
+
+                const text = document.createElement('span');
+                text.textContent = `${label} : ${task.title}`;
+                if (task.done) {
+                    text.title = `Fait par ${task.done_by || '?'}, ${formatDateTime(new Date(task.done_at))}`;
+                }
+
+                item.appendChild(box);
+                item.appendChild(text);
+                if (task.custom && editable) {
+                    const remove = document.createElement('button');
+                    remove.type = 'button';
+                    remove.textContent = 'Retirer';
+                    remove.addEventListener('click', () => deleteTask(reservation, task.id));
+                    item.appendChild(remove);
+                }
+                elements.tasksList.appendChild(item);
+            });
+        });
+    }
+
+    async function tickTask(reservation, task, done) {
+        try {
+            const response = await fetch(buildURL(`/api/reservations/${reservation.id}/tasks/${task.id}`), {
+                method: 'PATCH',
+                headers: {
+                    'Content-Type': 'application/json',
+                },
+                body: JSON.stringify({ done }),
+            });
+            if (!response.ok) {
+                throw new Error('task failed');
+            }
+            await loadTasks(reservation);
+        } catch (error) {
+            showToast("Echec de l'enregistrement de la tache");
+        }
+    }
+
+    async function addTask() {
+        const reservation = findReservation(state.pendingDeleteId);
+        const title = elements.taskNew.value.trim();
+        if (!reservation || !title) {
+            return;
+        }
+
+        try {
+            const response = await fetch(buildURL(`/api/reservations/${reservation.id}/tasks`), {
+                method: 'POST',
+                headers: {
+                    'Content-Type': 'application/json',
+                },
+                body: JSON.stringify({ title }),
+            });
+            if (!response.ok) {
+                throw new Error('task failed');
+            }
+            await loadTasks(reservation);
+        } catch (error) {
+            showToast("Echec de l'ajout de la tache");
+        }
+    }
+
+    async function deleteTask(reservation, id) {
+        try {
+            const response = await fetch(buildURL(`/api/reservations/${reservation.id}/tasks/${id}`), {
+                method: 'DELETE',
+            });
+            if (!response.ok) {
+                throw new Error('delete failed');
+            }
+            await loadTasks(reservation);
+        } catch (error) {
+            showToast('Echec de la suppression de la tache');
+        }
+    }
+
+    const METERS = [
+        ['electricity', 'Electricite', 'kWh'],
+        ['water', 'Eau', 'm3'],
+        ['gas', 'Gaz', 'm3'],
+    ];
+
+    async function loadMeters(reservation) {
+        elements.metersBody.innerHTML = '';
+        elements.metersCost.textContent = '';
+        elements.metersWrapper.classList.toggle('hidden', Boolean(reservation.seriesId));
+        if (reservation.seriesId) {
+            return;
+        }
+
+        try {
+            const response = await fetch(buildURL(`/api/reservations/${reservation.id}/meters`));
+            if (!response.ok) {
+                throw new Error('meters failed');
+            }
+            renderMeters(reservation, await response.json());
+        } catch (error) {
+            showToast('Echec du chargement des releves');
+        }
+    }
+
+    function renderMeters(reservation, data) {
+        const editable = canManageReservation(reservation);
+        elements.metersSave.classList.toggle('hidden', !editable);
+        elements.metersBody.innerHTML = '';
+        const usage = new Map((Array.isArray(data.usage) ? data.usage : []).map((item) => [item.meter, item]));
+
+        METERS.forEach(([meter, label, unit]) => {
+            const row = document.createElement('tr');
+            const name = document.createElement('th');
+            name.textContent = label;
+            row.appendChild(name);
+
+            const current = usage.get(meter) || {};
+            ['arrival', 'departure'].forEach((phase) => {
+                const cell = document.createElement('td');
+                const input = document.createElement('input');
+                input.type = 'number';
+                input.min = '0';
+                input.step = 'any';
+                input.className = 'modal-number';
+                input.disabled = !editable;
+                input.dataset.meter = meter;
+                input.dataset.phase = phase;
+                const value = current[phase];
+                input.value = typeof value === 'number' ? String(value) : '';
+                input.dataset.initial = input.value;
+                cell.appendChild(input);
+                row.appendChild(cell);
+            });
+
+            const result = document.createElement('td');
+            result.textContent = current.complete ? `${current.consumption.toFixed(2)} ${unit}` : '';
+            row.appendChild(result);
+            elements.metersBody.appendChild(row);
+        });
+
+        elements.metersCost.textContent = data.cost > 0 ? `Cout du sejour : ${data.cost.toFixed(2)}` : '';
+    }
+
+    async function saveMeters() {
+        const reservation = findReservation(state.pendingDeleteId);
+        if (!reservation) {
+            return;
+        }
+
+        const changed = Array.from(elements.metersBody.querySelectorAll('input'))
+            .filter((input) => input.value !== '' && input.value !== input.dataset.initial);
+        try {
+            for (const input of changed) {
+                const response = await fetch(buildURL(`/api/reservations/${reservation.id}/meters`), {
+                    method: 'POST',
+                    headers: {
+                        'Content-Type': 'application/json',
+                    },
+                    body: JSON.stringify({
+                        meter: input.dataset.meter,
+                        phase: input.dataset.phase,
+                        value: Number(input.value),
+                    }),
+                });
+                if (!response.ok) {
+                    throw new Error('meter failed');
+                }
+            }
+            await loadMeters(reservation);
+            showToast('Releves enregistres');
+        } catch (error) {
+            showToast("Echec de l'enregistrement des releves");
+        }
+    }
+
+    const RSVP_LABELS = {
+        yes: 'vient',
+        maybe: 'viendra peut-etre',
+        no: 'ne vient pas',
+    };
+
+    function renderRSVPs(reservation) {
+        const isEvent = reservation.kind === 'event';
+        elements.rsvpWrapper.classList.toggle('hidden', !isEvent);
+        elements.rsvpList.innerHTML = '';
+        if (!isEvent) {
+            return;
+        }
+
+        elements.rsvpSummary.textContent = `${reservation.attendees} participant(s) annonce(s) en plus du foyer organisateur`;
+        reservation.rsvps.forEach((rsvp) => {
+            const item = document.createElement('li');
+            item.className = 'comment-item';
+            let text = `${rsvp.household} ${RSVP_LABELS[rsvp.answer] || rsvp.answer}`;
+            if (rsvp.answer !== 'no') {
+                text += ` (${rsvp.headcount} pers.)`;
+            }
+            item.textContent = text;
+            const meta = document.createElement('span');
+            meta.className = 'comment-meta';
+            meta.textContent = ` ${rsvp.member}, ${formatDateTime(new Date(rsvp.updated_at))}`;
+            item.appendChild(meta);
+            elements.rsvpList.appendChild(item);
+        });
+
+        const canAnswer = Boolean(CURRENT_MEMBER) && CURRENT_HOUSEHOLD !== reservation.person && reservation.end > new Date();
+        elements.rsvpForm.classList.toggle('hidden', !canAnswer);
+        const own = reservation.rsvps.find((rsvp) => rsvp.household === CURRENT_HOUSEHOLD);
+        elements.rsvpAnswer.value = own ? own.answer : 'yes';
+        elements.rsvpHeadcount.value = own ? String(own.headcount) : '1';
+    }
+
+    async function sendRSVP() {
+        const reservation = findReservation(state.pendingDeleteId);
+        if (!reservation) {
+            return;
+        }
+
+        try {
+            const response = await fetch(buildURL(`/api/reservations/${reservation.id}/rsvp`), {
+                method: 'POST',
+                headers: {
+                    'Content-Type': 'application/json',
+                },
+                body: JSON.stringify({
+                    answer: elements.rsvpAnswer.value,
+                    headcount: Number(elements.rsvpHeadcount.value) || 0,
+                }),
+            });
+            if (response.status === 409) {
+                showToast('Capacite du logement depassee');
+                return;
+            }
+            if (!response.ok) {
+                throw new Error('rsvp failed');
+            }
+
+            const data = await response.json();
+            reservation.attendees = data.attendees;
+            reservation.rsvps = Array.isArray(data.rsvps) ? data.rsvps : [];
+            renderRSVPs(reservation);
+            renderReservations();
+            showToast(data.warnings && data.warnings.length > 0 ? 'Reponse enregistree (capacite depassee)' : 'Reponse enregistree');
+        } catch (error) {
+            showToast("Echec de l'enregistrement de la reponse");
+        }
+    }
+
+    async function uploadAttachment() {
+        const reservation = findReservation(state.pendingDeleteId);
+        const file = elements.attachmentFile.files[0];
+        if (!reservation || !file) {
+            return;
+        }
+
+        const form = new FormData();
+        form.append('file', file);
+        try {
+            const response = await fetch(buildURL(`/api/reservations/${reservation.id}/attachments`), {
+                method: 'POST',
+                body: form,
+            });
+            if (response.status === 413) {
+                showToast('Fichier trop volumineux');
+                return;
+            }
+            if (response.status === 415) {
+                showToast('Type de fichier non accepte');
+                return;
+            }
+            if (!response.ok) {
+                throw new Error('upload failed');
+            }
+
+            reservation.attachments = (reservation.attachments || []).concat([await response.json()]);
+            renderAttachments(reservation);
+            showToast('Piece jointe ajoutee');
+        } catch (error) {
+            showToast("Echec de l'envoi du fichier");
+        }
+    }
+
+    async function deleteAttachment(reservation, id) {
+        try {
+            const response = await fetch(buildURL(`/api/attachments/${id}`), {
+                method: 'DELETE',
+            });
+            if (!response.ok) {
+                throw new Error('delete failed');
+            }
+
+            reservation.attachments = (reservation.attachments || []).filter((attachment) => attachment.id !== id);
+            renderAttachments(reservation);
+        } catch (error) {
+            showToast('Echec de la suppression de la piece jointe');
+        }
+    }
+
+    function incomingTransfer(reservation) {
+        if (!CURRENT_HOUSEHOLD || reservation.seriesId) {
+            return null;
+        }
+        return state.transfers.find((item) => item.offered_id === reservation.id && item.to_person === CURRENT_HOUSEHOLD) || null;
+    }
+
+    function refreshTransferControls(reservation) {
+        const canPropose = Boolean(CURRENT_HOUSEHOLD) && CURRENT_HOUSEHOLD === reservation.person && !reservation.seriesId && reservation.status !== 'declined';
+        elements.transferWrapper.classList.toggle('hidden', !canPropose);
+        elements.transferPropose.classList.toggle('hidden', !canPropose);
+        if (canPropose) {
+            elements.transferSelect.innerHTML = '';
+            state.people.filter((person) => person.name !== reservation.person).forEach((person) => {
+                const option = document.createElement('option');
+                option.value = person.name;
+                option.textContent = person.name;
+                elements.transferSelect.appendChild(option);
+            });
+        }
+
+        const incoming = incomingTransfer(reservation);
+        elements.transferAccept.classList.toggle('hidden', !incoming);
+        elements.transferDecline.classList.toggle('hidden', !incoming);
+        elements.transferIncoming.classList.toggle('hidden', !incoming);
+        if (incoming) {
+            const kind = incoming.requested_id ? "propose un echange de sejour" : 'vous cede ce sejour';
+            const message = incoming.message ? ` : ${incoming.message}` : '';
+            elements.transferIncoming.textContent = `${incoming.from_person} ${kind}${message}`;
+        }
+    }
+
+    async function proposeTransfer() {
+        const reservation = findReservation(state.pendingDeleteId);
+        const to = elements.transferSelect.value;
+        if (!reservation || !to) {
+            return;
+        }
+
+        try {
+            const response = await fetch(buildURL('/api/transfers'), {
+                method: 'POST',
+                headers: {
+                    'Content-Type': 'application/json',
+                },
+                body: JSON.stringify({ reservation_id: reservation.id, to }),
+            });
+            if (!response.ok) {
+                throw new Error('transfer failed');
+            }
+
+            closeDeleteModal();
+            await loadReservations();
+            showToast(`Proposition envoyee a ${to}`);
+        } catch (error) {
+            showToast("Echec de la proposition");
+        }
+    }
+
+    async function answerTransfer(accept) {
+        const reservation = findReservation(state.pendingDeleteId);
+        const incoming = reservation ? incomingTransfer(reservation) : null;
+        if (!incoming) {
+            return;
+        }
+
+        try {
+            const action = accept ? 'accept' : 'decline';
+            const response = await fetch(buildURL(`/api/transfers/${incoming.id}/${action}`), {
+                method: 'POST',
+            });
+            if (response.status === 409) {
+                const conflict = await response.json().catch(() => ({}));
+                if (conflict.error === 'quota exceeded') {
+                    showToast('Quota annuel depasse : echange impossible sans motif');
+                } else {
+                    showToast("Cet echange n'est plus possible");
+                }
+                return;
+            }
+            if (!response.ok) {
+                throw new Error('answer failed');
+            }
+
+            closeDeleteModal();
+            await loadReservations();
+            showToast(accept ? 'Echange accepte' : 'Echange refuse');
+        } catch (error) {
+            showToast("Echec de l'enregistrement");
+        }
+    }
+
+    function canDecideReservation(reservation) {
+        if (reservation.status !== 'tentative') {
+            return false;
+        }
+        if (IS_ADMIN) {
+            return true;
+        }
+        return APPROVAL_MODE === 'majority' && CURRENT_HOUSEHOLD !== '' && CURRENT_HOUSEHOLD !== reservation.person;
+    }
+
+    async function decideReservation(approve) {
+        const reservation = findReservation(state.pendingDeleteId);
+        if (!reservation || reservation.status !== 'tentative') {
+            return;
+        }
+
+        try {
+            const action = approve ? 'approve' : 'decline';
+            const target = reservation.seriesId ? `/api/series/${reservation.seriesId}` : `/api/reservations/${reservation.id}`;
+            const response = await fetch(buildURL(`${target}/${action}`), {
+                method: 'POST',
+            });
+            if (!response.ok) {
+                throw new Error('decision failed');
+            }
+
+            const updated = await response.json();
+            const status = updated.status || reservation.status;
+            state.reservations.forEach((item) => {
+                if (item === reservation || (reservation.seriesId && item.seriesId === reservation.seriesId)) {
+                    item.status = status;
+                }
+            });
+            closeDeleteModal();
+            renderReservations();
+            if (reservation.status === 'confirmed') {
+                showToast('Reservation validee');
+            } else if (reservation.status === 'declined') {
+                showToast('Reservation refusee');
+            } else {
+                showToast('Vote enregistre');
+            }
+        } catch (error) {
+            showToast("Echec de l'enregistrement du vote");
+        }
+    }
+
+    async function createBlackout() {
+        const range = state.pendingRange;
+        if (!range) {
+            return;
+        }
+        const reason = elements.createComment ? elements.createComment.value.trim() : '';
+        if (!reason) {
+            showToast('Indiquez le motif dans le commentaire');
+            return;
+        }
+
+        try {
+            const response = await fetch(buildURL('/api/blackouts'), {
+                method: 'POST',
+                headers: {
+                    'Content-Type': 'application/json',
+                },
+                body: JSON.stringify({
+                    start: range.startDate.toISOString(),
+                    end: range.endDateExclusive.toISOString(),
+                    reason,
+                }),
+            });
+            if (!response.ok) {
+                throw new Error('blackout failed');
+            }
+
+            closeCreateModal();
+            await loadReservations();
+            showToast('Periode bloquee');
+        } catch (error) {
+            showToast("Echec du blocage");
+        }
+    }
+
+    async function createNote() {
+        const range = state.pendingRange;
+        if (!range) {
+            return;
+        }
+        const text = elements.createComment ? elements.createComment.value.trim() : '';
+        if (!text) {
+            showToast('Indiquez le texte de la note dans le commentaire');
+            return;
+        }
+
+        try {
+            const response = await fetch(buildURL('/api/notes'), {
+                method: 'POST',
+                headers: {
+                    'Content-Type': 'application/json',
+                },
+                body: JSON.stringify({
+                    start: range.startDate.toISOString(),
+                    end: range.endDateExclusive.toISOString(),
+                    text,
+                    visibility: elements.createNoteVisibility.value,
+                }),
+            });
+            if (!response.ok) {
+                throw new Error('note failed');
+            }
+
+            closeCreateModal();
+            await loadReservations();
+            showToast('Note ajoutee');
+        } catch (error) {
+            showToast("Echec de l'ajout de la note");
+        }
+    }
+
+    async function openNote(note) {
+        if (note.author !== CURRENT_MEMBER && !IS_ADMIN) {
+            showToast(`Note de ${note.author} : ${note.text}`);
+            return;
+        }
+        if (!window.confirm(`Supprimer la note : ${note.text} ?`)) {
+            return;
+        }
+
+        try {
+            const response = await fetch(buildURL(`/api/notes/${note.id}`), { method: 'DELETE' });
+            if (!response.ok) {
+                throw new Error('delete failed');
+            }
+            await loadReservations();
+            showToast('Note supprimee');
+        } catch (error) {
+            showToast('Echec de la suppression de la note');
+        }
+    }
+
+    async function joinWaitlist() {
+        const payload = reservationPayload();
+        if (!payload) {
+            return;
+        }
+
+        try {
+            const response = await fetch(buildURL('/api/waitlist'), {
+                method: 'POST',
+                headers: {
+                    'Content-Type': 'application/json',
+                },
+                body: JSON.stringify(payload),
+            });
+            if (response.status === 409) {
+                const conflict = await response.json().catch(() => ({}));
+                if (conflict.error === 'booking rules violated') {
+                    const broken = Array.isArray(conflict.violations) ? conflict.violations : [];
+                    if (IS_ADMIN) {
+                        elements.createOverrideWrapper.classList.remove('hidden');
+                        elements.createOverride.focus();
+                    }
+                    showToast(`Regles de reservation non respectees : ${broken.map((item) => item.message).join(' ; ')}`);
+                } else if (conflict.error === 'quota exceeded') {
+                    elements.createOverrideWrapper.classList.remove('hidden');
+                    elements.createOverride.focus();
+                    showToast('Quota annuel depasse : indiquez un motif pour vous inscrire malgre tout');
+                } else {
+                    showToast('Ces dates sont disponibles');
+                }
+                return;
+            }
+            if (response.status === 403 && payload.override_reason) {
+                showToast('Seul un administrateur peut passer outre');
+                return;
+            }
+            if (!response.ok) {
+                throw new Error('waitlist failed');
+            }
+
+            closeCreateModal();
+            showToast("Inscription sur la liste d'attente enregistree");
+        } catch (error) {
+            showToast("Echec de l'inscription");
+        }
+    }
+
+    async function answerOffer(accept) {
+        const reservation = findReservation(state.pendingDeleteId);
+        if (!reservation || !reservation.waitlistId) {
+            return;
+        }
+
+        try {
+            const action = accept ? 'accept' : 'decline';
+            const response = await fetch(buildURL(`/api/waitlist/${reservation.waitlistId}/${action}`), {
+                method: 'POST',
+            });
+            if (response.status === 409) {
+                const conflict = await response.json().catch(() => ({}));
+                if (conflict.error === 'booking rules violated') {
+                    showToast('Regles de reservation non respectees : reservation impossible');
+                } else if (conflict.error === 'quota exceeded') {
+                    showToast('Quota annuel depasse : reservation impossible sans motif');
+                } else {
+                    showToast("Cette proposition n'est plus disponible");
+                }
+                return;
+            }
+            if (!response.ok) {
+                throw new Error('answer failed');
+            }
+
+            closeDeleteModal();
+            await loadReservations();
+            showToast(accept ? 'Reservation confirmee' : 'Proposition declinee');
+        } catch (error) {
+            showToast("Echec de la reponse");
+        }
+    }
+
+    function reservationKey(item) {
+        return item.series_id ? `series-${item.series_id}-${item.occurrence}` : `res-${item.id}`;
+    }
+
+    function findReservation(key) {
+        return state.reservations.find((item) => item.key === key);
+    }
+
+    function reservationPath(reservation) {
+        if (reservation.seriesId) {
+            return `/api/series/${reservation.seriesId}/occurrences/${encodeURIComponent(reservation.occurrence)}`;
+        }
+        return `/api/reservations/${reservation.id}`;
+    }
+
     function openConfirmModal() {
         if (!state.pendingDeleteId) {
             return;
@@ -708,11 +1822,14 @@
             return;
         }
 
-        const id = state.pendingDeleteId;
+        const target = findReservation(state.pendingDeleteId);
+        if (!target) {
+            return;
+        }
         const comment = elements.deleteComment ? elements.deleteComment.value.trim() : '';
 
         try {
-            const response = await fetch(buildURL(`/api/reservations/${id}`), {
+            const response = await fetch(buildURL(reservationPath(target)), {
                 method: 'PATCH',
                 headers: {
                     'Content-Type': 'application/json',
@@ -725,10 +1842,7 @@
             }
 
             const updated = await response.json();
-            const target = state.reservations.find((reservation) => reservation.id === id);
-            if (target) {
-                target.comment = typeof updated.comment === 'string' ? updated.comment : comment;
-            }
+            target.comment = typeof updated.comment === 'string' ? updated.comment : comment;
 
             renderReservations();
             showToast('Commentaire mis a jour');
@@ -746,21 +1860,50 @@
     }
 
     function reservationTooltip(reservation) {
-        const summary = formatReservationSummary(reservation);
+        let summary = formatReservationSummary(reservation);
+        const guests = (reservation.adults || 0) + (reservation.children || 0);
+        if (guests > 0) {
+            summary += ` (${guests} pers.)`;
+        }
+        if (reservation.rooms && reservation.rooms.length > 0) {
+            summary += `\n${reservation.rooms.map(roomLabel).join(', ')}`;
+        }
+        if (reservation.seriesId) {
+            summary += '\nReservation recurrente';
+        }
+        if (reservation.kind === 'event') {
+            summary += `\nRassemblement, ${reservation.attendees} participant(s) annonce(s)`;
+        }
+        if (reservation.waitlistId) {
+            summary += "\nProposee depuis la liste d'attente";
+        } else if (reservation.status === 'tentative') {
+            summary += '\nEn attente de validation';
+        } else if (reservation.status === 'declined') {
+            summary += '\nReservation refusee';
+        }
         const comment = (reservation.comment || '').trim();
         return comment ? `${summary}\n${comment}` : summary;
     }
 
+    function roomLabel(roomId) {
+        const room = state.roomsMap.get(roomId);
+        return room ? room.name : roomId;
+    }
+
     function formatReservationSummary(reservation) {
         const startLabel = describeSlot(reservation.start);
         const endSlotDate = new Date(reservation.end.getTime() - HALF_DAY_MS);
         const endLabel = describeSlot(endSlotDate);
         const halfDays = Math.round((reservation.end - reservation.start) / HALF_DAY_MS);
+        const who =
+            reservation.member && reservation.member !== reservation.person
+                ? `${reservation.person} (${reservation.member})`
+                : reservation.person;
 
         if (halfDays <= 1) {
-            return `${reservation.person} - ${startLabel}`;
+            return `${who} - ${startLabel}`;
         }
-        return `${reservation.person} - du ${startLabel} au ${endLabel}`;
+        return `${who} - du ${startLabel} au ${endLabel}`;
     }
 
     function describeSlot(date) {
@@ -776,6 +1919,12 @@
         return `${day}/${month}/${year}`;
     }
 
+    function formatDateTime(date) {
+        const hours = String(date.getHours()).padStart(2, '0');
+        const minutes = String(date.getMinutes()).padStart(2, '0');
+        return `${formatDateDisplay(date)} ${hours}:${minutes}`;
+    }
+
     function formatDateKey(date) {
         const month = String(date.getMonth() + 1).padStart(2, '0');
         const day = String(date.getDate()).padStart(2, '0');